@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+)
+
+// copyToBolt übernimmt die Log-Dateien in die eingebettete Datenbank. Die Historien werden im
+// Backend file nur im Speicher gehalten und können daher nicht übernommen werden, nach dem Umzug
+// beginnen die Historien der bestehenden Trainings leer.
+func copyToBolt(args []string) error {
+	flags := flag.NewFlagSet("copy-to-bolt", flag.ExitOnError)
+	dataDir := flags.String("data-dir", utils.GetEnvOrDefault("DATA_DIR", "data/"), "data directory")
+	boltFile := flags.String("bolt-file", "", "database file (default <data-dir>/ceh.db)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	dir, err := storage.OpenDataDir(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if *boltFile == "" {
+		*boltFile = utils.GetEnvOrDefault("BOLT_FILE", dir.File(storage.BoltFile))
+	}
+
+	db, err := storage.OpenBolt(*boltFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if count, err := questions.CopyToBolt(dir.File(storage.QuestionsFile), db); err != nil {
+		return err
+	} else {
+		fmt.Printf("%d questions copied to %s\n", count, *boltFile)
+	}
+	if count, err := training.CopyToBolt(dir.File(storage.TrainingsFile), db); err != nil {
+		return err
+	} else {
+		fmt.Printf("%d trainings copied to %s\n", count, *boltFile)
+	}
+	fmt.Println("histories are kept in memory by the file backend and were not copied")
+	return nil
+}
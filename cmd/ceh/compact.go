@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/reports"
+	"github.com/mwildt/ceh-utils/pkg/review"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+)
+
+func compact(args []string) error {
+	flags := flag.NewFlagSet("compact", flag.ExitOnError)
+	dataDir := flags.String("data-dir", utils.GetEnvOrDefault("DATA_DIR", "data/"), "data directory")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	dir, err := storage.OpenDataDir(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	trainingRepo, err := training.CreateFileRepository(dir.File(storage.TrainingsFile))
+	if err != nil {
+		return err
+	}
+	questionRepo, err := questions.CreateRepo(dir.File(storage.QuestionsFile))
+	if err != nil {
+		return errors.Join(err, trainingRepo.Close())
+	}
+	reviewRepo, err := review.CreateFileRepository(dir.File(storage.ReviewsFile))
+	if err != nil {
+		return errors.Join(err, trainingRepo.Close(), questionRepo.Close())
+	}
+	reportRepo, err := reports.CreateFileRepository(dir.File(storage.ReportsFile))
+	if err != nil {
+		return errors.Join(err, trainingRepo.Close(), questionRepo.Close(), reviewRepo.Close())
+	}
+	closeAll := func() error {
+		return errors.Join(trainingRepo.Close(), questionRepo.Close(), reviewRepo.Close(), reportRepo.Close())
+	}
+
+	for name, target := range map[string]utils.Compactable{
+		storage.TrainingsFile: trainingRepo.(utils.Compactable),
+		storage.QuestionsFile: questionRepo,
+		storage.ReviewsFile:   reviewRepo.(utils.Compactable),
+		storage.ReportsFile:   reportRepo.(utils.Compactable),
+	} {
+		total, live := target.CompactionStats()
+		if err = target.Compact(); err != nil {
+			return errors.Join(err, closeAll())
+		}
+		fmt.Printf("%s: %d records, %d after compaction\n", name, total, live)
+	}
+	return closeAll()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+)
+
+// convert schreibt Log-Dateien in das angegebene Format um. Ohne Dateien als Argumente werden die
+// Logs im Datenverzeichnis umgeschrieben. Die Originaldatei bleibt als <datei>.preconvert erhalten.
+func convert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	dataDir := flags.String("data-dir", utils.GetEnvOrDefault("DATA_DIR", "data/"), "data directory")
+	to := flags.String("to", utils.CodecJsonLines, "target format (jsonl or binary)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	target, err := utils.CodecByName(*to)
+	if err != nil {
+		return err
+	}
+	dir, err := storage.OpenDataDir(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{dir.File(storage.QuestionsFile), dir.File(storage.TrainingsFile)}
+	}
+	for _, path := range paths {
+		if !utils.FileExist(path) {
+			fmt.Printf("%s: not found, skipped\n", path)
+			continue
+		}
+		source, err := utils.CodecFor(path, utils.BinaryCodec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		} else if source == target {
+			fmt.Printf("%s: already %s\n", path, target.Name())
+			continue
+		}
+		if err = utils.CopyFile(path, path+".preconvert"); err != nil {
+			return err
+		}
+		_, count, err := utils.ConvertLogFile(path, target)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("%s: %d records converted from %s to %s, original kept as %s.preconvert\n", path, count, source.Name(), target.Name(), path)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ein Kommando erhält die Argumente hinter seinem Namen
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"compact":      {"compact the training and question logs (server must be stopped)", compact},
+	"convert":      {"convert log files between the binary and the JSON Lines format (server must be stopped)", convert},
+	"copy-to-bolt": {"copy the training and question logs into the embedded database", copyToBolt},
+	"migrate":      {"migrate stored records to the current schema versions (--dry-run to report only)", migrate},
+	"restore":      {"verify and restore a backup (server must be stopped)", restore},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, exists := commands[os.Args[1]]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %s\n", os.Args[1], err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "usage: ceh <command> [options]\n\ncommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].description)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/config"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/reports"
+	"github.com/mwildt/ceh-utils/pkg/review"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+)
+
+type migrationTarget struct {
+	path     string
+	registry *utils.MigrationRegistry
+	readOnly bool
+}
+
+// migrate bringt alle Datensätze der Logs und der Fragen-Quellen auf die aktuelle Schema-Version.
+// Vor dem Umschreiben bleibt die Originaldatei als <datei>.premigration erhalten.
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dataDir := flags.String("data-dir", utils.GetEnvOrDefault("DATA_DIR", "data/"), "data directory")
+	dryRun := flags.Bool("dry-run", false, "only report the records that would change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := config.Configured()
+	if err != nil {
+		return err
+	}
+	dir, err := storage.OpenDataDir(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	targets := []migrationTarget{
+		{path: dir.File(storage.QuestionsFile), registry: questions.Schema()},
+		{path: dir.File(storage.TrainingsFile), registry: training.Schema()},
+		{path: dir.File(storage.ReviewsFile), registry: review.Schema()},
+		{path: dir.File(storage.ReportsFile), registry: reports.Schema()},
+	}
+	for _, source := range cfg.Sources {
+		targets = append(targets, migrationTarget{path: source.Path, registry: questions.Schema(), readOnly: source.ReadOnly})
+	}
+
+	for _, target := range targets {
+		if !utils.FileExist(target.path) {
+			continue
+		}
+		reports, err := utils.MigrateLogFile(target.path, target.registry, true)
+		if err != nil {
+			return fmt.Errorf("%s: %w", target.path, err)
+		}
+		fmt.Printf("%s: %d records need migration to %s schema version %d\n", target.path, len(reports), target.registry.Name(), target.registry.Current())
+		for _, report := range reports {
+			for _, migration := range report.Migrations {
+				fmt.Printf("  record %d: version %d -> %d: %s\n", report.Index, migration.From, migration.From+1, migration.Description)
+			}
+		}
+		if *dryRun || len(reports) == 0 {
+			continue
+		} else if target.readOnly {
+			fmt.Printf("  skipped, source is read-only and migrated on load\n")
+			continue
+		}
+		if err = utils.CopyFile(target.path, target.path+".premigration"); err != nil {
+			return err
+		} else if _, err = utils.MigrateLogFile(target.path, target.registry, false); err != nil {
+			return fmt.Errorf("%s: %w", target.path, err)
+		}
+		fmt.Printf("  migrated, original kept as %s.premigration\n", target.path)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/backup"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"os"
+)
+
+func restore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	dataDir := flags.String("data-dir", utils.GetEnvOrDefault("DATA_DIR", "data/"), "data directory")
+	mediaDir := flags.String("media-dir", questions.MediaPath, "media directory, empty to skip media")
+	force := flags.Bool("force", false, "overwrite existing files")
+	verify := flags.Bool("verify", false, "only verify the backup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	} else if flags.NArg() != 1 {
+		return errors.New("usage: ceh restore [flags] <backup.tar.gz>")
+	}
+
+	archive, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if *verify {
+		manifest, err := backup.Verify(archive)
+		if err == nil {
+			fmt.Printf("%s is valid: %d files, created %s\n", flags.Arg(0), len(manifest.Files), manifest.Created)
+		}
+		return err
+	}
+
+	dir, err := storage.OpenDataDir(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	manifest, err := backup.Restore(archive, dir, *mediaDir, *force)
+	if err == nil {
+		fmt.Printf("%d files restored from backup created %s\n", len(manifest.Files), manifest.Created)
+	}
+	return err
+}
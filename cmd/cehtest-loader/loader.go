@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/client"
 	"github.com/mwildt/ceh-utils/pkg/questions"
 	"github.com/mwildt/ceh-utils/pkg/utils"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -66,11 +68,15 @@ func (dto NewSessionRequestDTO) MustJson() []byte {
 	return data
 }
 
+// Loader legt die Fragen von cehtest.org über die REST-API des Trainers an und lädt die Bilder
+// neuer Fragen nach MediaDir
 type Loader struct {
-	BaseUrl string
+	BaseUrl  string
+	MediaDir string
+	Api      *client.Client
 }
 
-func (loader *Loader) LoadAll(dto NewSessionRequestDTO, repo *questions.FileLogRepository, tags ...string) (cntNew int, cntOld int, cntFailed int, err error) {
+func (loader *Loader) LoadAll(dto NewSessionRequestDTO, tags ...string) (cntNew int, cntOld int, cntFailed int, err error) {
 	cookieJar, _ := cookiejar.New(nil)
 	client := &http.Client{Jar: cookieJar}
 
@@ -89,22 +95,16 @@ func (loader *Loader) LoadAll(dto NewSessionRequestDTO, repo *questions.FileLogR
 			//fmt.Println(apiQuestion.Question)
 
 			question := mapToModel(apiQuestion, tags...)
-			if !repo.Contains(questions.ByQuestionText(question.Question)) {
-
-				if len(question.Media) > 0 {
-					for _, media := range question.Media {
-						if err = Download(loader.BaseUrl+"/media/"+media, "ceh-12-cehtest.org/media/"+media); err != nil {
-							log.Fatal(err.Error())
-						}
+			if created, err := loader.save(question); err != nil {
+				log.Printf("\nFehler: %s\n", err)
+				cntFailed = cntFailed + 1
+			} else if created {
+				for _, media := range question.Media {
+					if err = Download(loader.BaseUrl+"/media/"+media, filepath.Join(loader.MediaDir, media)); err != nil {
+						log.Fatal(err.Error())
 					}
 				}
-
-				_, err = repo.Save(question)
 				cntNew = cntNew + 1
-				if err != nil {
-					log.Printf("\nFehler: %s\n", err)
-					cntFailed = cntFailed + 1
-				}
 			} else {
 				cntOld = cntOld + 1
 			}
@@ -183,7 +183,7 @@ func Download(url, outputFilePath string) error {
 	return nil
 }
 
-func (loader *Loader) LoadFile(repo *questions.FileLogRepository, filePath string) (cntNew int, cntOld int, cntFailed int, err error) {
+func (loader *Loader) LoadFile(filePath string) (cntNew int, cntOld int, cntFailed int, err error) {
 	fileContent, err := os.ReadFile(filePath)
 	if err != nil {
 		return cntNew, cntOld, cntFailed, err
@@ -195,18 +195,33 @@ func (loader *Loader) LoadFile(repo *questions.FileLogRepository, filePath strin
 		return cntNew, cntOld, cntFailed, err
 	}
 
-	question := mapToModel(apiQuestion.Question)
-	if !repo.Contains(questions.ByQuestionText(question.Question)) {
-		_, err = repo.Save(question)
-		if err != nil {
-			return cntNew, cntOld, 1, err
-		} else {
-			return 1, cntOld, cntFailed, err
-		}
+	if created, err := loader.save(mapToModel(apiQuestion.Question)); err != nil {
+		return cntNew, cntOld, 1, err
+	} else if created {
+		return 1, cntOld, cntFailed, err
 	}
 	return cntNew, 1, cntFailed, err
 }
 
+// save legt die Frage über die API an, eine vorhandene Frage beantwortet der Server mit 409
+func (loader *Loader) save(question *questions.Question) (created bool, err error) {
+	choices := make([]client.Choice, 0, len(question.Options))
+	for _, option := range question.Options {
+		choices = append(choices, client.Choice{Id: option.Id, Text: option.Option})
+	}
+	_, err = loader.Api.CreateQuestion(client.NewQuestion{
+		Text:    question.Question,
+		Choices: choices,
+		Answer:  question.AnswerIds,
+		Media:   question.Media,
+		Tags:    question.Tags,
+	})
+	if client.IsStatus(err, http.StatusConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func mapToModel(question Question, tags ...string) *questions.Question {
 	var answerIds []uuid.UUID
 	options := make([]questions.Option, 0)
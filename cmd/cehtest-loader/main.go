@@ -1,39 +1,49 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/client"
+	"github.com/mwildt/ceh-utils/pkg/utils"
 	"log"
 )
 
 func main() {
+	server := flag.String("server", "http://localhost:8080", "REST-API des Trainers, in der die Fragen angelegt werden")
+	apiKey := flag.String("api-key", utils.GetEnvOrDefault("API_KEY", ""), "API-Key für -server")
+	mediaDir := flag.String("media-dir", "ceh-12-cehtest.org/media", "Verzeichnis, in das die Bilder neuer Fragen geladen werden")
+	flag.Parse()
 
-	repo, err := questions.CreateRepo("ceh-12-cehtest.org/question.data")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	loader := Loader{BaseUrl: "https://cehtest.org/"}
+	api := client.New(*server).WithApiKey(*apiKey)
+	loader := Loader{BaseUrl: "https://cehtest.org/", MediaDir: *mediaDir, Api: api}
 
 	cntNew := 1
 	cntOld := 0
 	cntFailed := 0
+	var err error
 
 	for cntNew > 0 {
 		fmt.Printf("start new round witdh 125 questions")
 		// load from cehtest.org
 		cntNew, cntOld, cntFailed, err = loader.LoadAll(
 			NewSessionRequestDTO{QuestionCount: 125, Versions: []int{12}},
-			repo,
 			"cehtest-12")
 
-		fmt.Printf("new %d, old %d, failed: %d, total: %d", cntNew, cntOld, cntFailed, repo.CountAll())
+		fmt.Printf("new %d, old %d, failed: %d, total: %d", cntNew, cntOld, cntFailed, total(api))
 
 	}
 
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("new %d, old %d, failed: %d, total: %d", cntNew, cntOld, cntFailed, repo.CountAll())
+	fmt.Printf("new %d, old %d, failed: %d, total: %d", cntNew, cntOld, cntFailed, total(api))
 
 }
+
+func total(api *client.Client) int {
+	all, err := api.GetQuestions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return len(all)
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// clientgen erzeugt die Typen (model.go) und Operationen (operations.go) des Clients aus dem
+// OpenAPI-Dokument. Aufruf über go generate in pkg/client.
+func main() {
+	spec := flag.String("spec", "../api/openapi.json", "OpenAPI document")
+	out := flag.String("out", ".", "directory of the client package")
+	flag.Parse()
+	if err := run(*spec, *out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+const header = "// Code generated by clientgen from pkg/api/openapi.json. DO NOT EDIT.\n\npackage client\n\n"
+
+func run(specPath string, out string) error {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return err
+	}
+	document, err := parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", specPath, err)
+	}
+	spec, ok := document.(*object)
+	if !ok {
+		return fmt.Errorf("%s: expected an object", specPath)
+	}
+	gen := &generator{spec: spec, imports: map[string]bool{}}
+	if err = gen.write(filepath.Join(out, "model.go"), gen.models); err != nil {
+		return err
+	}
+	return gen.write(filepath.Join(out, "operations.go"), gen.operations)
+}
+
+// object ist ein JSON-Objekt, das die Reihenfolge der Schlüssel aus dem Dokument behält
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *object) object(key string) *object {
+	if o == nil {
+		return nil
+	}
+	value, _ := o.values[key].(*object)
+	return value
+}
+
+func (o *object) string(key string) string {
+	if o == nil {
+		return ""
+	}
+	value, _ := o.values[key].(string)
+	return value
+}
+
+func (o *object) list(key string) []any {
+	if o == nil {
+		return nil
+	}
+	value, _ := o.values[key].([]any)
+	return value
+}
+
+func (o *object) has(key string) bool {
+	if o == nil {
+		return false
+	}
+	_, exists := o.values[key]
+	return exists
+}
+
+func parse(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return parseValue(decoder)
+}
+
+func parseValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		result := &object{values: map[string]any{}}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			result.keys = append(result.keys, key.(string))
+			result.values[key.(string)] = value
+		}
+		_, err = decoder.Token()
+		return result, err
+	case json.Delim('['):
+		result := make([]any, 0)
+		for decoder.More() {
+			value, err := parseValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		_, err = decoder.Token()
+		return result, err
+	}
+	return token, nil
+}
+
+type generator struct {
+	spec    *object
+	imports map[string]bool
+}
+
+// write erzeugt den Inhalt der Datei, die Imports ergeben sich aus den verwendeten Typen
+func (gen *generator) write(path string, generate func(source *bytes.Buffer) error) error {
+	gen.imports = map[string]bool{}
+	var body bytes.Buffer
+	if err := generate(&body); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	imports := make([]string, 0, len(gen.imports))
+	for name := range gen.imports {
+		imports = append(imports, strconv.Quote(name))
+	}
+	sort.Strings(imports)
+
+	var source bytes.Buffer
+	source.WriteString(header)
+	if len(imports) > 0 {
+		fmt.Fprintf(&source, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	source.Write(body.Bytes())
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return os.WriteFile(path, formatted, 0644)
+}
+
+func (gen *generator) models(source *bytes.Buffer) error {
+	schemas := gen.spec.object("components").object("schemas")
+	for _, name := range schemas.keys {
+		schema := schemas.object(name)
+		comment(source, "", schema.string("description"))
+		if schema.string("type") == "string" && len(schema.list("enum")) > 0 {
+			fmt.Fprintf(source, "type %s string\n\nconst (\n", name)
+			for _, value := range schema.list("enum") {
+				fmt.Fprintf(source, "%s%s %s = %q\n", name, exported(value.(string)), name, value)
+			}
+			source.WriteString(")\n\n")
+			continue
+		} else if schema.string("type") != "object" {
+			return fmt.Errorf("schema %s: unsupported type %s", name, schema.string("type"))
+		}
+
+		required := map[string]bool{}
+		for _, property := range schema.list("required") {
+			required[property.(string)] = true
+		}
+		properties := schema.object("properties")
+		fmt.Fprintf(source, "type %s struct {\n", name)
+		for _, property := range properties.keys {
+			definition := properties.object(property)
+			goType, err := gen.goType(definition, !required[property])
+			if err != nil {
+				return fmt.Errorf("schema %s, property %s: %w", name, property, err)
+			}
+			tag := property
+			if !required[property] {
+				tag += ",omitempty"
+			}
+			comment(source, "\t", definition.string("description"))
+			fmt.Fprintf(source, "%s %s `json:%q`\n", exported(property), goType, tag)
+		}
+		source.WriteString("}\n\n")
+	}
+	return nil
+}
+
+// goType liefert den Go-Typ eines Schemas. Optionale Ids, Zeitpunkte und Objekte werden Zeiger,
+// da omitempty bei ihnen nicht greift.
+func (gen *generator) goType(schema *object, optional bool) (string, error) {
+	pointer := ""
+	if optional {
+		pointer = "*"
+	}
+	if ref := schema.string("$ref"); ref != "" {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target := gen.spec.object("components").object("schemas").object(name)
+		if target == nil {
+			return "", fmt.Errorf("unknown schema %s", ref)
+		} else if target.string("type") == "object" {
+			return pointer + name, nil
+		}
+		return name, nil
+	}
+	switch schema.string("type") {
+	case "string":
+		if schema.string("format") == "uuid" {
+			gen.imports["github.com/google/uuid"] = true
+			return pointer + "uuid.UUID", nil
+		} else if schema.string("format") == "date-time" {
+			gen.imports["time"] = true
+			return pointer + "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		item, err := gen.goType(schema.object("items"), false)
+		return "[]" + item, err
+	case "object":
+		if !schema.has("properties") {
+			return "map[string]any", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported schema type %s", schema.string("type"))
+}
+
+type parameter struct {
+	name        string
+	in          string
+	required    bool
+	description string
+	schema      *object
+}
+
+var pathParameter = regexp.MustCompile(`\{([^}]+)}`)
+
+var statusConstants = map[string]string{
+	"200": "http.StatusOK",
+	"201": "http.StatusCreated",
+	"202": "http.StatusAccepted",
+	"204": "http.StatusNoContent",
+}
+
+func (gen *generator) operations(source *bytes.Buffer) error {
+	gen.imports["net/http"] = true
+	paths := gen.spec.object("paths")
+	for _, path := range paths.keys {
+		item := paths.object(path)
+		for _, method := range item.keys {
+			if method == "parameters" {
+				continue
+			}
+			operation := item.object(method)
+			if err := gen.operation(source, path, strings.ToUpper(method), item, operation); err != nil {
+				return fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (gen *generator) operation(source *bytes.Buffer, path string, method string, item *object, operation *object) error {
+	name := exported(operation.string("operationId"))
+	parameters, err := gen.parameters(append(append([]any{}, item.list("parameters")...), operation.list("parameters")...))
+	if err != nil {
+		return err
+	}
+
+	arguments := make([]string, 0)
+	var query []parameter
+	for _, in := range []string{"path", "query"} {
+		for _, parameter := range parameters {
+			if parameter.in != in {
+				continue
+			}
+			goType, err := gen.goType(parameter.schema, false)
+			if err != nil {
+				return fmt.Errorf("parameter %s: %w", parameter.name, err)
+			}
+			arguments = append(arguments, parameter.name+" "+goType)
+			if in == "query" {
+				query = append(query, parameter)
+			}
+		}
+	}
+	body := "nil"
+	if schema, found := jsonSchema(operation.object("requestBody")); found {
+		goType, err := gen.goType(schema, false)
+		if err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+		body = unexported(goType)
+		arguments = append(arguments, body+" "+goType)
+	}
+
+	status, response, err := success(operation.object("responses"))
+	if err != nil {
+		return err
+	}
+	statusConstant, found := statusConstants[status]
+	if !found {
+		statusConstant = status
+	}
+	target, err := gen.target(path, parameters, query)
+	if err != nil {
+		return err
+	}
+	httpMethod := "http.Method" + method[:1] + strings.ToLower(method[1:])
+
+	fmt.Fprintf(source, "// %s: %s (%s %s)\n", name, operation.string("summary"), method, path)
+	if schema, found := jsonSchema(response); found {
+		goType, err := gen.goType(schema, false)
+		if err != nil {
+			return fmt.Errorf("response: %w", err)
+		}
+		fmt.Fprintf(source, "func (client *Client) %s(%s) (%s, error) {\n", name, strings.Join(arguments, ", "), goType)
+		gen.query(source, query)
+		fmt.Fprintf(source, "return call[%s](client, %s, %s, %s, %s)\n}\n\n", goType, httpMethod, target, body, statusConstant)
+	} else if response.has("content") {
+		fmt.Fprintf(source, "func (client *Client) %s(%s) ([]byte, error) {\n", name, strings.Join(arguments, ", "))
+		gen.query(source, query)
+		fmt.Fprintf(source, "return read(client, %s, %s, %s, %s)\n}\n\n", httpMethod, target, body, statusConstant)
+	} else {
+		fmt.Fprintf(source, "func (client *Client) %s(%s) error {\n", name, strings.Join(arguments, ", "))
+		gen.query(source, query)
+		fmt.Fprintf(source, "return send(client, %s, %s, %s, %s)\n}\n\n", httpMethod, target, body, statusConstant)
+	}
+	return nil
+}
+
+// parameters löst die Verweise auf components/parameters auf. Der einzige unterstützte Header
+// ist x-user, ihn setzt der Client über WithUser.
+func (gen *generator) parameters(definitions []any) ([]parameter, error) {
+	result := make([]parameter, 0, len(definitions))
+	for _, value := range definitions {
+		definition := value.(*object)
+		if ref := definition.string("$ref"); ref != "" {
+			definition = gen.spec.object("components").object("parameters").object(strings.TrimPrefix(ref, "#/components/parameters/"))
+			if definition == nil {
+				return nil, fmt.Errorf("unknown parameter %s", ref)
+			}
+		}
+		in := definition.string("in")
+		if in == "header" && strings.EqualFold(definition.string("name"), "x-user") {
+			continue
+		} else if in != "path" && in != "query" {
+			return nil, fmt.Errorf("unsupported %s parameter %s", in, definition.string("name"))
+		} else if definition.string("name") == "query" || definition.string("name") == "client" {
+			return nil, fmt.Errorf("parameter name %s is reserved", definition.string("name"))
+		}
+		required, _ := definition.values["required"].(bool)
+		result = append(result, parameter{
+			name:        definition.string("name"),
+			in:          in,
+			required:    required,
+			description: definition.string("description"),
+			schema:      definition.object("schema"),
+		})
+	}
+	return result, nil
+}
+
+// target liefert den Ausdruck für den Pfad mit eingesetzten Parametern und Query
+func (gen *generator) target(path string, parameters []parameter, query []parameter) (string, error) {
+	types := map[string]*object{}
+	for _, parameter := range parameters {
+		types[parameter.name] = parameter.schema
+	}
+	parts := make([]string, 0)
+	position := 0
+	for _, match := range pathParameter.FindAllStringSubmatchIndex(path, -1) {
+		if match[0] > position {
+			parts = append(parts, strconv.Quote(path[position:match[0]]))
+		}
+		name := path[match[2]:match[3]]
+		schema, found := types[name]
+		if !found {
+			return "", fmt.Errorf("path parameter %s is not defined", name)
+		}
+		value, err := gen.format(name, schema)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, value)
+		position = match[1]
+	}
+	if position < len(path) {
+		parts = append(parts, strconv.Quote(path[position:]))
+	}
+	target := strings.Join(parts, "+")
+	if len(query) > 0 {
+		return "withQuery(" + target + ", query)", nil
+	}
+	return target, nil
+}
+
+// query setzt die Parameter der Query. Optionale Parameter werden nur gesendet, wenn sie vom
+// Default oder, ohne Default, vom Nullwert abweichen.
+func (gen *generator) query(source *bytes.Buffer, query []parameter) {
+	if len(query) == 0 {
+		return
+	}
+	gen.imports["net/url"] = true
+	source.WriteString("query := url.Values{}\n")
+	for _, parameter := range query {
+		value, _ := gen.format(parameter.name, parameter.schema)
+		if parameter.required {
+			fmt.Fprintf(source, "query.Set(%q, %s)\n", parameter.name, value)
+			continue
+		}
+		fmt.Fprintf(source, "if %s {\nquery.Set(%q, %s)\n}\n", gen.differs(parameter), parameter.name, value)
+	}
+}
+
+func (gen *generator) differs(parameter parameter) string {
+	name := parameter.name
+	defaultValue, hasDefault := parameter.schema.values["default"]
+	switch parameter.schema.string("type") {
+	case "boolean":
+		if defaultValue == true {
+			return "!" + name
+		}
+		return name
+	case "integer":
+		if hasDefault {
+			return fmt.Sprintf("%s != %v", name, defaultValue)
+		}
+		return name + " != 0"
+	}
+	if parameter.schema.string("format") == "uuid" {
+		return name + " != uuid.Nil"
+	} else if parameter.schema.string("format") == "date-time" {
+		return "!" + name + ".IsZero()"
+	} else if hasDefault {
+		return fmt.Sprintf("%s != %q", name, defaultValue)
+	}
+	return name + ` != ""`
+}
+
+// format liefert den Ausdruck, der den Wert eines Parameters als Text darstellt
+func (gen *generator) format(name string, schema *object) (string, error) {
+	switch schema.string("type") {
+	case "boolean":
+		gen.imports["strconv"] = true
+		return "strconv.FormatBool(" + name + ")", nil
+	case "integer":
+		gen.imports["strconv"] = true
+		return "strconv.Itoa(" + name + ")", nil
+	case "string":
+		if schema.string("format") == "uuid" {
+			gen.imports["github.com/google/uuid"] = true
+			return name + ".String()", nil
+		} else if schema.string("format") == "date-time" {
+			gen.imports["time"] = true
+			return name + ".Format(time.RFC3339)", nil
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("unsupported parameter type %s for %s", schema.string("type"), name)
+}
+
+// success liefert die einzige dokumentierte Antwort mit einem Status 2xx
+func success(responses *object) (string, *object, error) {
+	var found []string
+	for _, status := range responses.keys {
+		if strings.HasPrefix(status, "2") {
+			found = append(found, status)
+		}
+	}
+	if len(found) != 1 {
+		return "", nil, fmt.Errorf("expected exactly one success response, got %v", found)
+	}
+	return found[0], responses.object(found[0]), nil
+}
+
+func jsonSchema(definition *object) (*object, bool) {
+	schema := definition.object("content").object("application/json").object("schema")
+	return schema, schema != nil
+}
+
+func comment(source *bytes.Buffer, indent string, text string) {
+	if text != "" {
+		fmt.Fprintf(source, "%s// %s\n", indent, text)
+	}
+}
+
+// exported macht aus einem Namen wie wrong-answer oder dueNext24h einen exportierten Bezeichner
+func exported(name string) string {
+	var result strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		} else if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+func unexported(name string) string {
+	name = strings.TrimLeft(name, "[]*")
+	if strings.HasPrefix(name, "map[") {
+		return "body"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// der eingecheckte Client muss dem Dokument entsprechen, sonst fehlt ein go generate
+func TestClientIsUpToDate(t *testing.T) {
+	out := t.TempDir()
+	utils.AssertNoError(t, run("../../pkg/api/openapi.json", out), "generate failed")
+	for _, name := range []string{"model.go", "operations.go"} {
+		generated, err := os.ReadFile(filepath.Join(out, name))
+		utils.AssertNoError(t, err, "read generated %s failed", name)
+		current, err := os.ReadFile(filepath.Join("../../pkg/client", name))
+		utils.AssertNoError(t, err, "read %s failed", name)
+		utils.Assert(t, string(generated) == string(current), "pkg/client/%s is outdated, run go generate ./pkg/client", name)
+	}
+}
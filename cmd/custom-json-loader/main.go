@@ -2,10 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/client"
 	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/utils"
 	"log"
+	"net/http"
 	"os"
 )
 
@@ -23,7 +27,13 @@ type JsonQuestion struct {
 	Explanation string       `json:"explanation"`
 }
 
+// store legt eine Frage an und meldet, ob sie neu ist
+type store func(question *questions.Question) (created bool, err error)
+
 func main() {
+	server := flag.String("server", "", "Fragen über die REST-API des Trainers anlegen, z.B. http://localhost:8080")
+	apiKey := flag.String("api-key", utils.GetEnvOrDefault("API_KEY", ""), "API-Key für -server")
+	flag.Parse()
 
 	var jsonQuestions []JsonQuestion
 
@@ -31,47 +41,82 @@ func main() {
 		log.Fatal(err)
 	} else if err = json.Unmarshal(data, &jsonQuestions); err != nil {
 		log.Fatal(err)
+	} else if *server != "" {
+		load(jsonQuestions, serverStore(client.New(*server).WithApiKey(*apiKey)))
 	} else if repo, err := questions.CreateRepo("config/custom-json/question.data"); err != nil {
 		log.Fatal(err)
 	} else {
+		defer repo.Close()
+		load(jsonQuestions, repoStore(repo))
+		fmt.Printf(", total: %d", repo.CountAll())
+	}
+}
 
-		cntNew := 0
-		cntOld := 0
-		cntFailed := 0
+func repoStore(repo questions.Repository) store {
+	return func(question *questions.Question) (bool, error) {
+		if repo.Contains(questions.ByQuestionText(question.Question)) {
+			return false, nil
+		}
+		_, err := repo.Save(question)
+		return true, err
+	}
+}
 
-		for _, jsonJquestion := range jsonQuestions {
-			var options []questions.Option
-			var answers []uuid.UUID
-			for _, jsonOption := range jsonJquestion.Options {
-				id := uuid.New()
-				options = append(options, questions.Option{
-					Id:     id,
-					Option: jsonOption.Text,
-				})
-				if jsonOption.Answer {
-					answers = append(answers, id)
-				}
-			}
+// serverStore legt die Frage über die API an, eine vorhandene Frage beantwortet der Server mit 409
+func serverStore(api *client.Client) store {
+	return func(question *questions.Question) (bool, error) {
+		choices := make([]client.Choice, 0, len(question.Options))
+		for _, option := range question.Options {
+			choices = append(choices, client.Choice{Id: option.Id, Text: option.Option})
+		}
+		_, err := api.CreateQuestion(client.NewQuestion{
+			Text:    question.Question,
+			Choices: choices,
+			Answer:  question.AnswerIds,
+			Media:   question.Media,
+			Tags:    question.Tags,
+		})
+		if client.IsStatus(err, http.StatusConflict) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+}
 
-			question := questions.CreateQuestion(
-				jsonJquestion.Question,
-				options,
-				answers,
-				jsonJquestion.Media,
-				jsonJquestion.Tags)
+func load(jsonQuestions []JsonQuestion, store store) {
+	cntNew := 0
+	cntOld := 0
+	cntFailed := 0
 
-			if !repo.Contains(questions.ByQuestionText(question.Question)) {
-				cntNew = cntNew + 1
-				_, err = repo.Save(question)
-				if err != nil {
-					log.Printf("\nFehler: %s\n", err)
-					cntFailed = cntFailed + 1
-				}
-			} else {
-				cntOld = cntOld + 1
+	for _, jsonJquestion := range jsonQuestions {
+		var options []questions.Option
+		var answers []uuid.UUID
+		for _, jsonOption := range jsonJquestion.Options {
+			id := uuid.New()
+			options = append(options, questions.Option{
+				Id:     id,
+				Option: jsonOption.Text,
+			})
+			if jsonOption.Answer {
+				answers = append(answers, id)
 			}
 		}
-		fmt.Printf("new %d, old %d, failed: %d, total: %d", cntNew, cntOld, cntFailed, repo.CountAll())
-	}
 
+		question := questions.CreateQuestion(
+			jsonJquestion.Question,
+			options,
+			answers,
+			jsonJquestion.Media,
+			jsonJquestion.Tags)
+
+		if created, err := store(question); err != nil {
+			log.Printf("\nFehler: %s\n", err)
+			cntFailed = cntFailed + 1
+		} else if created {
+			cntNew = cntNew + 1
+		} else {
+			cntOld = cntOld + 1
+		}
+	}
+	fmt.Printf("new %d, old %d, failed: %d", cntNew, cntOld, cntFailed)
 }
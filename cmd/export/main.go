@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/config"
 	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/storage"
 	"github.com/ohrenpiraten/go-collections/collections"
 	"github.com/ohrenpiraten/go-collections/predicates"
 	"log"
@@ -28,10 +30,17 @@ type record struct {
 
 func main() {
 
-	repo, err := questions.CreateRepo(
-		"data/question.data",
-		path.Join("config/ceh-12-cehtest.org", "question.data"),
-		path.Join("config/custom-json", "question.data"))
+	cfg, err := config.Configured()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dataDir, err := storage.OpenDataDir("data/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dataDir.Close()
+
+	repo, err := questions.CreateRepo(dataDir.File(storage.QuestionsFile), cfg.Sources...)
 
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/httputils"
+	"net/http"
+	"sync/atomic"
+)
+
+type healthDTO struct {
+	Status string `json:"status"`
+}
+
+// health beantwortet /healthz und /readyz selbst, alle anderen Requests gehen an den Handler der
+// Anwendung. Solange die Logs geladen werden und beim Herunterfahren ist die Instanz nicht bereit
+// und lehnt Requests mit 503 ab.
+type health struct {
+	handler atomic.Pointer[http.Handler]
+	ready   atomic.Bool
+}
+
+// Ready setzt den Handler der Anwendung, ab jetzt werden Requests angenommen
+func (h *health) Ready(handler http.Handler) {
+	h.handler.Store(&handler)
+	h.ready.Store(true)
+}
+
+// Stopping meldet die Instanz als nicht mehr bereit, laufende Requests werden noch beendet
+func (h *health) Stopping() {
+	h.ready.Store(false)
+}
+
+func (h *health) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	handler := h.handler.Load()
+	switch {
+	case request.URL.Path == "/healthz":
+		httputils.OkJson(writer, request, healthDTO{"ok"})
+	case request.URL.Path == "/readyz" && h.ready.Load():
+		httputils.OkJson(writer, request, healthDTO{"ready"})
+	case request.URL.Path == "/readyz":
+		httputils.SendJson(writer, request, http.StatusServiceUnavailable, healthDTO{"not ready"})
+	case handler == nil:
+		utils.SendProblem(writer, request, http.StatusServiceUnavailable, "server is starting or shutting down")
+	default:
+		(*handler).ServeHTTP(writer, request)
+	}
+}
@@ -1,81 +1,331 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/api"
+	"github.com/mwildt/ceh-utils/pkg/backup"
+	"github.com/mwildt/ceh-utils/pkg/config"
+	"github.com/mwildt/ceh-utils/pkg/dashboard"
+	"github.com/mwildt/ceh-utils/pkg/events"
 	"github.com/mwildt/ceh-utils/pkg/history"
+	"github.com/mwildt/ceh-utils/pkg/metrics"
 	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/reminder"
+	"github.com/mwildt/ceh-utils/pkg/reports"
+	"github.com/mwildt/ceh-utils/pkg/review"
+	"github.com/mwildt/ceh-utils/pkg/stats"
+	"github.com/mwildt/ceh-utils/pkg/storage"
 	"github.com/mwildt/ceh-utils/pkg/training"
 	"github.com/mwildt/ceh-utils/pkg/utils"
-	"github.com/mwildt/go-http/httputils"
 	"github.com/mwildt/go-http/routing"
+	"go.etcd.io/bbolt"
+	"io"
 	"log"
 	"net/http"
-	"path"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	dataPath := utils.GetEnvOrDefault("DATA_DIR", "data/")
-
-	questionRepo, err := questions.CreateRepo(
-		path.Join(dataPath, "question.data"),
-		path.Join("config/ceh-12-cehtest.org", "question.data"),
-		path.Join("config/custom-json", "question.data"))
+// run startet den Server sofort, damit /healthz erreichbar ist. Bereit ist die Instanz erst,
+// wenn alle Logs geladen sind. Bei SIGTERM oder SIGINT werden laufende Requests beendet, die
+// Event-Zustellung abgewartet und die Dateien geschlossen.
+func run() error {
+	logger := utils.NewStdLogger("trainer")
 
+	cfg, err := config.Configured()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	questionsController := questions.NewRestController(questionRepo)
-	trainingRepo, err := training.CreateFileRepository(path.Join(dataPath, "trainings.data"))
+	shutdownTimeout, err := time.ParseDuration(utils.GetEnvOrDefault("SHUTDOWN_TIMEOUT", "30s"))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	historyRepo, err := history.CreateRepo()
+	dataDir, err := storage.OpenDataDir(utils.GetEnvOrDefault("DATA_DIR", "data/"))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer dataDir.Close()
+
+	status := &health{}
+	server := &http.Server{Addr: utils.GetEnvOrDefault("LISTEN_ADDRESS", ":8080"), Handler: status}
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+
+	if err := configureEventTransport(); err != nil {
+		return errors.Join(err, server.Close())
+	}
+	repos, err := createRepositories(dataDir, cfg.Sources)
+	if err != nil {
+		return errors.Join(err, server.Close(), events.Close())
+	}
+	handler, stop, err := createHandler(repos, dataDir)
+	if err != nil {
+		return errors.Join(err, server.Close(), events.Close(), repos.Close())
+	}
+	status.Ready(handler)
+	logger.Info("ready, listening on %s", server.Addr)
+
+	select {
+	case err = <-serverErrors:
+		stop()
+		return errors.Join(err, events.Close(), repos.Close())
+	case received := <-signals:
+		logger.Info("%s received, shutting down", received)
+	}
+
+	status.Stopping()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err = server.Shutdown(ctx); err != nil {
+		logger.Error("http server did not shut down cleanly: %s", err.Error())
+	}
+	stop()
+	// Drain schließt den Transport nur, wenn alle Zustellungen abgeschlossen wurden
+	if err = events.Drain(ctx); err != nil {
+		logger.Error("event bus not drained: %s", err.Error())
+		err = events.Close()
+	}
+	err = errors.Join(err, repos.Close())
+	logger.Info("shutdown complete")
+	return err
+}
+
+// createHandler meldet die Subscriber an, startet die regelmäßigen Backups, die Analyse der
+// Lösungsschlüssel und die Erinnerungen und erstellt das Routing. stop beendet alle drei.
+func createHandler(repos repositories, dataDir *storage.DataDir) (handler http.Handler, stop func(), err error) {
+	stop = func() {}
+	questionRepo, trainingRepo, historyRepo := repos.questions, repos.trainings, repos.history
+	reportsController := reports.NewRestController(repos.reports, questionRepo, trainingRepo)
+	questionsController := questions.NewRestController(questionRepo).WithAction("reports", reportsController.PostForQuestion)
+	questions.RegisterMetrics(questionRepo)
+	training.RegisterMetrics(trainingRepo)
 
 	if err = history.Subscribe(historyRepo); err != nil {
-		log.Fatal(err)
+		return handler, stop, err
+	} else if err = reports.Notify(utils.NewStdLogger("reports")); err != nil {
+		return handler, stop, err
+	}
+	statsRepo := stats.CreateRepo()
+	if err = stats.Rebuild(context.Background(), statsRepo, trainingRepo); err != nil {
+		return handler, stop, err
+	} else if err = stats.Subscribe(statsRepo); err != nil {
+		return handler, stop, err
 	}
-	trainingController := training.NewRestController(trainingRepo, func(excluedIds []uuid.UUID) (training.Challenge, error) {
-		q, err := questionRepo.FindRandom(questions.IdNotIn(excluedIds))
-		return training.Challenge{
-			Id:     q.Id,
-			Answer: q.AnswerIds,
-		}, err
-	})
+	selection, err := training.ConfiguredSelection(questionRepo, trainingRepo, statsRepo)
+	if err != nil {
+		return handler, stop, err
+	}
+	trainingController := training.NewRestController(trainingRepo, training.SelectingQuestionProvider(questionRepo, selection))
 
 	if err = training.Subscribe(trainingRepo); err != nil {
-		log.Fatal(err)
+		return handler, stop, err
+	}
+
+	thresholds, err := review.ConfiguredThresholds()
+	if err != nil {
+		return handler, stop, err
+	}
+	reminders, err := reminder.ConfiguredReminders()
+	if err != nil {
+		return handler, stop, err
+	}
+	analyzer := review.NewAnalyzer(repos.reviews, statsRepo, questionRepo, thresholds)
+	stops := []func(){analyzer.Stop}
+	stop = func() {
+		for _, stopFunc := range stops {
+			stopFunc()
+		}
+	}
+
+	// die Erinnerungen liegen auch mit bbolt in einer eigenen Datei und kommen ins Backup
+	snapshotters := repos.snapshotters
+	if reminders.Enabled() {
+		reminders.State = dataDir.File(storage.RemindersFile)
+		scheduler := reminder.NewScheduler(reminders, trainingRepo)
+		stops = append(stops, scheduler.Stop)
+		snapshotters = append(snapshotters, scheduler)
+	}
+	if schedule, err := backup.ConfiguredSchedule(dataDir); err != nil {
+		stop()
+		return handler, func() {}, err
+	} else if schedule.Enabled() {
+		scheduler, err := backup.NewScheduler(schedule, snapshotters, questions.MediaPath)
+		if err != nil {
+			stop()
+			return handler, func() {}, err
+		}
+		stops = append(stops, scheduler.Stop)
 	}
 
 	baseHandler := routing.NewRouter()
 
 	baseHandler.Route(
-		routing.Filtering(requestLoggingFilter(utils.NewStdLogger("http-request-trace"))),
+		routing.Filtering(requestLoggingFilter(utils.NewStdLogger("http-request-trace"))).Filter(metrics.Instrument()),
+		func(router routing.Routing) {
+			router.HandleFunc(routing.Get("/metrics"), metrics.Handler())
+		},
+		api.Routing,
 		questionsController.Routing,
 		trainingController.Routing,
+		stats.NewRestController(statsRepo, questionRepo).Routing,
+		dashboard.NewRestController(trainingRepo, questionRepo).Routing,
+		review.NewRestController(repos.reviews, questionRepo, analyzer).Routing,
+		reportsController.Routing,
 		history.NewRestController(historyRepo).Routing,
+		backup.NewRestController(snapshotters, questions.MediaPath).Routing,
 		func(router routing.Routing) {
-			router.HandleFunc(routing.Path("/"), httputils.NotFound)
+			router.HandleFunc(routing.Path("/**"), func(w http.ResponseWriter, r *http.Request) {
+				utils.NotFound(w, r, "no route for "+r.URL.Path)
+			})
 		},
 	)
+	return baseHandler, stop, nil
+}
 
-	err = http.ListenAndServe(
-		utils.GetEnvOrDefault("LISTEN_ADDRESS", ":8080"),
-		baseHandler)
+type repositories struct {
+	questions    questions.Repository
+	trainings    training.Repository
+	history      history.Repository
+	reviews      review.Repository
+	reports      reports.Repository
+	snapshotters []storage.Snapshotter
+	db           *bbolt.DB
+}
 
-	if err != nil {
-		log.Fatal(err)
+// Close schließt die Repositories und zuletzt die gemeinsam genutzte Datenbank. Nach einem Fehler
+// in createRepositories sind nicht alle Repositories angelegt, fehlende werden übersprungen.
+func (repos repositories) Close() (err error) {
+	for _, closer := range []io.Closer{repos.questions, repos.trainings, repos.reviews, repos.reports} {
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+	}
+	if repos.db != nil {
+		err = errors.Join(err, repos.db.Close())
+	}
+	return err
+}
+
+// STORAGE_BACKEND=bolt speichert alle Daten in einer eingebetteten Datenbank (BOLT_FILE),
+// ansonsten werden die Log-Dateien im Datenverzeichnis verwendet.
+// Schlägt ein Repository fehl, werden die bereits angelegten wieder geschlossen.
+func createRepositories(dataDir *storage.DataDir, sources []questions.Source) (repos repositories, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, repos.Close())
+		}
+	}()
+	switch backend := utils.GetEnvOrDefault("STORAGE_BACKEND", "file"); backend {
+	case "file":
+		questionRepo, err := questions.CreateRepo(dataDir.File(storage.QuestionsFile), sources...)
+		if err != nil {
+			return repos, err
+		}
+		repos.questions = questionRepo
+		trainingRepo, err := training.CreateFileRepository(dataDir.File(storage.TrainingsFile))
+		if err != nil {
+			return repos, err
+		}
+		repos.trainings = trainingRepo
+		reviewRepo, err := review.CreateFileRepository(dataDir.File(storage.ReviewsFile))
+		if err != nil {
+			return repos, err
+		}
+		repos.reviews = reviewRepo
+		reportRepo, err := reports.CreateFileRepository(dataDir.File(storage.ReportsFile))
+		if err != nil {
+			return repos, err
+		}
+		repos.reports = reportRepo
+		repos.snapshotters = []storage.Snapshotter{questionRepo, trainingRepo.(storage.Snapshotter), reviewRepo.(storage.Snapshotter), reportRepo.(storage.Snapshotter)}
+		repos.history, err = history.CreateRepo()
+		return repos, err
+	case "bolt":
+		db, err := storage.OpenBolt(utils.GetEnvOrDefault("BOLT_FILE", dataDir.File(storage.BoltFile)))
+		if err != nil {
+			return repos, err
+		}
+		repos.db = db
+		repos.snapshotters = []storage.Snapshotter{storage.BoltSnapshotter(db)}
+		if repos.questions, err = questions.CreateBoltRepository(db, sources...); err != nil {
+			return repos, err
+		} else if repos.trainings, err = training.CreateBoltRepository(db); err != nil {
+			return repos, err
+		} else if repos.reviews, err = review.CreateBoltRepository(db); err != nil {
+			return repos, err
+		} else if repos.reports, err = reports.CreateBoltRepository(db); err != nil {
+			return repos, err
+		}
+		repos.history, err = history.CreateBoltRepository(db)
+		return repos, err
+	default:
+		return repos, fmt.Errorf("unknown storage backend %s", backend)
+	}
+}
+
+// EVENT_TRANSPORT=file verteilt die Events über eine gemeinsame Datei an alle Instanzen,
+// ansonsten bleibt es beim Transport innerhalb des Prozesses. Die Datei muss außerhalb der
+// Datenverzeichnisse liegen, da sich die Instanzen diese nicht teilen können.
+func configureEventTransport() error {
+	switch transport := utils.GetEnvOrDefault("EVENT_TRANSPORT", "inprocess"); transport {
+	case "inprocess":
+		return nil
+	case "file":
+		path := utils.GetEnvOrDefault("EVENT_TRANSPORT_FILE", "")
+		if path == "" {
+			return fmt.Errorf("EVENT_TRANSPORT_FILE is required for event transport file")
+		}
+		interval, err := time.ParseDuration(utils.GetEnvOrDefault("EVENT_POLL_INTERVAL", "200ms"))
+		if err != nil {
+			return err
+		}
+		fileTransport, err := events.NewFileTransport(path, interval)
+		if err != nil {
+			return err
+		}
+		return events.UseTransport(fileTransport)
+	default:
+		return fmt.Errorf("unknown event transport %s", transport)
 	}
 }
 
+// requestLoggingFilter legt Request-Id, Benutzer und Training-Id im Kontext ab, damit alle
+// Log-Ausgaben zu einem Request diese Felder enthalten. Eine mitgeschickte X-Request-Id wird
+// übernommen und in der Antwort zurückgegeben.
 func requestLoggingFilter(logger utils.Logger) routing.Filter {
 
 	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-		logger.Info("%s %s", r.Method, r.URL.String())
-		next(w, r)
+		requestId := r.Header.Get("X-Request-Id")
+		if requestId == "" {
+			requestId = uuid.NewString()
+		}
+		w.Header().Set("X-Request-Id", requestId)
+		ctx := utils.WithLogField(r.Context(), utils.LogFieldRequestId, requestId)
+		if user := r.Header.Get("x-user"); user != "" {
+			ctx = utils.WithLogField(ctx, utils.LogFieldUser, user)
+		}
+		if trainingId, exists := routing.GetParameter(ctx, "trainingId"); exists {
+			ctx = utils.WithLogField(ctx, utils.LogFieldTrainingId, trainingId)
+		}
+		logger.WithContext(ctx).Info("%s %s", r.Method, r.URL.String())
+		next(w, r.WithContext(ctx))
 	}
 }
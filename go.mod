@@ -6,5 +6,8 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/mwildt/go-http v1.3.0
 	github.com/ohrenpiraten/go-collections v1.0.8
+	go.etcd.io/bbolt v1.3.10
+	gopkg.in/yaml.v3 v3.0.1
 )
 
+require golang.org/x/sys v0.4.0 // indirect
@@ -0,0 +1,23 @@
+package api
+
+import (
+	_ "embed"
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+)
+
+// Spec ist das OpenAPI-Dokument der REST-API. Änderungen an den Handlern werden hier nachgezogen,
+// die Contract-Tests prüfen, dass Handler und Dokument übereinstimmen.
+//
+//go:embed openapi.json
+var Spec []byte
+
+func Routing(router routing.Routing) {
+	router.HandleFunc(routing.Get("/api/openapi.json"), GetSpec)
+}
+
+func GetSpec(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(Spec)
+}
@@ -0,0 +1,560 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/api"
+	"github.com/mwildt/ceh-utils/pkg/backup"
+	"github.com/mwildt/ceh-utils/pkg/client"
+	"github.com/mwildt/ceh-utils/pkg/dashboard"
+	"github.com/mwildt/ceh-utils/pkg/history"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/reports"
+	"github.com/mwildt/ceh-utils/pkg/review"
+	"github.com/mwildt/ceh-utils/pkg/stats"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/routing"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exchange ist ein vom Client gesendeter Request mit der Antwort des Servers
+type exchange struct {
+	method       string
+	path         string
+	requestBody  []byte
+	status       int
+	contentType  string
+	responseBody []byte
+}
+
+type recordingTransport struct {
+	exchanges []exchange
+}
+
+func (transport *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if request.Body != nil {
+		requestBody, _ = io.ReadAll(request.Body)
+		request.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	response, err := http.DefaultTransport.RoundTrip(request)
+	if err != nil {
+		return response, err
+	}
+	responseBody, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+	transport.exchanges = append(transport.exchanges, exchange{
+		method:       request.Method,
+		path:         request.URL.Path,
+		requestBody:  requestBody,
+		status:       response.StatusCode,
+		contentType:  response.Header.Get("Content-Type"),
+		responseBody: responseBody,
+	})
+	return response, err
+}
+
+// createServer liefert den Server und die Lösungen der vorhandenen Fragen
+func createServer(t *testing.T) (*httptest.Server, map[uuid.UUID][]uuid.UUID) {
+	dir := t.TempDir()
+	upstreamPath := filepath.Join(dir, "upstream.data")
+	upstream, err := questions.CreateRepo(upstreamPath)
+	utils.AssertNoError(t, err, "create upstream failed")
+	options := []questions.Option{{Id: uuid.New(), Option: "a"}, {Id: uuid.New(), Option: "b"}}
+	question, err := upstream.Save(questions.CreateQuestion("upstream question", options, []uuid.UUID{options[0].Id}, nil, nil))
+	utils.AssertNoError(t, err, "save upstream question failed")
+	utils.AssertNoError(t, upstream.Close(), "close upstream failed")
+
+	questionRepo, err := questions.CreateRepo(filepath.Join(dir, "question.data"), questions.Source{Name: "upstream", Path: upstreamPath})
+	utils.AssertNoError(t, err, "create question repository failed")
+	t.Cleanup(func() { _ = questionRepo.Close() })
+	trainingRepo, err := training.CreateFileRepository(filepath.Join(dir, "trainings.data"))
+	utils.AssertNoError(t, err, "create training repository failed")
+	t.Cleanup(func() { _ = trainingRepo.Close() })
+	historyRepo, err := history.CreateRepo()
+	utils.AssertNoError(t, err, "create history repository failed")
+	utils.AssertNoError(t, history.Subscribe(historyRepo), "subscribe history failed")
+	statsRepo := stats.CreateRepo()
+	utils.AssertNoError(t, stats.Subscribe(statsRepo), "subscribe stats failed")
+	reviewRepo, err := review.CreateFileRepository(filepath.Join(dir, "reviews.data"))
+	utils.AssertNoError(t, err, "create review repository failed")
+	t.Cleanup(func() { _ = reviewRepo.Close() })
+	analyzer := review.NewAnalyzer(reviewRepo, statsRepo, questionRepo, review.Thresholds{MinAttempts: 1, Ratio: 1})
+	reportRepo, err := reports.CreateFileRepository(filepath.Join(dir, "reports.data"))
+	utils.AssertNoError(t, err, "create report repository failed")
+	t.Cleanup(func() { _ = reportRepo.Close() })
+	reportsController := reports.NewRestController(reportRepo, questionRepo, trainingRepo)
+
+	mediaDir := filepath.Join(dir, "media")
+	utils.AssertNoError(t, os.Mkdir(mediaDir, 0o755), "create media directory failed")
+
+	router := routing.NewRouter(
+		api.Routing,
+		questions.NewRestController(questionRepo).WithAction("reports", reportsController.PostForQuestion).Routing,
+		training.NewRestController(trainingRepo, training.QuestionProvider(questionRepo)).Routing,
+		history.NewRestController(historyRepo).Routing,
+		stats.NewRestController(statsRepo, questionRepo).Routing,
+		dashboard.NewRestController(trainingRepo, questionRepo).Routing,
+		review.NewRestController(reviewRepo, questionRepo, analyzer).Routing,
+		reportsController.Routing,
+		backup.NewRestController([]storage.Snapshotter{questionRepo, trainingRepo.(storage.Snapshotter)}, mediaDir).Routing,
+	)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, map[uuid.UUID][]uuid.UUID{question.Id: question.AnswerIds}
+}
+
+// TestHandlersMatchOpenApi ruft jede dokumentierte Operation über den Client auf und prüft Status
+// und Inhalt der Antworten gegen das OpenAPI-Dokument
+func TestHandlersMatchOpenApi(t *testing.T) {
+	t.Setenv("API_KEY", "contract")
+	server, answers := createServer(t)
+	transport := &recordingTransport{}
+	c := client.New(server.URL).WithApiKey("contract").WithUser("alice")
+	c.HttpClient = &http.Client{Transport: transport}
+
+	_, err := c.GetOpenApi()
+	utils.AssertNoError(t, err, "get openapi failed")
+
+	choices := []client.Choice{{Id: uuid.New(), Text: "yes"}, {Id: uuid.New(), Text: "no"}}
+	created, err := c.CreateQuestion(client.NewQuestion{Text: "local question", Choices: choices, Answer: []uuid.UUID{choices[0].Id}, Tags: []string{"local"}})
+	utils.AssertNoError(t, err, "create question failed")
+	answers[created.Id] = []uuid.UUID{choices[0].Id}
+	_, err = c.CreateQuestion(client.NewQuestion{Text: "local question", Choices: choices, Answer: []uuid.UUID{choices[0].Id}})
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for duplicate question, got %v", err)
+	_, err = c.CreateQuestion(client.NewQuestion{Text: "no answer", Choices: choices})
+	utils.Assert(t, client.IsStatus(err, http.StatusUnprocessableEntity), "expected unprocessable entity without answer, got %v", err)
+	_, err = c.CreateQuestion(client.NewQuestion{Text: "one choice", Choices: choices[:1], Answer: []uuid.UUID{choices[0].Id}})
+	var statusError *client.StatusError
+	utils.Assert(t, errors.As(err, &statusError) && statusError.Problem.Detail == "options must be min 2", "expected validation message, got %v", err)
+
+	all, err := c.GetQuestions()
+	utils.AssertNoError(t, err, "get questions failed")
+	utils.Assert(t, len(all) == 2, "expected 2 questions, got %d", len(all))
+	_, err = c.GetQuestion(created.Id)
+	utils.AssertNoError(t, err, "get question failed")
+	_, err = c.GetQuestion(uuid.New())
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found, got %v", err)
+
+	var upstream client.Question
+	for _, question := range all {
+		if question.Source == "upstream" {
+			upstream = question
+		}
+	}
+	_, err = c.UpdateQuestion(upstream.Id, client.QuestionUpdate{Text: "changed", Choices: upstream.Choices})
+	utils.AssertNoError(t, err, "update question failed")
+	overrides, err := c.GetQuestionOverrides(false)
+	utils.AssertNoError(t, err, "get overrides failed")
+	utils.Assert(t, len(overrides) == 1, "expected 1 override, got %d", len(overrides))
+	_, err = c.ResetQuestion(upstream.Id)
+	utils.AssertNoError(t, err, "reset question failed")
+	_, err = c.ResetQuestion(upstream.Id)
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for second reset, got %v", err)
+
+	_, err = c.GetMedia("missing.jpg")
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected missing media, got %v", err)
+
+	trainingCreated, err := c.CreateTraining(client.NewTraining{})
+	utils.AssertNoError(t, err, "create training failed")
+	_, err = c.GetTrainings(false)
+	utils.AssertNoError(t, err, "get trainings failed")
+	current, err := c.GetTraining(trainingCreated.Id, time.Time{})
+	utils.AssertNoError(t, err, "get training failed")
+	utils.Assert(t, current.State == "active", "expected active training, got %s", current.State)
+	utils.Assert(t, current.Owner == "alice", "expected owner from x-user, got %q", current.Owner)
+	_, err = c.CreateTraining(client.NewTraining{OnExhausted: "never"})
+	utils.Assert(t, client.IsStatus(err, http.StatusUnprocessableEntity), "expected unprocessable entity for unknown policy, got %v", err)
+	tagged, err := c.CreateTraining(client.NewTraining{Tags: []string{"local"}, OnExhausted: "widen"})
+	utils.AssertNoError(t, err, "create tagged training failed")
+	taggedTraining, err := c.GetTraining(tagged.Id, time.Time{})
+	utils.AssertNoError(t, err, "get tagged training failed")
+	utils.Assert(t, taggedTraining.Challenge == created.Id, "expected tagged question, got %s", taggedTraining.Challenge)
+	utils.Assert(t, taggedTraining.OnExhausted == "widen", "expected widen policy, got %s", taggedTraining.OnExhausted)
+	report, err := c.ReportQuestion(created.Id, client.NewReport{Category: "typo", Text: "yse instead of yes", TrainingId: &tagged.Id})
+	utils.AssertNoError(t, err, "report question failed")
+	utils.Assert(t, report.Status == "open" && report.Reporter == "alice", "expected open report by alice, got %+v", report)
+	_, err = c.ReportQuestion(created.Id, client.NewReport{Category: "boring"})
+	utils.Assert(t, client.IsStatus(err, http.StatusUnprocessableEntity), "expected unprocessable entity for unknown category, got %v", err)
+	unknownTraining := uuid.New()
+	_, err = c.ReportQuestion(created.Id, client.NewReport{Category: "unclear", TrainingId: &unknownTraining})
+	utils.Assert(t, client.IsStatus(err, http.StatusUnprocessableEntity), "expected unprocessable entity for unknown training, got %v", err)
+	_, err = c.ReportQuestion(uuid.New(), client.NewReport{Category: "unclear"})
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found for unknown question, got %v", err)
+	openReports, err := c.GetReports("open", created.Id)
+	utils.AssertNoError(t, err, "get reports failed")
+	utils.Assert(t, len(openReports) == 1 && openReports[0].Id == report.Id, "expected the new report, got %v", openReports)
+	_, err = c.GetReports("done", uuid.Nil)
+	utils.Assert(t, client.IsStatus(err, http.StatusBadRequest), "expected bad request for unknown status, got %v", err)
+	resolvedReport, err := c.ResolveReport(report.Id, client.ReportResolution{Resolution: "typo fixed"})
+	utils.AssertNoError(t, err, "resolve report failed")
+	utils.Assert(t, resolvedReport.Status == "resolved", "expected resolved report, got %s", resolvedReport.Status)
+	_, err = c.ResolveReport(report.Id, client.ReportResolution{})
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for resolved report, got %v", err)
+	_, err = c.ResolveReport(uuid.New(), client.ReportResolution{})
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found for unknown report, got %v", err)
+
+	_, err = c.AnswerChallenge(trainingCreated.Id, client.Answer{})
+	utils.Assert(t, client.IsStatus(err, http.StatusUnprocessableEntity), "expected unprocessable entity without answer, got %v", err)
+	result, err := c.AnswerChallenge(trainingCreated.Id, client.Answer{Answer: answers[current.Challenge]})
+	utils.AssertNoError(t, err, "answer challenge failed")
+	utils.Assert(t, result.Success, "expected correct answer")
+	_, err = c.GetTraining(trainingCreated.Id, current.Updated.Add(time.Second))
+	utils.AssertNoError(t, err, "get training at failed")
+	_, err = c.GetTrainingChallenges(trainingCreated.Id)
+	utils.AssertNoError(t, err, "get challenges failed")
+	timeline, err := c.GetTrainingTimeline(trainingCreated.Id)
+	utils.AssertNoError(t, err, "get timeline failed")
+	utils.Assert(t, len(timeline.Changes) > 1, "expected changes for creation and answer, got %d", len(timeline.Changes))
+
+	deadline := time.Now().Add(5 * time.Second)
+	hist, err := c.GetHistory(trainingCreated.Id)
+	for (err != nil || hist.Total == 0) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		hist, err = c.GetHistory(trainingCreated.Id)
+	}
+	utils.AssertNoError(t, err, "get history failed")
+	_, err = c.GetHistoryItem(trainingCreated.Id, 0)
+	utils.AssertNoError(t, err, "get history item failed")
+
+	questionStats, err := c.GetQuestionStats(current.Challenge)
+	for (err != nil || questionStats.Attempts == 0) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		questionStats, err = c.GetQuestionStats(current.Challenge)
+	}
+	utils.AssertNoError(t, err, "get question stats failed")
+	utils.Assert(t, questionStats.FirstTrySuccessRate == 1, "expected first try success, got %f", questionStats.FirstTrySuccessRate)
+	_, err = c.GetQuestionStats(uuid.New())
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found for unknown question, got %v", err)
+	hardest, err := c.GetHardestQuestions(5, 1)
+	utils.AssertNoError(t, err, "get hardest questions failed")
+	utils.Assert(t, len(hardest) == 1, "expected 1 answered question, got %d", len(hardest))
+	_, err = c.GetHardestQuestions(0, 1)
+	utils.Assert(t, client.IsStatus(err, http.StatusBadRequest), "expected bad request for limit 0, got %v", err)
+
+	_, err = c.AnswerChallenge(tagged.Id, client.Answer{Answer: []uuid.UUID{choices[1].Id}})
+	utils.AssertNoError(t, err, "wrong answer failed")
+	wrongStats, err := c.GetQuestionStats(created.Id)
+	for (err != nil || wrongStats.Attempts == 0) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		wrongStats, err = c.GetQuestionStats(created.Id)
+	}
+	utils.AssertNoError(t, err, "get stats of wrongly answered question failed")
+	flagged, err := c.AnalyzeReviews()
+	utils.AssertNoError(t, err, "analyze reviews failed")
+	utils.Assert(t, len(flagged) == 1 && flagged[0].QuestionId == created.Id, "expected flagged local question, got %v", flagged)
+	utils.Assert(t, flagged[0].WrongChoice.Id == choices[1].Id, "expected wrong choice %s, got %s", choices[1].Id, flagged[0].WrongChoice.Id)
+	open, err := c.GetReviews("open")
+	utils.AssertNoError(t, err, "get reviews failed")
+	utils.Assert(t, len(open) == 1, "expected 1 open review, got %d", len(open))
+	_, err = c.GetReviews("unknown")
+	utils.Assert(t, client.IsStatus(err, http.StatusBadRequest), "expected bad request for unknown status, got %v", err)
+	confirmed, err := c.ConfirmReview(created.Id, client.ReviewDecision{Note: "key fixed"})
+	utils.AssertNoError(t, err, "confirm review failed")
+	utils.Assert(t, confirmed.Status == "confirmed" && confirmed.Note == "key fixed", "expected confirmed review, got %v", confirmed)
+	_, err = c.DismissReview(created.Id, client.ReviewDecision{})
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for resolved review, got %v", err)
+	_, err = c.DismissReview(uuid.New(), client.ReviewDecision{})
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found for unflagged question, got %v", err)
+
+	userDashboard, err := c.GetDashboard("UTC")
+	utils.AssertNoError(t, err, "get dashboard failed")
+	utils.Assert(t, userDashboard.Trainings == 2 && userDashboard.Streak == 1, "expected 2 trainings studied today, got %+v", userDashboard)
+	utils.Assert(t, userDashboard.Daily[len(userDashboard.Daily)-1].Answers == 2, "expected 2 answers today, got %+v", userDashboard.Daily)
+	utils.Assert(t, len(userDashboard.Tags) == 1 && userDashboard.Tags[0].Tag == "local", "expected mastery of tag local, got %+v", userDashboard.Tags)
+	_, err = c.GetDashboard("Mars/Olympus_Mons")
+	utils.Assert(t, client.IsStatus(err, http.StatusBadRequest), "expected bad request for unknown time zone, got %v", err)
+	_, err = client.New(server.URL).GetDashboard("")
+	utils.Assert(t, client.IsStatus(err, http.StatusBadRequest), "expected bad request without user, got %v", err)
+
+	due, err := c.GetTrainingDue(trainingCreated.Id)
+	utils.AssertNoError(t, err, "get due challenges failed")
+	utils.Assert(t, len(due.Challenges) == 2 && due.Challenges[0].Current && due.Challenges[0].Overdue, "expected the current challenge first, got %+v", due.Challenges)
+	utils.Assert(t, due.Challenges[1].Level == 1 && !due.Challenges[1].Overdue, "expected the answered first challenge on level 1, got %+v", due.Challenges[1])
+	skipped, err := c.SkipToDue(trainingCreated.Id)
+	utils.AssertNoError(t, err, "skip to due failed")
+	utils.Assert(t, skipped.Challenge == due.Challenges[1].Id, "expected skip to the first challenge, got %s", skipped.Challenge)
+	_, err = c.GetTrainingDue(uuid.New())
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found for unknown training, got %v", err)
+
+	bookmarks, err := c.BookmarkChallenge(trainingCreated.Id, client.Bookmark{})
+	utils.AssertNoError(t, err, "bookmark current challenge failed")
+	utils.Assert(t, len(bookmarks.Challenges) == 1 && bookmarks.Challenges[0].Id == skipped.Challenge && bookmarks.Challenges[0].Bookmarked, "expected bookmarked current challenge, got %+v", bookmarks.Challenges)
+	unknownChallenge := uuid.New()
+	_, err = c.BookmarkChallenge(trainingCreated.Id, client.Bookmark{ChallengeId: &unknownChallenge})
+	utils.Assert(t, client.IsStatus(err, http.StatusUnprocessableEntity), "expected unprocessable entity for unknown challenge, got %v", err)
+	deferred, err := c.SkipChallenge(trainingCreated.Id)
+	utils.AssertNoError(t, err, "skip challenge failed")
+	utils.Assert(t, deferred.Stats.Skipped == 1, "expected skip counted in stats, got %+v", deferred.Stats)
+	bookmarks, err = c.GetTrainingBookmarks(trainingCreated.Id)
+	utils.AssertNoError(t, err, "get bookmarks failed")
+	utils.Assert(t, len(bookmarks.Challenges) == 1, "expected bookmark to survive the skip, got %+v", bookmarks.Challenges)
+	bookmarks, err = c.UnbookmarkChallenge(trainingCreated.Id, client.Bookmark{ChallengeId: &skipped.Challenge})
+	utils.AssertNoError(t, err, "remove bookmark failed")
+	utils.Assert(t, len(bookmarks.Challenges) == 0, "expected no bookmarks, got %+v", bookmarks.Challenges)
+	paused, err := c.PauseTraining(trainingCreated.Id)
+	utils.AssertNoError(t, err, "pause training failed")
+	utils.Assert(t, paused.State == "paused", "expected paused training, got %s", paused.State)
+	_, err = c.AnswerChallenge(trainingCreated.Id, client.Answer{Answer: answers[paused.Challenge]})
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for answer to paused training, got %v", err)
+	_, err = c.PauseTraining(trainingCreated.Id)
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for second pause, got %v", err)
+	resumed, err := c.ResumeTraining(trainingCreated.Id)
+	utils.AssertNoError(t, err, "resume training failed")
+	utils.Assert(t, resumed.State == "active", "expected active training, got %s", resumed.State)
+	_, err = c.ResumeTraining(trainingCreated.Id)
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for resume of running training, got %v", err)
+	reset, err := c.ResetTraining(trainingCreated.Id)
+	utils.AssertNoError(t, err, "reset training failed")
+	utils.Assert(t, reset.CurrentLevel == 0 && reset.ChallengeStats.Proceeding == 0 && reset.ChallengeStats.Done == 0, "expected all challenges on level 0, got %+v", reset)
+	archived, err := c.ArchiveTraining(trainingCreated.Id)
+	utils.AssertNoError(t, err, "archive training failed")
+	utils.Assert(t, archived.State == "archived", "expected archived training, got %s", archived.State)
+	_, err = c.ArchiveTraining(trainingCreated.Id)
+	utils.Assert(t, client.IsStatus(err, http.StatusConflict), "expected conflict for second archive, got %v", err)
+	running, err := c.GetTrainings(false)
+	utils.AssertNoError(t, err, "get running trainings failed")
+	for _, listed := range running {
+		utils.Assert(t, listed.Id != trainingCreated.Id, "archived training must not be listed by default")
+	}
+	withArchived, err := c.GetTrainings(true)
+	utils.AssertNoError(t, err, "get all trainings failed")
+	utils.Assert(t, len(withArchived) == len(running)+1, "expected archived training in full list, got %d of %d", len(withArchived), len(running))
+	archivedHistory, err := c.GetHistory(trainingCreated.Id)
+	utils.AssertNoError(t, err, "get history of archived training failed")
+	utils.Assert(t, archivedHistory.State == "archived", "expected archived history, got %s", archivedHistory.State)
+	utils.AssertNoError(t, c.DeleteTraining(trainingCreated.Id), "delete training failed")
+	_, err = c.GetTraining(trainingCreated.Id, time.Time{})
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found for deleted training, got %v", err)
+	_, err = c.GetHistory(trainingCreated.Id)
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected history to be deleted with the training, got %v", err)
+	err = c.DeleteTraining(trainingCreated.Id)
+	utils.Assert(t, client.IsStatus(err, http.StatusNotFound), "expected not found for second delete, got %v", err)
+
+	_, err = c.CreateBackup()
+	utils.AssertNoError(t, err, "create backup failed")
+	_, err = client.New(server.URL).CreateBackup()
+	utils.Assert(t, client.IsStatus(err, http.StatusUnauthorized), "expected unauthorized without api key, got %v", err)
+
+	var spec map[string]any
+	utils.AssertNoError(t, json.Unmarshal(api.Spec, &spec), "parse openapi failed")
+	documented := operations(spec)
+	exercised := map[string]bool{}
+	for _, exchange := range transport.exchanges {
+		id, operation, found := findOperation(documented, exchange.method, exchange.path)
+		if !found {
+			t.Errorf("%s %s is not documented", exchange.method, exchange.path)
+			continue
+		}
+		exercised[id] = true
+		checkExchange(t, spec, id, operation, exchange)
+	}
+	for id := range documented {
+		if !exercised[id] {
+			t.Errorf("operation %s is documented but not exercised", id)
+		}
+	}
+}
+
+type operation struct {
+	method  string
+	pattern *regexp.Regexp
+	spec    map[string]any
+}
+
+func operations(spec map[string]any) map[string]operation {
+	parameter := regexp.MustCompile(`\\\{[^}]+\\\}`)
+	result := map[string]operation{}
+	for path, item := range spec["paths"].(map[string]any) {
+		pattern := regexp.MustCompile("^" + parameter.ReplaceAllString(regexp.QuoteMeta(path), "[^/]+") + "$")
+		if strings.HasSuffix(path, "{path}") {
+			pattern = regexp.MustCompile("^" + regexp.QuoteMeta(strings.TrimSuffix(path, "{path}")) + ".+$")
+		}
+		for method, value := range item.(map[string]any) {
+			if definition, ok := value.(map[string]any); ok && definition["operationId"] != nil {
+				result[definition["operationId"].(string)] = operation{strings.ToUpper(method), pattern, definition}
+			}
+		}
+	}
+	return result
+}
+
+// findOperation bevorzugt feste Pfade gegenüber Pfaden mit Parametern (overrides vor {questionId})
+func findOperation(documented map[string]operation, method string, path string) (string, operation, bool) {
+	var candidates []string
+	for id, operation := range documented {
+		if operation.method == method && operation.pattern.MatchString(path) {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", operation{}, false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return strings.Count(documented[candidates[i]].pattern.String(), "[^/]+") < strings.Count(documented[candidates[j]].pattern.String(), "[^/]+")
+	})
+	return candidates[0], documented[candidates[0]], true
+}
+
+func checkExchange(t *testing.T, spec map[string]any, id string, operation operation, exchange exchange) {
+	if body, ok := operation.spec["requestBody"].(map[string]any); ok && exchange.status < 300 && (len(exchange.requestBody) > 0 || body["required"] == true) {
+		if schema, ok := jsonSchema(body); ok {
+			checkJson(t, spec, fmt.Sprintf("%s request", id), schema, exchange.requestBody)
+		}
+	}
+	response, ok := operation.spec["responses"].(map[string]any)[fmt.Sprint(exchange.status)].(map[string]any)
+	if !ok {
+		t.Errorf("%s: status %d is not documented", id, exchange.status)
+		return
+	}
+	content, ok := response["content"].(map[string]any)
+	if !ok {
+		return
+	}
+	for mediaType, media := range content {
+		if strings.HasPrefix(exchange.contentType, mediaType) && strings.HasSuffix(mediaType, "json") {
+			checkJson(t, spec, fmt.Sprintf("%s %d", id, exchange.status), media.(map[string]any)["schema"].(map[string]any), exchange.responseBody)
+			return
+		} else if strings.HasPrefix(exchange.contentType, mediaType) {
+			return
+		}
+	}
+	t.Errorf("%s %d: content type %s is not documented", id, exchange.status, exchange.contentType)
+}
+
+func jsonSchema(definition map[string]any) (map[string]any, bool) {
+	content, ok := definition["content"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	media, ok := content["application/json"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	schema, ok := media["schema"].(map[string]any)
+	return schema, ok
+}
+
+func checkJson(t *testing.T, spec map[string]any, name string, schema map[string]any, data []byte) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Errorf("%s: invalid json: %s", name, err)
+		return
+	}
+	for _, violation := range validate(spec, schema, value, "$") {
+		t.Errorf("%s: %s", name, violation)
+	}
+}
+
+// validate prüft value gegen das Schema. Unterstützt wird nur, was das Dokument verwendet;
+// Properties, die in einem Schema mit properties nicht dokumentiert sind, gelten als Verletzung.
+func validate(spec map[string]any, schema map[string]any, value any, path string) (violations []string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		return validate(spec, resolve(spec, ref), value, path)
+	}
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: null is not allowed", path)}
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object", path)}
+		}
+		properties, closed := schema["properties"].(map[string]any)
+		for _, required := range asList(schema["required"]) {
+			if _, exists := object[required.(string)]; !exists {
+				violations = append(violations, fmt.Sprintf("%s: missing property %s", path, required))
+			}
+		}
+		for key, property := range object {
+			if definition, documented := properties[key]; !documented && closed {
+				violations = append(violations, fmt.Sprintf("%s: undocumented property %s", path, key))
+			} else if documented {
+				violations = append(violations, validate(spec, definition.(map[string]any), property, path+"."+key)...)
+			}
+		}
+	case "array":
+		list, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array", path)}
+		}
+		for i, item := range list {
+			violations = append(violations, validate(spec, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected string", path)}
+		}
+		if enum := asList(schema["enum"]); len(enum) > 0 && !contains(enum, text) {
+			violations = append(violations, fmt.Sprintf("%s: %q is not one of %v", path, text, enum))
+		}
+		if schema["format"] == "uuid" {
+			if _, err := uuid.Parse(text); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %q is not a uuid", path, text))
+			}
+		} else if schema["format"] == "date" {
+			if _, err := time.Parse(time.DateOnly, text); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %q is not a date", path, text))
+			}
+		} else if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, text); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %q is not a date-time", path, text))
+			}
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != float64(int64(number)) {
+			return []string{fmt.Sprintf("%s: expected integer", path)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: expected number", path)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected boolean", path)}
+		}
+	}
+	return violations
+}
+
+func resolve(spec map[string]any, ref string) map[string]any {
+	var node any = spec
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		node = node.(map[string]any)[segment]
+	}
+	return node.(map[string]any)
+}
+
+func asList(value any) []any {
+	list, _ := value.([]any)
+	return list
+}
+
+func contains(list []any, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
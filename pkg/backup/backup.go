@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	FormatVersion = 1
+	manifestName  = "manifest.json"
+	dataPrefix    = "data/"
+	mediaPrefix   = "media/"
+)
+
+// Manifest wird als letzter Eintrag in das Archiv geschrieben und enthält die Prüfsummen
+// aller vorher geschriebenen Dateien
+type Manifest struct {
+	Version int         `json:"version"`
+	Created time.Time   `json:"created"`
+	Files   []FileEntry `json:"files"`
+}
+
+type FileEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// Write schreibt ein tar.gz mit dem Stand aller Repositories und den Medien. Jeder Snapshotter
+// ist für sich konsistent, die Snapshotter werden aber nacheinander übernommen und Schreibzugriffe
+// laufen dazwischen weiter. Ein Backup der Log-Dateien kann daher eine Änderung in einem
+// Repository enthalten, deren Folgeänderung in einem anderen fehlt (etwa ein Training mit einer
+// Frage, die in den gesicherten Fragen noch fehlt). Mit bbolt gibt es nur einen Snapshotter.
+func Write(writer io.Writer, snapshotters []storage.Snapshotter, mediaDir string) (manifest Manifest, err error) {
+	manifest = Manifest{Version: FormatVersion, Created: time.Now().UTC()}
+	compressed := gzip.NewWriter(writer)
+	archive := tar.NewWriter(compressed)
+
+	add := func(name string, size int64, content io.Reader) error {
+		hash := sha256.New()
+		if err := archive.WriteHeader(&tar.Header{Name: name, Size: size, Mode: 0644, ModTime: manifest.Created}); err != nil {
+			return err
+		} else if _, err := io.Copy(archive, io.TeeReader(content, hash)); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, FileEntry{Name: name, Size: size, Sha256: hex.EncodeToString(hash.Sum(nil))})
+		return nil
+	}
+
+	for _, snapshotter := range snapshotters {
+		if err = snapshotter.Snapshot(func(name string, size int64, content io.Reader) error {
+			return add(dataPrefix+name, size, content)
+		}); err != nil {
+			return manifest, err
+		}
+	}
+	if err = addMedia(mediaDir, add); err != nil {
+		return manifest, err
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err = archive.WriteHeader(&tar.Header{Name: manifestName, Size: int64(len(content)), Mode: 0644, ModTime: manifest.Created}); err != nil {
+		return manifest, err
+	} else if _, err = archive.Write(content); err != nil {
+		return manifest, err
+	}
+	return manifest, errors.Join(archive.Close(), compressed.Close())
+}
+
+func addMedia(mediaDir string, add storage.SnapshotWriter) error {
+	if mediaDir == "" {
+		return nil
+	}
+	return filepath.WalkDir(mediaDir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relative, err := filepath.Rel(mediaDir, file)
+		if err != nil {
+			return err
+		}
+		return storage.SnapshotFile(file, mediaPrefix+filepath.ToSlash(relative), add)
+	})
+}
+
+// WriteFile schreibt das Backup zunächst in eine temporäre Datei, damit nie ein halbes Archiv
+// unter dem endgültigen Namen liegt
+func WriteFile(target string, snapshotters []storage.Snapshotter, mediaDir string) (manifest Manifest, err error) {
+	file, err := os.CreateTemp(filepath.Dir(target), ".backup-*")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.Remove(file.Name())
+	if manifest, err = Write(file, snapshotters, mediaDir); err != nil {
+		return manifest, errors.Join(err, file.Close())
+	}
+	if err = file.Sync(); err != nil {
+		return manifest, errors.Join(err, file.Close())
+	} else if err = file.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, os.Rename(file.Name(), target)
+}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fileSnapshotter struct {
+	path string
+	name string
+}
+
+func (snapshotter fileSnapshotter) Snapshot(write storage.SnapshotWriter) error {
+	return storage.SnapshotFile(snapshotter.path, snapshotter.name, write)
+}
+
+type contentSnapshotter map[string]string
+
+func (snapshotter contentSnapshotter) Snapshot(write storage.SnapshotWriter) error {
+	for name, content := range snapshotter {
+		if err := write(name, int64(len(content)), strings.NewReader(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLog(t *testing.T, path string, records ...string) {
+	log, err := utils.BinaryCodec.Create(path, utils.SyncNever)
+	utils.AssertNoError(t, err, "create log failed")
+	defer log.Close()
+	for _, record := range records {
+		utils.AssertNoError(t, log.Append([]byte(record)), "append failed")
+	}
+}
+
+func assertSameContent(t *testing.T, expected string, actual string) {
+	expectedContent, err := os.ReadFile(expected)
+	utils.AssertNoError(t, err, "read %s failed", expected)
+	actualContent, err := os.ReadFile(actual)
+	utils.AssertNoError(t, err, "read %s failed", actual)
+	utils.Assert(t, bytes.Equal(expectedContent, actualContent), "content of %s differs from %s", actual, expected)
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	writeLog(t, filepath.Join(source, storage.TrainingsFile), `{"id":"training-1"}`, `{"id":"training-2"}`)
+	writeLog(t, filepath.Join(source, storage.QuestionsFile), `{"id":"question-1"}`)
+	mediaDir := filepath.Join(source, "media")
+	utils.AssertNoError(t, os.MkdirAll(filepath.Join(mediaDir, "sub"), 0755), "mkdir failed")
+	utils.AssertNoError(t, os.WriteFile(filepath.Join(mediaDir, "sub", "image.png"), []byte("png"), 0644), "write media failed")
+
+	var archive bytes.Buffer
+	manifest, err := Write(&archive, []storage.Snapshotter{
+		fileSnapshotter{filepath.Join(source, storage.TrainingsFile), storage.TrainingsFile},
+		fileSnapshotter{filepath.Join(source, storage.QuestionsFile), storage.QuestionsFile},
+		contentSnapshotter{storage.RemindersFile: `{"alice":"2026-10-19"}`},
+	}, mediaDir)
+	utils.AssertNoError(t, err, "backup failed")
+	utils.Assert(t, len(manifest.Files) == 4, "expected 4 files, got %v", manifest.Files)
+
+	_, err = Verify(bytes.NewReader(archive.Bytes()))
+	utils.AssertNoError(t, err, "verify failed")
+
+	dataDir, err := storage.OpenDataDir(filepath.Join(target, "data"))
+	utils.AssertNoError(t, err, "open data dir failed")
+	defer dataDir.Close()
+	_, err = Restore(bytes.NewReader(archive.Bytes()), dataDir, filepath.Join(target, "media"), false)
+	utils.AssertNoError(t, err, "restore failed")
+	assertSameContent(t, filepath.Join(source, storage.TrainingsFile), dataDir.File(storage.TrainingsFile))
+	assertSameContent(t, filepath.Join(source, storage.QuestionsFile), dataDir.File(storage.QuestionsFile))
+	reminders, err := os.ReadFile(dataDir.File(storage.RemindersFile))
+	utils.AssertNoError(t, err, "read reminders failed")
+	utils.Assert(t, string(reminders) == `{"alice":"2026-10-19"}`, "wrong reminders %s", reminders)
+	assertSameContent(t, filepath.Join(mediaDir, "sub", "image.png"), filepath.Join(target, "media", "sub", "image.png"))
+
+	_, err = Restore(bytes.NewReader(archive.Bytes()), dataDir, filepath.Join(target, "media"), false)
+	utils.Assert(t, errors.Is(err, ErrDataExists), "expected ErrDataExists, got %v", err)
+	_, err = Restore(bytes.NewReader(archive.Bytes()), dataDir, filepath.Join(target, "media"), true)
+	utils.AssertNoError(t, err, "forced restore failed")
+}
+
+func TestRestoreRejectsInvalidBackup(t *testing.T) {
+	for name, snapshotter := range map[string]contentSnapshotter{
+		"corrupt log":       {storage.TrainingsFile: "not a log file"},
+		"corrupt reminders": {storage.RemindersFile: "not json"},
+		"unknown data file": {"passwd": "root"},
+	} {
+		var archive bytes.Buffer
+		_, err := Write(&archive, []storage.Snapshotter{snapshotter}, "")
+		utils.AssertNoError(t, err, "%s: backup failed", name)
+
+		dataDir, err := storage.OpenDataDir(t.TempDir())
+		utils.AssertNoError(t, err, "open data dir failed")
+		_, err = Restore(&archive, dataDir, "", true)
+		utils.Assert(t, errors.Is(err, ErrInvalidBackup), "%s: expected ErrInvalidBackup, got %v", name, err)
+		entries, _ := os.ReadDir(dataDir.Path())
+		utils.Assert(t, len(entries) == 1, "%s: expected only the lock file, got %d entries", name, len(entries))
+		utils.AssertNoError(t, dataDir.Close(), "close failed")
+	}
+
+	_, err := Verify(io.LimitReader(strings.NewReader("garbage"), 7))
+	utils.Assert(t, errors.Is(err, ErrInvalidBackup), "expected ErrInvalidBackup for garbage, got %v", err)
+}
+
+func TestPruneKeepsNewestBackups(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		utils.AssertNoError(t, os.WriteFile(filepath.Join(dir, FileName(start.Add(time.Duration(i)*time.Hour))), nil, 0644), "write failed")
+	}
+	utils.AssertNoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644), "write failed")
+
+	removed, err := Prune(dir, 2)
+	utils.AssertNoError(t, err, "prune failed")
+	utils.Assert(t, len(removed) == 3, "expected 3 removed backups, got %v", removed)
+	utils.Assert(t, utils.FileExist(filepath.Join(dir, FileName(start.Add(4*time.Hour)))), "newest backup removed")
+	utils.Assert(t, utils.FileExist(filepath.Join(dir, "notes.txt")), "unrelated file removed")
+}
@@ -0,0 +1,54 @@
+package backup
+
+import (
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/routing"
+	"io"
+	"net/http"
+	"os"
+)
+
+type Controller struct {
+	snapshotters []storage.Snapshotter
+	mediaDir     string
+	logger       utils.Logger
+}
+
+func NewRestController(snapshotters []storage.Snapshotter, mediaDir string) *Controller {
+	return &Controller{
+		snapshotters: snapshotters,
+		mediaDir:     mediaDir,
+		logger:       utils.NewStdLogger("backup.rest"),
+	}
+}
+
+func (controller *Controller) Routing(router routing.Routing) {
+	router.HandleFunc(routing.Post("/api/admin/backup").Filter(utils.ApiSecured()), controller.PostBackup)
+}
+
+// PostBackup schreibt das Archiv zunächst in eine temporäre Datei, damit ein Fehler noch als
+// Status gemeldet werden kann
+func (controller *Controller) PostBackup(writer http.ResponseWriter, request *http.Request) {
+	logger := controller.logger.WithContext(request.Context())
+	file, err := os.CreateTemp("", "ceh-backup-*")
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if manifest, err := Write(file, controller.snapshotters, controller.mediaDir); err != nil {
+		utils.InternalServerError(writer, request, err)
+	} else if _, err = file.Seek(0, io.SeekStart); err != nil {
+		utils.InternalServerError(writer, request, err)
+	} else {
+		logger.Info("backup with %d files created", len(manifest.Files))
+		writer.Header().Set("Content-Type", "application/gzip")
+		writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", FileName(manifest.Created)))
+		writer.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(writer, file)
+	}
+}
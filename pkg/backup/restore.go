@@ -0,0 +1,208 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"go.etcd.io/bbolt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidBackup = errors.New("invalid backup")
+	ErrDataExists    = errors.New("data directory is not empty")
+)
+
+// Restore prüft das Archiv vollständig und übernimmt es erst danach in das Datenverzeichnis und
+// das Medienverzeichnis. Vorhandene Daten werden nur mit force überschrieben.
+func Restore(archive io.Reader, dataDir *storage.DataDir, mediaDir string, force bool) (manifest Manifest, err error) {
+	staging, err := os.MkdirTemp(dataDir.Path(), ".restore-*")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err = extract(archive, staging)
+	if err != nil {
+		return manifest, err
+	}
+	if err = validate(staging, manifest); err != nil {
+		return manifest, err
+	}
+	if !force {
+		for _, entry := range manifest.Files {
+			if target := targetPath(entry.Name, dataDir, mediaDir); target != "" && utils.FileExist(target) {
+				return manifest, fmt.Errorf("%w: %s exists, use force to overwrite", ErrDataExists, target)
+			}
+		}
+	}
+	for _, entry := range manifest.Files {
+		target := targetPath(entry.Name, dataDir, mediaDir)
+		if target == "" {
+			continue
+		} else if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return manifest, err
+		} else if err = moveFile(filepath.Join(staging, filepath.FromSlash(entry.Name)), target); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, utils.SyncDir(dataDir.Path())
+}
+
+// Verify prüft das Archiv, ohne Daten zu übernehmen
+func Verify(archive io.Reader) (manifest Manifest, err error) {
+	staging, err := os.MkdirTemp("", "ceh-verify-*")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(staging)
+	if manifest, err = extract(archive, staging); err != nil {
+		return manifest, err
+	}
+	return manifest, validate(staging, manifest)
+}
+
+func targetPath(name string, dataDir *storage.DataDir, mediaDir string) string {
+	if strings.HasPrefix(name, dataPrefix) {
+		return dataDir.File(strings.TrimPrefix(name, dataPrefix))
+	} else if strings.HasPrefix(name, mediaPrefix) && mediaDir != "" {
+		return filepath.Join(mediaDir, filepath.FromSlash(strings.TrimPrefix(name, mediaPrefix)))
+	}
+	return ""
+}
+
+func moveFile(source string, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return nil
+	}
+	return utils.CopyFile(source, target)
+}
+
+// extract entpackt das Archiv und liest das Manifest, Pfade außerhalb des Ziels werden abgelehnt
+func extract(archive io.Reader, target string) (manifest Manifest, err error) {
+	compressed, err := gzip.NewReader(archive)
+	if err != nil {
+		return manifest, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+	}
+	reader := tar.NewReader(compressed)
+	foundManifest := false
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return manifest, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+		}
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg {
+			return manifest, fmt.Errorf("%w: unexpected entry %s", ErrInvalidBackup, header.Name)
+		} else if name == manifestName {
+			if err = json.NewDecoder(reader).Decode(&manifest); err != nil {
+				return manifest, fmt.Errorf("%w: manifest: %w", ErrInvalidBackup, err)
+			}
+			foundManifest = true
+		} else if path.IsAbs(name) || strings.HasPrefix(name, "../") || !(strings.HasPrefix(name, dataPrefix) || strings.HasPrefix(name, mediaPrefix)) {
+			return manifest, fmt.Errorf("%w: unexpected path %s", ErrInvalidBackup, header.Name)
+		} else if err = extractFile(reader, filepath.Join(target, filepath.FromSlash(name))); err != nil {
+			return manifest, err
+		}
+	}
+	if !foundManifest {
+		return manifest, fmt.Errorf("%w: manifest missing", ErrInvalidBackup)
+	} else if manifest.Version != FormatVersion {
+		return manifest, fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, manifest.Version)
+	}
+	return manifest, nil
+}
+
+func extractFile(reader io.Reader, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, reader); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	return errors.Join(file.Sync(), file.Close())
+}
+
+// validate vergleicht die entpackten Dateien mit dem Manifest und prüft, ob sich die Logs und die
+// Datenbank lesen lassen
+func validate(staging string, manifest Manifest) error {
+	listed := make(map[string]bool)
+	for _, entry := range manifest.Files {
+		listed[entry.Name] = true
+		file := filepath.Join(staging, filepath.FromSlash(entry.Name))
+		if sum, size, err := checksum(file); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidBackup, entry.Name, err)
+		} else if size != entry.Size || sum != entry.Sha256 {
+			return fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidBackup, entry.Name)
+		}
+		if err := validateContent(entry.Name, file); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidBackup, entry.Name, err)
+		}
+	}
+	return filepath.Walk(staging, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relative, _ := filepath.Rel(staging, file)
+		if !listed[filepath.ToSlash(relative)] {
+			return fmt.Errorf("%w: %s is not listed in the manifest", ErrInvalidBackup, relative)
+		}
+		return nil
+	})
+}
+
+func checksum(file string) (sum string, size int64, err error) {
+	reader, err := os.Open(file)
+	if err != nil {
+		return sum, size, err
+	}
+	defer reader.Close()
+	hash := sha256.New()
+	size, err = io.Copy(hash, reader)
+	return hex.EncodeToString(hash.Sum(nil)), size, err
+}
+
+func validateContent(name string, file string) error {
+	switch strings.TrimPrefix(name, dataPrefix) {
+	case storage.QuestionsFile, storage.TrainingsFile, storage.ReviewsFile, storage.ReportsFile:
+		_, err := utils.LoadRecords(file, func([]byte) error { return nil })
+		return err
+	case storage.BoltFile:
+		db, err := bbolt.Open(file, 0600, &bbolt.Options{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		return errors.Join(db.View(func(tx *bbolt.Tx) (first error) {
+			for err := range tx.Check() {
+				first = errors.Join(first, err)
+			}
+			return first
+		}), db.Close())
+	case storage.RemindersFile:
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &map[string]string{})
+	}
+	if strings.HasPrefix(name, dataPrefix) {
+		return fmt.Errorf("unknown data file")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	filePrefix = "ceh-backup-"
+	fileSuffix = ".tar.gz"
+)
+
+// FileName liefert den Namen eines Backups, die Namen lassen sich chronologisch sortieren
+func FileName(at time.Time) string {
+	return filePrefix + at.UTC().Format("20060102T150405Z") + fileSuffix
+}
+
+// Schedule beschreibt die regelmäßigen Backups: BACKUP_INTERVAL (leer = keine Backups),
+// BACKUP_DIR und BACKUP_RETENTION (Anzahl der aufbewahrten Backups)
+type Schedule struct {
+	Dir       string
+	Interval  time.Duration
+	Retention int
+}
+
+func ConfiguredSchedule(dataDir *storage.DataDir) (schedule Schedule, err error) {
+	schedule.Dir = utils.GetEnvOrDefault("BACKUP_DIR", dataDir.File("backups"))
+	if interval := utils.GetEnvOrDefault("BACKUP_INTERVAL", ""); interval != "" {
+		if schedule.Interval, err = time.ParseDuration(interval); err != nil {
+			return schedule, fmt.Errorf("invalid BACKUP_INTERVAL: %w", err)
+		}
+	}
+	if _, err = fmt.Sscan(utils.GetEnvOrDefault("BACKUP_RETENTION", "7"), &schedule.Retention); err != nil || schedule.Retention < 1 {
+		return schedule, fmt.Errorf("invalid BACKUP_RETENTION: must be a positive number")
+	}
+	return schedule, nil
+}
+
+func (schedule Schedule) Enabled() bool {
+	return schedule.Interval > 0
+}
+
+type Scheduler struct {
+	schedule     Schedule
+	snapshotters []storage.Snapshotter
+	mediaDir     string
+	logger       utils.Logger
+	done         chan struct{}
+	stopped      chan struct{}
+	once         *sync.Once
+}
+
+func NewScheduler(schedule Schedule, snapshotters []storage.Snapshotter, mediaDir string) (*Scheduler, error) {
+	if err := os.MkdirAll(schedule.Dir, 0755); err != nil {
+		return nil, err
+	}
+	scheduler := &Scheduler{
+		schedule:     schedule,
+		snapshotters: snapshotters,
+		mediaDir:     mediaDir,
+		logger:       utils.NewStdLogger("backup.scheduler"),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+		once:         &sync.Once{},
+	}
+	go scheduler.run()
+	return scheduler, nil
+}
+
+func (scheduler *Scheduler) run() {
+	defer close(scheduler.stopped)
+	ticker := time.NewTicker(scheduler.schedule.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-scheduler.done:
+			return
+		case <-ticker.C:
+			if err := scheduler.Backup(); err != nil {
+				scheduler.logger.Error("backup failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Backup schreibt ein neues Backup und löscht danach die Backups, die über die Aufbewahrung
+// hinausgehen
+func (scheduler *Scheduler) Backup() error {
+	target := filepath.Join(scheduler.schedule.Dir, FileName(time.Now()))
+	manifest, err := WriteFile(target, scheduler.snapshotters, scheduler.mediaDir)
+	if err != nil {
+		return err
+	}
+	scheduler.logger.Info("backup %s written with %d files", target, len(manifest.Files))
+	removed, err := Prune(scheduler.schedule.Dir, scheduler.schedule.Retention)
+	for _, file := range removed {
+		scheduler.logger.Info("backup %s removed", file)
+	}
+	return err
+}
+
+// Stop beendet die Goroutine und wartet auf ein ggf. laufendes Backup
+func (scheduler *Scheduler) Stop() {
+	scheduler.once.Do(func() {
+		close(scheduler.done)
+		<-scheduler.stopped
+	})
+}
+
+// Prune löscht die ältesten Backups im Verzeichnis, bis höchstens retention übrig sind
+func Prune(dir string, retention int) (removed []string, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return removed, err
+	}
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), filePrefix) && strings.HasSuffix(entry.Name(), fileSuffix) {
+			backups = append(backups, entry.Name())
+		}
+	}
+	sort.Strings(backups)
+	for len(backups) > retention {
+		file := filepath.Join(dir, backups[0])
+		if err = os.Remove(file); err != nil {
+			return removed, err
+		}
+		removed = append(removed, file)
+		backups = backups[1:]
+	}
+	return removed, nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+//go:generate go run ../../cmd/clientgen -spec ../api/openapi.json -out .
+
+// Client ruft die REST-API des Trainers auf. Die Operationen (operations.go) und Typen (model.go)
+// werden aus dem OpenAPI-Dokument pkg/api/openapi.json erzeugt, die operationId ist der
+// Methodenname. Optionale Query-Parameter mit dem Default oder dem Nullwert werden nicht gesendet.
+type Client struct {
+	BaseUrl    string
+	ApiKey     string
+	User       string
+	HttpClient *http.Client
+}
+
+func New(baseUrl string) *Client {
+	return &Client{BaseUrl: strings.TrimSuffix(baseUrl, "/"), HttpClient: http.DefaultClient}
+}
+
+// WithApiKey setzt den API-Key für die gesicherten Operationen, er wird base64-kodiert übertragen
+func (client *Client) WithApiKey(apiKey string) *Client {
+	client.ApiKey = apiKey
+	return client
+}
+
+// WithUser setzt den Benutzer, der als x-user mitgeschickt wird
+func (client *Client) WithUser(user string) *Client {
+	client.User = user
+	return client
+}
+
+// StatusError ist die Antwort mit einem unerwarteten Status. Problem enthält die Fehlerantwort
+// des Servers (RFC 7807), sofern er eine geschickt hat.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Problem    Problem
+}
+
+func (err *StatusError) Error() string {
+	if err.Problem.Detail != "" {
+		return fmt.Sprintf("%s %s: status %d: %s", err.Method, err.Path, err.StatusCode, err.Problem.Detail)
+	}
+	return fmt.Sprintf("%s %s: unexpected status %d", err.Method, err.Path, err.StatusCode)
+}
+
+// IsStatus prüft, ob err eine Antwort mit dem angegebenen Status ist
+func IsStatus(err error, statusCode int) bool {
+	var statusError *StatusError
+	return errors.As(err, &statusError) && statusError.StatusCode == statusCode
+}
+
+func (client *Client) do(method string, path string, body any, expected int) (*http.Response, error) {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(data)
+	}
+	request, err := http.NewRequest(method, client.BaseUrl+path, payload)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if client.ApiKey != "" {
+		request.Header.Set("x-api-key", base64.StdEncoding.EncodeToString([]byte(client.ApiKey)))
+	}
+	if client.User != "" {
+		request.Header.Set("x-user", client.User)
+	}
+	response, err := client.HttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != expected {
+		defer response.Body.Close()
+		statusError := &StatusError{Method: method, Path: path, StatusCode: response.StatusCode}
+		if strings.HasPrefix(response.Header.Get("Content-Type"), "application/problem+json") {
+			_ = json.NewDecoder(response.Body).Decode(&statusError.Problem)
+		}
+		return nil, statusError
+	}
+	return response, nil
+}
+
+func call[T any](client *Client, method string, path string, body any, expected int) (result T, err error) {
+	response, err := client.do(method, path, body, expected)
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+	err = json.NewDecoder(response.Body).Decode(&result)
+	return result, err
+}
+
+func read(client *Client, method string, path string, body any, expected int) ([]byte, error) {
+	response, err := client.do(method, path, body, expected)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+// send ist der Aufruf einer Operation ohne Inhalt in der Antwort
+func send(client *Client, method string, path string, body any, expected int) error {
+	response, err := client.do(method, path, body, expected)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,235 @@
+// Code generated by clientgen from pkg/api/openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// GetOpenApi: Dieses Dokument (GET /api/openapi.json)
+func (client *Client) GetOpenApi() (map[string]any, error) {
+	return call[map[string]any](client, http.MethodGet, "/api/openapi.json", nil, http.StatusOK)
+}
+
+// GetQuestions: Alle Fragen (GET /api/questions/)
+func (client *Client) GetQuestions() ([]Question, error) {
+	return call[[]Question](client, http.MethodGet, "/api/questions/", nil, http.StatusOK)
+}
+
+// CreateQuestion: Neue lokale Frage anlegen (POST /api/questions/)
+func (client *Client) CreateQuestion(newQuestion NewQuestion) (Question, error) {
+	return call[Question](client, http.MethodPost, "/api/questions/", newQuestion, http.StatusCreated)
+}
+
+// GetQuestionOverrides: Lokale Änderungen an Fragen aus den Quellen (GET /api/questions/overrides)
+func (client *Client) GetQuestionOverrides(diverged bool) ([]Override, error) {
+	query := url.Values{}
+	if diverged {
+		query.Set("diverged", strconv.FormatBool(diverged))
+	}
+	return call[[]Override](client, http.MethodGet, withQuery("/api/questions/overrides", query), nil, http.StatusOK)
+}
+
+// GetQuestion: Eine Frage (GET /api/questions/{questionId})
+func (client *Client) GetQuestion(questionId uuid.UUID) (Question, error) {
+	return call[Question](client, http.MethodGet, "/api/questions/"+questionId.String(), nil, http.StatusOK)
+}
+
+// UpdateQuestion: Text, Antwortmöglichkeiten und Lösung einer Frage ändern (PATCH /api/questions/{questionId})
+func (client *Client) UpdateQuestion(questionId uuid.UUID, questionUpdate QuestionUpdate) (Question, error) {
+	return call[Question](client, http.MethodPatch, "/api/questions/"+questionId.String(), questionUpdate, http.StatusOK)
+}
+
+// ResetQuestion: Lokale Änderung verwerfen (POST /api/questions/{questionId}/reset)
+func (client *Client) ResetQuestion(questionId uuid.UUID) (Question, error) {
+	return call[Question](client, http.MethodPost, "/api/questions/"+questionId.String()+"/reset", nil, http.StatusOK)
+}
+
+// ReportQuestion: Frage als falsch oder unklar melden (POST /api/questions/{questionId}/reports)
+func (client *Client) ReportQuestion(questionId uuid.UUID, newReport NewReport) (Report, error) {
+	return call[Report](client, http.MethodPost, "/api/questions/"+questionId.String()+"/reports", newReport, http.StatusCreated)
+}
+
+// GetQuestionStats: Antwortstatistik einer Frage über alle Trainings (GET /api/questions/{questionId}/stats)
+func (client *Client) GetQuestionStats(questionId uuid.UUID) (QuestionStats, error) {
+	return call[QuestionStats](client, http.MethodGet, "/api/questions/"+questionId.String()+"/stats", nil, http.StatusOK)
+}
+
+// GetHardestQuestions: Die schwierigsten Fragen, geringste Erfolgsquote im ersten Versuch zuerst (GET /api/stats/hardest-questions)
+func (client *Client) GetHardestQuestions(limit int, minAttempts int) ([]QuestionStats, error) {
+	query := url.Values{}
+	if limit != 20 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if minAttempts != 1 {
+		query.Set("minAttempts", strconv.Itoa(minAttempts))
+	}
+	return call[[]QuestionStats](client, http.MethodGet, withQuery("/api/stats/hardest-questions", query), nil, http.StatusOK)
+}
+
+// GetDashboard: Lernfortschritt des Benutzers über alle seine Trainings (GET /api/dashboard)
+func (client *Client) GetDashboard(tz string) (Dashboard, error) {
+	query := url.Values{}
+	if tz != "UTC" {
+		query.Set("tz", tz)
+	}
+	return call[Dashboard](client, http.MethodGet, withQuery("/api/dashboard", query), nil, http.StatusOK)
+}
+
+// GetReviews: Fragen mit vermutlich falschem Lösungsschlüssel, älteste Markierung zuerst (GET /api/reviews/)
+func (client *Client) GetReviews(status string) ([]ReviewFlag, error) {
+	query := url.Values{}
+	if status != "open" {
+		query.Set("status", status)
+	}
+	return call[[]ReviewFlag](client, http.MethodGet, withQuery("/api/reviews/", query), nil, http.StatusOK)
+}
+
+// AnalyzeReviews: Analyse sofort ausführen (POST /api/reviews/analysis)
+func (client *Client) AnalyzeReviews() ([]ReviewFlag, error) {
+	return call[[]ReviewFlag](client, http.MethodPost, "/api/reviews/analysis", nil, http.StatusOK)
+}
+
+// ConfirmReview: Markierung bestätigen, der Schlüssel wird über updateQuestion korrigiert (POST /api/reviews/{questionId}/confirm)
+func (client *Client) ConfirmReview(questionId uuid.UUID, reviewDecision ReviewDecision) (ReviewFlag, error) {
+	return call[ReviewFlag](client, http.MethodPost, "/api/reviews/"+questionId.String()+"/confirm", reviewDecision, http.StatusOK)
+}
+
+// DismissReview: Markierung verwerfen (POST /api/reviews/{questionId}/dismiss)
+func (client *Client) DismissReview(questionId uuid.UUID, reviewDecision ReviewDecision) (ReviewFlag, error) {
+	return call[ReviewFlag](client, http.MethodPost, "/api/reviews/"+questionId.String()+"/dismiss", reviewDecision, http.StatusOK)
+}
+
+// GetReports: Meldungen zu Fragen, älteste zuerst (GET /api/reports/)
+func (client *Client) GetReports(status string, questionId uuid.UUID) ([]Report, error) {
+	query := url.Values{}
+	if status != "open" {
+		query.Set("status", status)
+	}
+	if questionId != uuid.Nil {
+		query.Set("questionId", questionId.String())
+	}
+	return call[[]Report](client, http.MethodGet, withQuery("/api/reports/", query), nil, http.StatusOK)
+}
+
+// ResolveReport: Meldung erledigen (POST /api/reports/{reportId}/resolve)
+func (client *Client) ResolveReport(reportId uuid.UUID, reportResolution ReportResolution) (Report, error) {
+	return call[Report](client, http.MethodPost, "/api/reports/"+reportId.String()+"/resolve", reportResolution, http.StatusOK)
+}
+
+// GetMedia: Bild zu einer Frage (GET /api/media/{path})
+func (client *Client) GetMedia(path string) ([]byte, error) {
+	return read(client, http.MethodGet, "/api/media/"+path, nil, http.StatusOK)
+}
+
+// GetTrainings: Alle Trainings (GET /api/trainings/)
+func (client *Client) GetTrainings(archived bool) ([]Training, error) {
+	query := url.Values{}
+	if archived {
+		query.Set("archived", strconv.FormatBool(archived))
+	}
+	return call[[]Training](client, http.MethodGet, withQuery("/api/trainings/", query), nil, http.StatusOK)
+}
+
+// CreateTraining: Neues Training starten (POST /api/trainings/)
+func (client *Client) CreateTraining(newTraining NewTraining) (TrainingCreated, error) {
+	return call[TrainingCreated](client, http.MethodPost, "/api/trainings/", newTraining, http.StatusCreated)
+}
+
+// GetTraining: Aktueller oder früherer Zustand eines Trainings (GET /api/trainings/{trainingId})
+func (client *Client) GetTraining(trainingId uuid.UUID, at time.Time) (Training, error) {
+	query := url.Values{}
+	if !at.IsZero() {
+		query.Set("at", at.Format(time.RFC3339))
+	}
+	return call[Training](client, http.MethodGet, withQuery("/api/trainings/"+trainingId.String(), query), nil, http.StatusOK)
+}
+
+// AnswerChallenge: Aktuelle Frage des Trainings beantworten (PATCH /api/trainings/{trainingId})
+func (client *Client) AnswerChallenge(trainingId uuid.UUID, answer Answer) (AnswerResult, error) {
+	return call[AnswerResult](client, http.MethodPatch, "/api/trainings/"+trainingId.String(), answer, http.StatusOK)
+}
+
+// DeleteTraining: Training mit Timeline und Historie endgültig löschen (DELETE /api/trainings/{trainingId})
+func (client *Client) DeleteTraining(trainingId uuid.UUID) error {
+	return send(client, http.MethodDelete, "/api/trainings/"+trainingId.String(), nil, http.StatusNoContent)
+}
+
+// GetTrainingChallenges: Fragen eines Trainings mit Level (GET /api/trainings/{trainingId}/challenges)
+func (client *Client) GetTrainingChallenges(trainingId uuid.UUID) (TrainingChallenges, error) {
+	return call[TrainingChallenges](client, http.MethodGet, "/api/trainings/"+trainingId.String()+"/challenges", nil, http.StatusOK)
+}
+
+// GetTrainingBookmarks: Zum Nachschlagen vorgemerkte Fragen eines Trainings (GET /api/trainings/{trainingId}/bookmarks)
+func (client *Client) GetTrainingBookmarks(trainingId uuid.UUID) (TrainingChallenges, error) {
+	return call[TrainingChallenges](client, http.MethodGet, "/api/trainings/"+trainingId.String()+"/bookmarks", nil, http.StatusOK)
+}
+
+// GetTrainingDue: Offene Challenges eines Trainings, die am frühesten fällige zuerst (GET /api/trainings/{trainingId}/due)
+func (client *Client) GetTrainingDue(trainingId uuid.UUID) (DueQueue, error) {
+	return call[DueQueue](client, http.MethodGet, "/api/trainings/"+trainingId.String()+"/due", nil, http.StatusOK)
+}
+
+// SkipToDue: Die nächste fällige Challenge vorzeitig stellen, die aktuelle bleibt offen (POST /api/trainings/{trainingId}/skip-to-due)
+func (client *Client) SkipToDue(trainingId uuid.UUID) (Training, error) {
+	return call[Training](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/skip-to-due", nil, http.StatusOK)
+}
+
+// PauseTraining: Training pausieren, die Fälligkeiten ruhen bis zum Fortsetzen (POST /api/trainings/{trainingId}/pause)
+func (client *Client) PauseTraining(trainingId uuid.UUID) (Training, error) {
+	return call[Training](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/pause", nil, http.StatusOK)
+}
+
+// ResumeTraining: Pausiertes Training fortsetzen, die Fälligkeiten verschieben sich um die Dauer der Pause (POST /api/trainings/{trainingId}/resume)
+func (client *Client) ResumeTraining(trainingId uuid.UUID) (Training, error) {
+	return call[Training](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/resume", nil, http.StatusOK)
+}
+
+// ResetTraining: Alle Challenges auf Level 0 zurücksetzen, ein abgeschlossenes Training wird wieder aufgenommen (POST /api/trainings/{trainingId}/reset)
+func (client *Client) ResetTraining(trainingId uuid.UUID) (Training, error) {
+	return call[Training](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/reset", nil, http.StatusOK)
+}
+
+// ArchiveTraining: Training archivieren, danach kann es nur noch gelesen werden (POST /api/trainings/{trainingId}/archive)
+func (client *Client) ArchiveTraining(trainingId uuid.UUID) (Training, error) {
+	return call[Training](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/archive", nil, http.StatusOK)
+}
+
+// SkipChallenge: Aktuelle Frage überspringen, sie kommt nach kurzer Zeit wieder (POST /api/trainings/{trainingId}/skip)
+func (client *Client) SkipChallenge(trainingId uuid.UUID) (Training, error) {
+	return call[Training](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/skip", nil, http.StatusOK)
+}
+
+// BookmarkChallenge: Frage zum Nachschlagen vormerken, ohne Angabe die aktuelle (POST /api/trainings/{trainingId}/bookmark)
+func (client *Client) BookmarkChallenge(trainingId uuid.UUID, bookmark Bookmark) (TrainingChallenges, error) {
+	return call[TrainingChallenges](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/bookmark", bookmark, http.StatusOK)
+}
+
+// UnbookmarkChallenge: Vormerkung einer Frage entfernen, ohne Angabe der aktuellen (POST /api/trainings/{trainingId}/unbookmark)
+func (client *Client) UnbookmarkChallenge(trainingId uuid.UUID, bookmark Bookmark) (TrainingChallenges, error) {
+	return call[TrainingChallenges](client, http.MethodPost, "/api/trainings/"+trainingId.String()+"/unbookmark", bookmark, http.StatusOK)
+}
+
+// GetTrainingTimeline: Gespeicherte Änderungen eines Trainings (GET /api/trainings/{trainingId}/timeline)
+func (client *Client) GetTrainingTimeline(trainingId uuid.UUID) (Timeline, error) {
+	return call[Timeline](client, http.MethodGet, "/api/trainings/"+trainingId.String()+"/timeline", nil, http.StatusOK)
+}
+
+// GetHistory: Historie eines Trainings (GET /api/history/{historyId})
+func (client *Client) GetHistory(historyId uuid.UUID) (History, error) {
+	return call[History](client, http.MethodGet, "/api/history/"+historyId.String(), nil, http.StatusOK)
+}
+
+// GetHistoryItem: Abgeschlossene Frage aus der Historie (GET /api/history/{historyId}/{historyIndex})
+func (client *Client) GetHistoryItem(historyId uuid.UUID, historyIndex int) (HistoryItem, error) {
+	return call[HistoryItem](client, http.MethodGet, "/api/history/"+historyId.String()+"/"+strconv.Itoa(historyIndex), nil, http.StatusOK)
+}
+
+// CreateBackup: Backup als tar.gz, über alle Repositories konsistent nur im Backend bolt (POST /api/admin/backup)
+func (client *Client) CreateBackup() ([]byte, error) {
+	return read(client, http.MethodPost, "/api/admin/backup", nil, http.StatusOK)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+)
+
+// Config ist der Inhalt der Konfigurationsdatei (CONFIG_FILE, Default config/ceh.yaml)
+type Config struct {
+	Sources []questions.Source `yaml:"sources"`
+}
+
+// Default entspricht den bisher fest verdrahteten Quellen und wird verwendet, wenn keine
+// Konfigurationsdatei existiert
+func Default() Config {
+	return Config{Sources: []questions.Source{
+		{Name: "ceh-12-cehtest.org", Path: "config/ceh-12-cehtest.org/question.data"},
+		{Name: "custom-json", Path: "config/custom-json/question.data"},
+	}}
+}
+
+func Configured() (Config, error) {
+	return Load(utils.GetEnvOrDefault("CONFIG_FILE", "config/ceh.yaml"))
+}
+
+func Load(path string) (config Config, err error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	} else if err != nil {
+		return config, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return config, config.validate()
+}
+
+func (config Config) validate() error {
+	names := make(map[string]bool)
+	for index, source := range config.Sources {
+		if source.Name == "" {
+			return fmt.Errorf("source %d: name must not be empty", index+1)
+		} else if source.Path == "" {
+			return fmt.Errorf("source %s: path must not be empty", source.Name)
+		} else if names[source.Name] {
+			return fmt.Errorf("source %s: name must be unique", source.Name)
+		}
+		names[source.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "ceh.yaml")
+	utils.AssertNoError(t, os.WriteFile(path, []byte(content), 0644), "write config failed")
+	return path
+}
+
+func TestLoadSources(t *testing.T) {
+	config, err := Load(writeConfig(t, `
+sources:
+  - name: upstream
+    path: config/upstream/question.data
+    readOnly: true
+    tags: [ceh-12, upstream]
+  - name: custom
+    path: config/custom/question.data
+`))
+	utils.AssertNoError(t, err, "load failed")
+	utils.Assert(t, len(config.Sources) == 2, "expected 2 sources, got %d", len(config.Sources))
+	utils.Assert(t, config.Sources[0].ReadOnly, "upstream should be read-only")
+	utils.Assert(t, len(config.Sources[0].Tags) == 2, "expected 2 default tags, got %v", config.Sources[0].Tags)
+	utils.Assert(t, !config.Sources[1].ReadOnly, "custom should be writable")
+}
+
+func TestLoadMissingFileUsesDefault(t *testing.T) {
+	config, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	utils.AssertNoError(t, err, "load failed")
+	utils.Assert(t, len(config.Sources) == len(Default().Sources), "expected default sources, got %v", config.Sources)
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field":  "sources:\n  - name: a\n    path: a.data\n    readonly: true\n",
+		"missing path":   "sources:\n  - name: a\n",
+		"duplicate name": "sources:\n  - name: a\n    path: a.data\n  - name: a\n    path: b.data\n",
+	} {
+		_, err := Load(writeConfig(t, content))
+		utils.Assert(t, err != nil, "%s: expected error", name)
+	}
+}
+
+func TestRepositoryConfigIsValid(t *testing.T) {
+	_, err := Load("../../config/ceh.yaml")
+	utils.AssertNoError(t, err, "config/ceh.yaml is invalid")
+}
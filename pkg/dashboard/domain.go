@@ -0,0 +1,164 @@
+package dashboard
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"sort"
+	"time"
+)
+
+const (
+	// masteredLevel ist das Level, mit dem eine Challenge abgeschlossen wird
+	masteredLevel = 4
+	dueSoonWindow = 24 * time.Hour
+	dailyPeriods  = 14
+	weeklyPeriods = 8
+)
+
+// Accuracy ist der Anteil richtiger Antworten in einem Zeitraum ab Start
+type Accuracy struct {
+	Start   time.Time
+	Answers int
+	Passed  int
+}
+
+func (accuracy Accuracy) Rate() float64 {
+	if accuracy.Answers == 0 {
+		return 0
+	}
+	return float64(accuracy.Passed) / float64(accuracy.Answers)
+}
+
+// TagMastery fasst das Level aller Fragen eines Tags zusammen, die der Benutzer bisher hatte
+type TagMastery struct {
+	Tag       string
+	Questions int
+	Mastered  int
+	levels    int
+}
+
+// Percent ist das durchschnittliche Level der Fragen im Verhältnis zum Abschluss-Level
+func (mastery TagMastery) Percent() float64 {
+	if mastery.Questions == 0 {
+		return 0
+	}
+	return 100 * float64(mastery.levels) / float64(mastery.Questions*masteredLevel)
+}
+
+type Dashboard struct {
+	User      string
+	Trainings int
+	// Mastered zählt die Fragen, die in mindestens einem Training abgeschlossen sind
+	Mastered int
+	// DueNow und DueSoon zählen die offenen Challenges der laufenden Trainings
+	DueNow  int
+	DueSoon int
+	// Streak ist die Anzahl aufeinanderfolgender Tage mit Antworten bis heute. Ein Tag ohne
+	// Antwort unterbricht die Serie erst, wenn er vorbei ist.
+	Streak int
+	Daily  []Accuracy
+	Weekly []Accuracy
+	Tags   []TagMastery
+}
+
+// Build berechnet das Dashboard aus den Trainings eines Benutzers und deren Timelines. Tage
+// und Wochen (ab Montag) beginnen in der Zeitzone von now.
+func Build(user string, trainings []*training.Training, timelines map[uuid.UUID][]training.Change, tagsOf func(uuid.UUID) []string, now time.Time) Dashboard {
+	dashboard := Dashboard{User: user, Trainings: len(trainings)}
+
+	levels := make(map[uuid.UUID]int)
+	for _, t := range trainings {
+		for _, challenge := range t.AllChallenges() {
+			level := challenge.Level
+			if challenge.Done {
+				level = masteredLevel
+			}
+			levels[challenge.Id] = max(levels[challenge.Id], min(level, masteredLevel))
+			if t.Completed || t.Archived || t.IsPaused() || challenge.Done {
+				continue
+			} else if !challenge.Timestamp.After(now) {
+				dashboard.DueNow++
+			} else if !challenge.Timestamp.After(now.Add(dueSoonWindow)) {
+				dashboard.DueSoon++
+			}
+		}
+	}
+
+	tags := make(map[string]*TagMastery)
+	for questionId, level := range levels {
+		if level == masteredLevel {
+			dashboard.Mastered++
+		}
+		for _, tag := range tagsOf(questionId) {
+			if tags[tag] == nil {
+				tags[tag] = &TagMastery{Tag: tag}
+			}
+			tags[tag].Questions++
+			tags[tag].levels += level
+			if level == masteredLevel {
+				tags[tag].Mastered++
+			}
+		}
+	}
+	for _, mastery := range tags {
+		dashboard.Tags = append(dashboard.Tags, *mastery)
+	}
+	sort.Slice(dashboard.Tags, func(i, j int) bool {
+		return dashboard.Tags[i].Tag < dashboard.Tags[j].Tag
+	})
+
+	today := startOfDay(now)
+	dashboard.Daily = periods(today, dailyPeriods, 1)
+	dashboard.Weekly = periods(startOfWeek(today), weeklyPeriods, 7)
+	studyDays := make(map[time.Time]bool)
+	for _, changes := range timelines {
+		for _, change := range changes {
+			if change.Type != training.AnswerGiven {
+				continue
+			}
+			studyDays[startOfDay(change.Timestamp.In(now.Location()))] = true
+			count(dashboard.Daily, change)
+			count(dashboard.Weekly, change)
+		}
+	}
+
+	day := today
+	if !studyDays[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for ; studyDays[day]; day = day.AddDate(0, 0, -1) {
+		dashboard.Streak++
+	}
+	return dashboard
+}
+
+// periods liefert n leere Zeiträume zu je days Tagen, der letzte beginnt mit last
+func periods(last time.Time, n int, days int) []Accuracy {
+	result := make([]Accuracy, n)
+	for index := range result {
+		result[index].Start = last.AddDate(0, 0, -(n-1-index)*days)
+	}
+	return result
+}
+
+// count zählt die Antwort im Zeitraum, in den sie fällt. Ältere Antworten werden ignoriert.
+func count(accuracies []Accuracy, change training.Change) {
+	for index := len(accuracies) - 1; index >= 0; index-- {
+		if !change.Timestamp.Before(accuracies[index].Start) {
+			accuracies[index].Answers++
+			if change.Passed {
+				accuracies[index].Passed++
+			}
+			return
+		}
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func startOfWeek(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
@@ -0,0 +1,83 @@
+package dashboard
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"testing"
+	"time"
+)
+
+func noTags(uuid.UUID) []string {
+	return nil
+}
+
+func answer(at time.Time, passed bool) training.Change {
+	return training.Change{Type: training.AnswerGiven, Timestamp: at, Passed: passed}
+}
+
+func TestStreakCountsConsecutiveDays(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	timelines := map[uuid.UUID][]training.Change{uuid.New(): {
+		answer(now.AddDate(0, 0, -4), true),
+		answer(now.AddDate(0, 0, -2), true),
+		answer(now.AddDate(0, 0, -1), false),
+	}}
+	dashboard := Build("alice", nil, timelines, noTags, now)
+	// heute wurde noch nicht gelernt, die Serie von gestern zählt weiter
+	utils.Assert(t, dashboard.Streak == 2, "expected streak of 2 days, got %d", dashboard.Streak)
+
+	timelines[uuid.New()] = []training.Change{answer(now.Add(-time.Hour), true)}
+	dashboard = Build("alice", nil, timelines, noTags, now)
+	utils.Assert(t, dashboard.Streak == 3, "expected streak of 3 days, got %d", dashboard.Streak)
+}
+
+func TestAccuracyPerDayAndWeek(t *testing.T) {
+	// Montag
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	timelines := map[uuid.UUID][]training.Change{uuid.New(): {
+		answer(now.Add(-time.Hour), true),
+		answer(now.Add(-time.Hour), false),
+		answer(now.AddDate(0, 0, -1), true),
+		answer(now.AddDate(0, 0, -100), true),
+	}}
+	dashboard := Build("alice", nil, timelines, noTags, now)
+	today, yesterday := dashboard.Daily[len(dashboard.Daily)-1], dashboard.Daily[len(dashboard.Daily)-2]
+	utils.Assert(t, today.Answers == 2 && today.Rate() == 0.5, "wrong accuracy today %+v", today)
+	utils.Assert(t, yesterday.Answers == 1 && yesterday.Rate() == 1, "wrong accuracy yesterday %+v", yesterday)
+	thisWeek, lastWeek := dashboard.Weekly[len(dashboard.Weekly)-1], dashboard.Weekly[len(dashboard.Weekly)-2]
+	utils.Assert(t, thisWeek.Start.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)), "week must start on monday, got %s", thisWeek.Start)
+	utils.Assert(t, thisWeek.Answers == 2 && lastWeek.Answers == 1, "wrong weekly answers %+v %+v", thisWeek, lastWeek)
+	utils.Assert(t, len(dashboard.Daily) == dailyPeriods && len(dashboard.Weekly) == weeklyPeriods, "wrong number of periods")
+}
+
+func TestDueAndTagMastery(t *testing.T) {
+	var provided []training.Challenge
+	for i := 0; i < 3; i++ {
+		provided = append(provided, training.Challenge{Id: uuid.New(), Answer: []uuid.UUID{uuid.New()}})
+	}
+	first, second, third := provided[0], provided[1], provided[2]
+	provider := func(training.ChallengeQuery) (training.Challenge, error) {
+		if len(provided) == 0 {
+			return training.Challenge{}, training.ErrPoolExhausted
+		}
+		challenge := provided[0]
+		provided = provided[1:]
+		return challenge, nil
+	}
+	t1, err := training.CreateTrainingWithSettings(provider, training.Settings{OnExhausted: training.CompleteOnExhausted, Owner: "alice"})
+	utils.AssertNoError(t, err, "create training failed")
+	// first und second steigen auf Level 1 und sind in 10 Minuten fällig, third ist sofort fällig
+	_, err = t1.Next(context.Background(), first.Answer, provider)
+	utils.AssertNoError(t, err, "answer first failed")
+	_, err = t1.Next(context.Background(), second.Answer, provider)
+	utils.AssertNoError(t, err, "answer second failed")
+
+	tags := map[uuid.UUID][]string{second.Id: {"network"}, third.Id: {"network", "crypto"}}
+	dashboard := Build("alice", []*training.Training{t1}, nil, func(id uuid.UUID) []string { return tags[id] }, time.Now())
+	utils.Assert(t, dashboard.DueNow == 1 && dashboard.DueSoon == 2, "expected 1 due now and 2 due soon, got %d and %d", dashboard.DueNow, dashboard.DueSoon)
+	utils.Assert(t, len(dashboard.Tags) == 2 && dashboard.Tags[0].Tag == "crypto", "expected tags sorted by name, got %+v", dashboard.Tags)
+	network := dashboard.Tags[1]
+	utils.Assert(t, network.Questions == 2 && network.Percent() == 12.5, "expected 1 of 8 levels for network, got %+v (%f)", network, network.Percent())
+}
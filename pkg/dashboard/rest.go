@@ -0,0 +1,114 @@
+package dashboard
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+	"github.com/ohrenpiraten/go-collections/collections"
+	"net/http"
+	"time"
+)
+
+type Controller struct {
+	trainingRepo training.Repository
+	questionRepo questions.Repository
+}
+
+func NewRestController(trainingRepo training.Repository, questionRepo questions.Repository) *Controller {
+	return &Controller{
+		trainingRepo: trainingRepo,
+		questionRepo: questionRepo,
+	}
+}
+
+func (controller *Controller) Routing(router routing.Routing) {
+	router.HandleFunc(routing.Get("/api/dashboard"), controller.Get)
+}
+
+type accuracyDTO struct {
+	Start    string  `json:"start"`
+	Answers  int     `json:"answers"`
+	Passed   int     `json:"passed"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+type tagMasteryDTO struct {
+	Tag            string  `json:"tag"`
+	Questions      int     `json:"questions"`
+	Mastered       int     `json:"mastered"`
+	MasteryPercent float64 `json:"masteryPercent"`
+}
+
+type dashboardDTO struct {
+	User       string          `json:"user"`
+	Trainings  int             `json:"trainings"`
+	Mastered   int             `json:"mastered"`
+	DueNow     int             `json:"dueNow"`
+	DueNext24h int             `json:"dueNext24h"`
+	Streak     int             `json:"streak"`
+	Daily      []accuracyDTO   `json:"daily"`
+	Weekly     []accuracyDTO   `json:"weekly"`
+	Tags       []tagMasteryDTO `json:"tags"`
+}
+
+func mapAccuracyDTO(accuracy Accuracy) accuracyDTO {
+	return accuracyDTO{
+		Start:    accuracy.Start.Format(time.DateOnly),
+		Answers:  accuracy.Answers,
+		Passed:   accuracy.Passed,
+		Accuracy: accuracy.Rate(),
+	}
+}
+
+func mapTagMasteryDTO(mastery TagMastery) tagMasteryDTO {
+	return tagMasteryDTO{
+		Tag:            mastery.Tag,
+		Questions:      mastery.Questions,
+		Mastered:       mastery.Mastered,
+		MasteryPercent: mastery.Percent(),
+	}
+}
+
+func mapDashboardDTO(dashboard Dashboard) dashboardDTO {
+	return dashboardDTO{
+		User:       dashboard.User,
+		Trainings:  dashboard.Trainings,
+		Mastered:   dashboard.Mastered,
+		DueNow:     dashboard.DueNow,
+		DueNext24h: dashboard.DueSoon,
+		Streak:     dashboard.Streak,
+		Daily:      collections.Map(dashboard.Daily, mapAccuracyDTO),
+		Weekly:     collections.Map(dashboard.Weekly, mapAccuracyDTO),
+		Tags:       collections.Map(append(make([]TagMastery, 0), dashboard.Tags...), mapTagMasteryDTO),
+	}
+}
+
+// Get liefert das Dashboard des Benutzers aus dem Header x-user über alle seine Trainings. Tage
+// beginnen in der Zeitzone ?tz= (IANA-Name, Default UTC).
+func (controller *Controller) Get(w http.ResponseWriter, r *http.Request) {
+	user := r.Header.Get("x-user")
+	if user == "" {
+		utils.BadRequest(w, r, "missing header x-user")
+	} else if location, err := time.LoadLocation(r.URL.Query().Get("tz")); err != nil {
+		utils.BadRequest(w, r, "unknown time zone")
+	} else if trainings, err := controller.trainingRepo.FindAllBy(r.Context(), training.OwnedBy(user)); err != nil {
+		utils.InternalServerError(w, r, err)
+	} else {
+		timelines := make(map[uuid.UUID][]training.Change)
+		for _, t := range trainings {
+			timelines[t.Id], _ = controller.trainingRepo.Timeline(r.Context(), t.Id)
+		}
+		dashboard := Build(user, trainings, timelines, controller.tagsOf, time.Now().In(location))
+		httputils.OkJson(w, r, mapDashboardDTO(dashboard))
+	}
+}
+
+func (controller *Controller) tagsOf(questionId uuid.UUID) []string {
+	if question, exists := controller.questionRepo.FindFirst(questions.IdEquals(questionId)); exists {
+		return question.Tags
+	}
+	return nil
+}
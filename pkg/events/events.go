@@ -1,8 +1,14 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/metrics"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"sync"
+	"time"
 )
 
 type eventType string
@@ -15,33 +21,134 @@ type Event struct {
 	Type       eventType
 	Payload    []byte
 	ContenType string
+	Origin     string
 }
 
 type eventBus struct {
 	subscriber map[eventType]subscriptions
+	transport  Transport
+	// Anzahl der Events je Typ, deren Zustellung an die Subscriber noch läuft
+	pending map[eventType]int
+	// nach Drain werden keine Events mehr angenommen
+	closed bool
+	logger utils.Logger
+	mutex  *sync.RWMutex
 }
 
-var bus eventBus = eventBus{subscriber: make(map[eventType]subscriptions)}
+var ErrBusClosed = errors.New("event bus is closed")
+
+var bus = newEventBus(NewInProcessTransport())
+
+var handlerErrors = metrics.NewCounter("ceh_event_handler_errors_total", "Number of event handlers that returned an error.", "type")
+
+var _ = metrics.NewGaugeVecFunc("ceh_event_queue_depth", "Number of events currently dispatched to their subscribers.", "type", func() map[string]float64 {
+	return bus.queueDepth()
+})
+
+var _ = metrics.NewGaugeFunc("ceh_event_transport_backlog_bytes", "Bytes written to the event transport that have not been read yet.", func() float64 {
+	bus.mutex.RLock()
+	transport := bus.transport
+	bus.mutex.RUnlock()
+	if backlog, ok := transport.(interface{ Backlog() int64 }); ok {
+		return float64(backlog.Backlog())
+	}
+	return 0
+})
+
+func newEventBus(transport Transport) *eventBus {
+	bus := &eventBus{
+		subscriber: make(map[eventType]subscriptions),
+		pending:    make(map[eventType]int),
+		logger:     utils.NewStdLogger("events.bus"),
+		mutex:      &sync.RWMutex{},
+	}
+	bus.transport = transport
+	_ = transport.Listen(bus.dispatch)
+	return bus
+}
+
+func (bus *eventBus) emit(event Event) error {
+	bus.mutex.RLock()
+	transport, closed := bus.transport, bus.closed
+	bus.mutex.RUnlock()
+	if closed {
+		return ErrBusClosed
+	}
+	return transport.Publish(event)
+}
+
+// stellt ein Event, egal ob lokal oder über den Transport empfangen, an die Subscriber zu
+func (bus *eventBus) dispatch(event Event) {
+	bus.mutex.Lock()
+	specific := bus.subscriber[event.Type]
+	global := bus.subscriber[eventType("*")]
+	bus.pending[event.Type]++
+	bus.mutex.Unlock()
+	defer func() {
+		bus.mutex.Lock()
+		bus.pending[event.Type]--
+		bus.mutex.Unlock()
+	}()
 
-func (bus *eventBus) emit(event Event) {
 	// es werden erstmal die konkreten subscriber bedient
-	subscriptions, ok := bus.subscriber[event.Type]
-	if ok && len(subscriptions) > 0 {
-		for _, sub := range subscriptions {
-			sub(event)
-		}
+	for _, sub := range specific {
+		bus.handle(sub, event)
 	}
 
 	// und dann noch ein globaler subscriber
-	subscriptions, ok = bus.subscriber[eventType("*")]
-	if ok && len(subscriptions) > 0 {
-		for _, sub := range subscriptions {
-			sub(event)
+	for _, sub := range global {
+		bus.handle(sub, event)
+	}
+}
+
+func (bus *eventBus) handle(sub subscription, event Event) {
+	if err := sub(event); err != nil {
+		handlerErrors.Inc(string(event.Type))
+		bus.logger.Error("handler for event %s failed: %s", event.Type, err.Error())
+	}
+}
+
+func (bus *eventBus) queueDepth() map[string]float64 {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+	depth := make(map[string]float64, len(bus.pending))
+	for eType, count := range bus.pending {
+		depth[string(eType)] = float64(count)
+	}
+	return depth
+}
+
+// drain nimmt keine neuen Events mehr an und wartet, bis alle laufenden Zustellungen beendet sind
+func (bus *eventBus) drain(ctx context.Context) error {
+	bus.mutex.Lock()
+	bus.closed = true
+	bus.mutex.Unlock()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if bus.inFlight() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d events still dispatched: %w", bus.inFlight(), ctx.Err())
+		case <-ticker.C:
 		}
 	}
 }
 
+func (bus *eventBus) inFlight() (count int) {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+	for _, pending := range bus.pending {
+		count += pending
+	}
+	return count
+}
+
 func (bus *eventBus) subscribe(eType eventType, subscription subscription) error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
 	if _, ok := bus.subscriber[eType]; !ok {
 		bus.subscriber[eType] = make(subscriptions, 0)
 	}
@@ -49,13 +156,44 @@ func (bus *eventBus) subscribe(eType eventType, subscription subscription) error
 	return nil
 }
 
+func (bus *eventBus) useTransport(transport Transport) error {
+	if err := transport.Listen(bus.dispatch); err != nil {
+		return err
+	}
+	bus.mutex.Lock()
+	previous := bus.transport
+	bus.transport = transport
+	bus.mutex.Unlock()
+	return previous.Close()
+}
+
+// UseTransport ersetzt den Transport des Busses. Der bisherige Transport wird geschlossen.
+func UseTransport(transport Transport) error {
+	return bus.useTransport(transport)
+}
+
+// Drain wartet beim Herunterfahren auf die laufenden Zustellungen und schließt dann den Transport.
+// Danach liefert Emit ErrBusClosed.
+func Drain(ctx context.Context) error {
+	if err := bus.drain(ctx); err != nil {
+		return err
+	}
+	return Close()
+}
+
+// Close schließt den aktuell verwendeten Transport.
+func Close() error {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+	return bus.transport.Close()
+}
+
 func Emit(eType string, event interface{}) error {
 	if payload, err := json.Marshal(event); err != nil {
 		return err
 	} else {
-		bus.emit(Event{Type: eventType(eType), Payload: payload, ContenType: "application/json"})
+		return bus.emit(Event{Type: eventType(eType), Payload: payload, ContenType: "application/json"})
 	}
-	return nil
 }
 
 func Subscribe[T any](eType string, handler func(T) error) error {
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"testing"
+	"time"
+)
+
+func TestDrainWaitsForRunningHandlers(t *testing.T) {
+	bus := newEventBus(NewInProcessTransport())
+	started, release := make(chan struct{}), make(chan struct{})
+	_ = bus.subscribe("test.slow", func(Event) error {
+		close(started)
+		<-release
+		return nil
+	})
+	go func() { _ = bus.emit(Event{Type: "test.slow"}) }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := bus.drain(ctx)
+	utils.Assert(t, errors.Is(err, context.DeadlineExceeded), "expected timeout while handler runs, got %v", err)
+	utils.Assert(t, errors.Is(bus.emit(Event{Type: "test.slow"}), ErrBusClosed), "expected closed bus")
+
+	close(release)
+	utils.AssertNoError(t, bus.drain(context.Background()), "drain failed")
+	utils.Assert(t, bus.queueDepth()["test.slow"] == 0, "event still pending")
+}
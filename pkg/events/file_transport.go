@@ -0,0 +1,283 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxEventLogSize ist die Größe, ab der die Event-Datei durch eine leere ersetzt wird
+const MaxEventLogSize = 16 << 20
+
+// fileTransport nutzt eine gemeinsame Log-Datei als lokalen Broker. Jede Instanz hängt ihre
+// Events an die Datei an und liest in einem festen Intervall die Events der anderen Instanzen.
+//
+// Wird die Datei zu groß, ersetzt die schreibende Instanz sie durch eine leere, sobald sie selbst
+// alles gelesen hat. Die anderen Instanzen lesen die ersetzte Datei über ihr offenes Handle zu
+// Ende und wechseln erst dann, der Platz wird mit dem letzten Handle frei. Schreiben und Ersetzen
+// laufen unter einer Sperre auf <path>.lock, damit keine Instanz mehr in die alte Datei schreibt.
+// Eine Instanz, die eine ganze Datei lang nicht liest, verpasst deren Events.
+type fileTransport struct {
+	path     string
+	origin   string
+	interval time.Duration
+	maxSize  int64
+	lock     *os.File
+	writer   *utils.LogFile
+	reader   *utils.LogReader
+	deliver  func(Event)
+	logger   utils.Logger
+	mutex    *sync.Mutex
+	done     chan struct{}
+	stopped  chan struct{}
+	closed   bool
+	// Offset des Readers, damit Backlog ihn ohne Zugriff auf den Reader lesen kann
+	offset *atomic.Int64
+	// current ist gesetzt, solange der Reader die Datei unter path liest
+	current *atomic.Bool
+}
+
+func NewFileTransport(path string, pollInterval time.Duration) (Transport, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	transport := &fileTransport{
+		path:     path,
+		origin:   uuid.New().String(),
+		interval: pollInterval,
+		maxSize:  MaxEventLogSize,
+		lock:     lock,
+		logger:   utils.NewStdLogger("events.file-transport"),
+		mutex:    &sync.Mutex{},
+		offset:   &atomic.Int64{},
+		current:  &atomic.Bool{},
+	}
+	err = transport.locked(func() (err error) {
+		if transport.writer, err = utils.OpenLogFile(path, utils.SyncNever); err != nil {
+			return err
+		} else if transport.reader, err = utils.OpenLogReader(path); err != nil {
+			return errors.Join(err, transport.writer.Close())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(err, lock.Close())
+	}
+	return transport, nil
+}
+
+// locked führt action unter der Sperre aller Instanzen aus
+func (transport *fileTransport) locked(action func() error) error {
+	if err := lockFile(transport.lock); err != nil {
+		return err
+	}
+	err := action()
+	return errors.Join(err, unlockFile(transport.lock))
+}
+
+func (transport *fileTransport) Publish(event Event) error {
+	event.Origin = transport.origin
+	transport.mutex.Lock()
+	err := transport.locked(func() error {
+		if err := transport.prepareWriter(); err != nil {
+			return err
+		}
+		return utils.Append(transport.writer, event, utils.B64JsonEncoder[Event])
+	})
+	deliver := transport.deliver
+	transport.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	// die eigene Instanz wird direkt bedient, die Events aus der Datei werden dann übersprungen
+	if deliver != nil {
+		deliver(event)
+	}
+	return nil
+}
+
+// prepareWriter öffnet die Datei neu, wenn eine andere Instanz sie ersetzt hat, und ersetzt sie
+// selbst, wenn sie zu groß ist und die eigene Instanz alles gelesen hat
+func (transport *fileTransport) prepareWriter() error {
+	written, err := transport.writer.Stat()
+	if err != nil {
+		return err
+	}
+	current, err := os.Stat(transport.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	} else if err != nil || !os.SameFile(written, current) {
+		writer, err := utils.OpenLogFile(transport.path, utils.SyncNever)
+		if err != nil {
+			return err
+		}
+		return transport.replaceWriter(writer)
+	} else if !rotationSupported || written.Size() < transport.maxSize || !transport.current.Load() || transport.offset.Load() < written.Size() {
+		return nil
+	}
+
+	temp := transport.path + ".tmp"
+	writer, err := utils.CreateLogFile(temp, utils.SyncNever)
+	if err != nil {
+		return err
+	} else if err = os.Rename(temp, transport.path); err != nil {
+		return errors.Join(err, writer.Close())
+	}
+	transport.logger.Info("replaced %s after %d bytes", transport.path, written.Size())
+	transport.current.Store(false)
+	return transport.replaceWriter(writer)
+}
+
+func (transport *fileTransport) replaceWriter(writer *utils.LogFile) error {
+	previous := transport.writer
+	transport.writer = writer
+	return previous.Close()
+}
+
+func (transport *fileTransport) Listen(deliver func(Event)) error {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	if transport.done != nil {
+		return fmt.Errorf("transport for %s is already listening", transport.path)
+	}
+	// es werden nur Events zugestellt, die nach dem Start geschrieben werden
+	err := transport.locked(func() error {
+		if next, err := transport.replacement(); err != nil {
+			return err
+		} else if next != nil {
+			transport.replaceReader(next)
+		}
+		return transport.reader.SeekEnd()
+	})
+	if err != nil {
+		return err
+	}
+	transport.offset.Store(transport.reader.Offset())
+	transport.deliver = deliver
+	transport.done = make(chan struct{})
+	transport.stopped = make(chan struct{})
+	go transport.poll(transport.done)
+	return nil
+}
+
+func (transport *fileTransport) poll(done chan struct{}) {
+	defer close(transport.stopped)
+	ticker := time.NewTicker(transport.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := transport.receive(); err != nil {
+				transport.logger.Error("unable to read events from %s: %s", transport.path, err.Error())
+			}
+		}
+	}
+}
+
+// liest alle vollständig geschriebenen Events ab dem aktuellen Offset. Ein unvollständiger
+// Datensatz am Ende wird beim nächsten Durchlauf erneut gelesen. Wurde die Datei ersetzt, wird
+// die alte zu Ende gelesen und dann auf die neue gewechselt.
+func (transport *fileTransport) receive() error {
+	defer func() { transport.offset.Store(transport.reader.Offset()) }()
+	var next *utils.LogReader
+	for {
+		data, err := transport.reader.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if next != nil {
+				// in die ersetzte Datei wird nicht mehr geschrieben, ein Rest ist ein abgebrochener Datensatz
+				if errors.Is(err, io.ErrUnexpectedEOF) {
+					transport.logger.Warn("skip incomplete event at the end of the replaced %s", transport.path)
+				}
+				transport.replaceReader(next)
+				next = nil
+				continue
+			}
+			if err = transport.locked(func() (err error) {
+				next, err = transport.replacement()
+				transport.current.Store(err == nil && next == nil)
+				return err
+			}); err != nil || next == nil {
+				return err
+			}
+			continue
+		} else if errors.Is(err, utils.ErrChecksum) {
+			transport.logger.Warn("skip corrupt event in %s before offset %d", transport.path, transport.reader.Offset())
+			continue
+		} else if err != nil {
+			if next != nil {
+				_ = next.Close()
+			}
+			return err
+		}
+
+		event, err := utils.B64JsonDecoder[Event](data)
+		if err != nil {
+			if next != nil {
+				_ = next.Close()
+			}
+			return err
+		}
+		if event.Origin != transport.origin {
+			transport.deliver(event)
+		}
+	}
+}
+
+// replacement öffnet die Datei unter path, wenn sie nicht mehr die gelesene ist
+func (transport *fileTransport) replacement() (*utils.LogReader, error) {
+	read, err := transport.reader.Stat()
+	if err != nil {
+		return nil, err
+	}
+	current, err := os.Stat(transport.path)
+	if errors.Is(err, os.ErrNotExist) || err == nil && os.SameFile(read, current) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return utils.OpenLogReader(transport.path)
+}
+
+func (transport *fileTransport) replaceReader(reader *utils.LogReader) {
+	if err := transport.reader.Close(); err != nil {
+		transport.logger.Warn("unable to close replaced %s: %s", transport.path, err.Error())
+	}
+	transport.reader = reader
+	transport.offset.Store(reader.Offset())
+	transport.current.Store(false)
+}
+
+// Backlog liefert die Anzahl der Bytes in der Datei, die noch nicht gelesen wurden. Direkt nach
+// dem Ersetzen der Datei zählen die Events der alten Datei nicht mehr mit.
+func (transport *fileTransport) Backlog() int64 {
+	info, err := os.Stat(transport.path)
+	if err != nil || info.Size() < transport.offset.Load() {
+		return 0
+	}
+	return info.Size() - transport.offset.Load()
+}
+
+// Close beendet das Polling und schließt die Datei. Weitere Aufrufe, etwa nach Drain, liefern nil.
+func (transport *fileTransport) Close() error {
+	transport.mutex.Lock()
+	done, closed := transport.done, transport.closed
+	transport.done, transport.closed = nil, true
+	transport.mutex.Unlock()
+	if closed {
+		return nil
+	}
+	if done != nil {
+		close(done)
+		<-transport.stopped
+	}
+	return errors.Join(transport.writer.Close(), transport.reader.Close(), transport.lock.Close())
+}
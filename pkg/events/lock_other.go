@@ -0,0 +1,16 @@
+//go:build !unix
+
+package events
+
+import "os"
+
+// ohne flock können sich die Instanzen nicht abstimmen, die Event-Datei wird dann nie ersetzt
+const rotationSupported = false
+
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
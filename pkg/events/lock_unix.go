@@ -0,0 +1,18 @@
+//go:build unix
+
+package events
+
+import (
+	"os"
+	"syscall"
+)
+
+const rotationSupported = true
+
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,44 @@
+package events
+
+import "sync"
+
+// Transport verteilt Events zwischen den Instanzen einer Anwendung. Publish stellt ein Event
+// allen Instanzen zu, auch der eigenen. Über Listen registriert der Bus die Funktion, mit der
+// empfangene Events an die Subscriber weitergereicht werden.
+type Transport interface {
+	Publish(event Event) error
+	Listen(deliver func(Event)) error
+	Close() error
+}
+
+type inProcessTransport struct {
+	deliver func(Event)
+	mutex   *sync.RWMutex
+}
+
+// NewInProcessTransport erzeugt den Standard-Transport, der Events synchron innerhalb des
+// eigenen Prozesses zustellt.
+func NewInProcessTransport() Transport {
+	return &inProcessTransport{mutex: &sync.RWMutex{}}
+}
+
+func (transport *inProcessTransport) Publish(event Event) error {
+	transport.mutex.RLock()
+	deliver := transport.deliver
+	transport.mutex.RUnlock()
+	if deliver != nil {
+		deliver(event)
+	}
+	return nil
+}
+
+func (transport *inProcessTransport) Listen(deliver func(Event)) error {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	transport.deliver = deliver
+	return nil
+}
+
+func (transport *inProcessTransport) Close() error {
+	return nil
+}
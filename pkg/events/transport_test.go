@@ -0,0 +1,128 @@
+package events
+
+import (
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func receiveInto(channel chan Event) func(Event) {
+	return func(event Event) {
+		channel <- event
+	}
+}
+
+func awaitEvent(channel chan Event) (Event, bool) {
+	select {
+	case event := <-channel:
+		return event, true
+	case <-time.After(time.Second):
+		return Event{}, false
+	}
+}
+
+func TestInProcessTransportDeliversLocally(t *testing.T) {
+	received := make(chan Event, 1)
+	transport := NewInProcessTransport()
+	utils.AssertNoError(t, transport.Listen(receiveInto(received)), "listen failed")
+	utils.AssertNoError(t, transport.Publish(Event{Type: "test.event"}), "publish failed")
+
+	event, ok := awaitEvent(received)
+	utils.Assert(t, ok, "event not received")
+	utils.Assert(t, event.Type == "test.event", "wrong event type %s", event.Type)
+}
+
+func TestFileTransportDeliversToOtherInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	receivedA := make(chan Event, 10)
+	receivedB := make(chan Event, 10)
+
+	instanceA, err := NewFileTransport(path, 10*time.Millisecond)
+	utils.AssertNoError(t, err, "unable to create transport A")
+	defer instanceA.Close()
+	instanceB, err := NewFileTransport(path, 10*time.Millisecond)
+	utils.AssertNoError(t, err, "unable to create transport B")
+	defer instanceB.Close()
+
+	utils.AssertNoError(t, instanceA.Listen(receiveInto(receivedA)), "listen A failed")
+	utils.AssertNoError(t, instanceB.Listen(receiveInto(receivedB)), "listen B failed")
+
+	utils.AssertNoError(t, instanceA.Publish(Event{Type: "test.event", Payload: []byte(`{"a":1}`), ContenType: "application/json"}), "publish failed")
+
+	local, ok := awaitEvent(receivedA)
+	utils.Assert(t, ok, "event not delivered to own instance")
+	utils.Assert(t, local.Type == "test.event", "wrong local event type %s", local.Type)
+
+	remote, ok := awaitEvent(receivedB)
+	utils.Assert(t, ok, "event not delivered to other instance")
+	utils.Assert(t, string(remote.Payload) == `{"a":1}`, "wrong payload %s", remote.Payload)
+
+	// die eigene Instanz darf das Event nicht ein zweites Mal aus der Datei erhalten
+	_, duplicate := awaitEvent(receivedA)
+	utils.Assert(t, !duplicate, "event delivered twice to own instance")
+}
+
+func TestFileTransportSkipsEventsBeforeListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	received := make(chan Event, 10)
+
+	writer, err := NewFileTransport(path, 10*time.Millisecond)
+	utils.AssertNoError(t, err, "unable to create writer")
+	defer writer.Close()
+	utils.AssertNoError(t, writer.Publish(Event{Type: "old.event"}), "publish failed")
+
+	reader, err := NewFileTransport(path, 10*time.Millisecond)
+	utils.AssertNoError(t, err, "unable to create reader")
+	defer reader.Close()
+	utils.AssertNoError(t, reader.Listen(receiveInto(received)), "listen failed")
+	utils.AssertNoError(t, writer.Publish(Event{Type: "new.event"}), "publish failed")
+
+	event, ok := awaitEvent(received)
+	utils.Assert(t, ok, "event not received")
+	utils.Assert(t, event.Type == "new.event", "unexpected event %s", event.Type)
+}
+
+func TestFileTransportCloseIsIdempotent(t *testing.T) {
+	transport, err := NewFileTransport(filepath.Join(t.TempDir(), "events.log"), 10*time.Millisecond)
+	utils.AssertNoError(t, err, "unable to create transport")
+	utils.AssertNoError(t, transport.Listen(receiveInto(make(chan Event, 1))), "listen failed")
+	utils.AssertNoError(t, transport.Close(), "first close failed")
+	utils.AssertNoError(t, transport.Close(), "second close failed")
+}
+
+func TestFileTransportReplacesLargeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	receivedA := make(chan Event, 100)
+	receivedB := make(chan Event, 100)
+
+	instanceA, err := NewFileTransport(path, 5*time.Millisecond)
+	utils.AssertNoError(t, err, "unable to create transport A")
+	defer instanceA.Close()
+	instanceA.(*fileTransport).maxSize = 512
+	instanceB, err := NewFileTransport(path, 5*time.Millisecond)
+	utils.AssertNoError(t, err, "unable to create transport B")
+	defer instanceB.Close()
+
+	utils.AssertNoError(t, instanceA.Listen(receiveInto(receivedA)), "listen A failed")
+	utils.AssertNoError(t, instanceB.Listen(receiveInto(receivedB)), "listen B failed")
+
+	for i := 0; i < 100; i++ {
+		payload := []byte(fmt.Sprintf(`{"i":%d}`, i))
+		utils.AssertNoError(t, instanceA.Publish(Event{Type: "test.event", Payload: payload}), "publish %d failed", i)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// B liest jede ersetzte Datei zu Ende, es darf kein Event fehlen
+	for i := 0; i < 100; i++ {
+		event, ok := awaitEvent(receivedB)
+		utils.Assert(t, ok, "event %d not received", i)
+		utils.Assert(t, string(event.Payload) == fmt.Sprintf(`{"i":%d}`, i), "unexpected payload %s at %d", event.Payload, i)
+	}
+
+	info, err := os.Stat(path)
+	utils.AssertNoError(t, err, "unable to stat event log")
+	utils.Assert(t, !rotationSupported || info.Size() < 1024, "event log not replaced, size %d", info.Size())
+}
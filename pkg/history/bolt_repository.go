@@ -0,0 +1,58 @@
+package history
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"go.etcd.io/bbolt"
+	"sync"
+)
+
+var historiesBucket = []byte("histories")
+
+type boltRepository struct {
+	*memoryRepository
+	db *bbolt.DB
+}
+
+func CreateBoltRepository(db *bbolt.DB) (Repository, error) {
+	repo := &boltRepository{
+		memoryRepository: &memoryRepository{values: make(map[uuid.UUID]History), mutex: &sync.Mutex{}},
+		db:               db,
+	}
+	if err := storage.CreateBuckets(db, string(historiesBucket)); err != nil {
+		return nil, err
+	}
+	err := db.View(func(tx *bbolt.Tx) error {
+		return storage.ForEachJson(tx.Bucket(historiesBucket), func(_ []byte, record historyRecord) error {
+			repo.values[record.Id] = record.toDomain()
+			return nil
+		})
+	})
+	return repo, err
+}
+
+func (repo *boltRepository) Save(ctx context.Context, hist History) (History, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		return storage.PutJson(tx.Bucket(historiesBucket), hist.Id[:], toHistoryRecord(hist))
+	})
+	if err != nil {
+		return hist, err
+	}
+	repo.values[hist.Id] = hist
+	return hist, nil
+}
+
+func (repo *boltRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(historiesBucket).Delete(id[:])
+	})
+	if err == nil {
+		delete(repo.values, id)
+	}
+	return err
+}
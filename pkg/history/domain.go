@@ -4,8 +4,18 @@ import (
 	"github.com/google/uuid"
 )
 
+// State folgt dem Lebenszyklus des Trainings
+type State string
+
+const (
+	Active   State = "active"
+	Paused   State = "paused"
+	Archived State = "archived"
+)
+
 type History struct {
 	Id             uuid.UUID
+	State          State
 	currentAnswers []uuid.UUID
 	history        []Item
 }
@@ -19,6 +29,7 @@ type Item struct {
 func CreateHistory(id uuid.UUID) History {
 	return History{
 		Id:             id,
+		State:          Active,
 		currentAnswers: make([]uuid.UUID, 0),
 		history:        make([]Item, 0),
 	}
@@ -37,6 +48,11 @@ func (hist *History) AddAnswer(answerIds []uuid.UUID) {
 	hist.currentAnswers = append(hist.currentAnswers, answerIds...)
 }
 
+// Reset verwirft die Antworten zur aktuellen Challenge, die abgeschlossenen Einträge bleiben erhalten
+func (hist *History) Reset() {
+	hist.currentAnswers = make([]uuid.UUID, 0)
+}
+
 func (hist *History) Size() int {
 	return len(hist.history)
 }
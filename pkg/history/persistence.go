@@ -0,0 +1,42 @@
+package history
+
+import "github.com/google/uuid"
+
+// historyRecord ist das persistierte Modell einer History, unabhängig von den privaten Feldern
+// des Domain-Structs
+type historyRecord struct {
+	Id             uuid.UUID    `json:"id"`
+	State          State        `json:"state,omitempty"`
+	CurrentAnswers []uuid.UUID  `json:"currentAnswers"`
+	Items          []itemRecord `json:"items"`
+}
+
+type itemRecord struct {
+	ChallengeId   uuid.UUID   `json:"challengeId"`
+	GivenAnswers  []uuid.UUID `json:"givenAnswers"`
+	SolvingAnswer []uuid.UUID `json:"solvingAnswer"`
+}
+
+func toHistoryRecord(hist History) historyRecord {
+	items := make([]itemRecord, 0, len(hist.history))
+	for _, item := range hist.history {
+		items = append(items, itemRecord(item))
+	}
+	return historyRecord{Id: hist.Id, State: hist.State, CurrentAnswers: hist.currentAnswers, Items: items}
+}
+
+func (record historyRecord) toDomain() History {
+	items := make([]Item, 0, len(record.Items))
+	for _, item := range record.Items {
+		items = append(items, Item(item))
+	}
+	currentAnswers := record.CurrentAnswers
+	if currentAnswers == nil {
+		currentAnswers = make([]uuid.UUID, 0)
+	}
+	state := record.State
+	if state == "" {
+		state = Active
+	}
+	return History{Id: record.Id, State: state, currentAnswers: currentAnswers, history: items}
+}
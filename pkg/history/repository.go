@@ -4,28 +4,50 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"github.com/ohrenpiraten/go-collections/predicates"
+	"sync"
 )
 
-type Repository struct {
+type Repository interface {
+	Save(ctx context.Context, hist History) (History, error)
+	FindFirst(ctx context.Context, predicate predicates.Predicate[History]) (History, bool)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+func IdEquals(value uuid.UUID) predicates.Predicate[History] {
+	return func(q History) bool {
+		return value == q.Id
+	}
+}
+
+type memoryRepository struct {
 	values map[uuid.UUID]History
+	mutex  *sync.Mutex
 }
 
+// CreateRepo hält die Historien nur im Speicher, das ist die Ablage des Backends file. Nach einem
+// Neustart fehlen die Historien aller Trainings und werden beim nächsten Event leer angelegt.
+// ceh copy-to-bolt kann sie daher nicht übernehmen, persistent sind sie nur im Backend bolt.
 func CreateRepo() (repo Repository, err error) {
-	return Repository{make(map[uuid.UUID]History)}, nil
+	return &memoryRepository{values: make(map[uuid.UUID]History), mutex: &sync.Mutex{}}, nil
 }
 
-func (repo *Repository) Save(_ context.Context, hist History) (History, error) {
+func (repo *memoryRepository) Save(_ context.Context, hist History) (History, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
 	repo.values[hist.Id] = hist
 	return hist, nil
 }
 
-func IdEquals(value uuid.UUID) predicates.Predicate[History] {
-	return func(q History) bool {
-		return value == q.Id
-	}
+func (repo *memoryRepository) Delete(_ context.Context, id uuid.UUID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	delete(repo.values, id)
+	return nil
 }
 
-func (repo *Repository) FindFirst(_ context.Context, predicate predicates.Predicate[History]) (history History, exists bool) {
+func (repo *memoryRepository) FindFirst(_ context.Context, predicate predicates.Predicate[History]) (history History, exists bool) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
 	for _, history := range repo.values {
 		if predicate(history) {
 			return history, true
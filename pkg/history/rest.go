@@ -2,6 +2,7 @@ package history
 
 import (
 	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
 	"github.com/mwildt/go-http/httputils"
 	"github.com/mwildt/go-http/routing"
 	"net/http"
@@ -26,17 +27,18 @@ func (controller *Controller) Routing(router routing.Routing) {
 func (controller *Controller) GetHistory(w http.ResponseWriter, r *http.Request) {
 	type responseDTO struct {
 		Id    uuid.UUID `json:"id"`
+		State State     `json:"state"`
 		Total int       `json:"total"`
 	}
 
 	if idString, exists := routing.GetParameter(r.Context(), "historyId"); !exists {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "missing history id")
 	} else if historyId, err := uuid.Parse(idString); err != nil {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "invalid history id")
 	} else if hist, exists := controller.repo.FindFirst(r.Context(), IdEquals(historyId)); !exists {
-		httputils.NotFound(w, r)
+		utils.NotFound(w, r, "history not found")
 	} else {
-		httputils.OkJson(w, r, responseDTO{Id: hist.Id, Total: hist.Size()})
+		httputils.OkJson(w, r, responseDTO{Id: hist.Id, State: hist.State, Total: hist.Size()})
 	}
 }
 
@@ -50,17 +52,17 @@ func (controller *Controller) GetHistoryItem(w http.ResponseWriter, r *http.Requ
 	}
 
 	if idString, exists := routing.GetParameter(r.Context(), "historyId"); !exists {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "missing history id")
 	} else if historyId, err := uuid.Parse(idString); err != nil {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "invalid history id")
 	} else if idxString, exists := routing.GetParameter(r.Context(), "historyIndex"); !exists {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "missing history index")
 	} else if historyIndex, err := strconv.Atoi(idxString); err != nil {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "invalid history index")
 	} else if hist, exists := controller.repo.FindFirst(r.Context(), IdEquals(historyId)); !exists {
-		httputils.NotFound(w, r)
+		utils.NotFound(w, r, "history not found")
 	} else if found, item := hist.HistoryItemAt(historyIndex); !found {
-		w.WriteHeader(http.StatusNotFound)
+		utils.NotFound(w, r, "history item not found")
 	} else {
 		httputils.OkJson(w, r, responseDTO{
 			Id:             hist.Id,
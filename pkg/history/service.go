@@ -46,5 +46,40 @@ func Subscribe(repository Repository) error {
 		return err
 	}
 	logger.Info("successfully registered to training.updaed")
+
+	// der Lebenszyklus des Trainings wird in der History nachgeführt
+	lifecycle := map[string]func(*History){
+		"training.paused":   func(history *History) { history.State = Paused },
+		"training.resumed":  func(history *History) { history.State = Active },
+		"training.archived": func(history *History) { history.State = Archived },
+		"training.reset":    (*History).Reset,
+	}
+	for eventType, update := range lifecycle {
+		eventType, update := eventType, update
+		err = events.Subscribe(eventType, func(event training.LifecycleEvent) error {
+			logger.Info("handle event %s for id %s", eventType, event.TrainingId)
+			history, found := repository.FindFirst(context.TODO(), IdEquals(event.TrainingId))
+			if !found {
+				logger.Error("unable to find history with id %s - try to create new ", event.TrainingId)
+				history = CreateHistory(event.TrainingId)
+			}
+			update(&history)
+			_, err := repository.Save(context.TODO(), history)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		logger.Info("successfully registered to %s", eventType)
+	}
+
+	err = events.Subscribe("training.deleted", func(event training.LifecycleEvent) error {
+		logger.Info("handle event training.deleted for id %s", event.TrainingId)
+		return repository.Delete(context.TODO(), event.TrainingId)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("successfully registered to training.deleted")
 	return nil
 }
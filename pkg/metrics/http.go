@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	httpRequests = NewCounter("ceh_http_requests_total", "Number of HTTP requests by route and status.", "method", "route", "status")
+	httpDuration = NewHistogram("ceh_http_request_duration_seconds", "Latency of HTTP requests by route.", DefaultBuckets, "method", "route")
+)
+
+// statusRecorder merkt sich den Status, den der Handler schreibt
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Instrument zählt Requests und misst ihre Dauer je Route. Requests ohne passende Route (404
+// ohne Parameter) werden unter "unmatched" gezählt.
+func Instrument() routing.Filter {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next(recorder, r)
+		route := Route(r)
+		if recorder.status == http.StatusNotFound && len(routing.GetParameters(r.Context())) == 0 {
+			route = "unmatched"
+		}
+		httpRequests.Inc(r.Method, route, strconv.Itoa(recorder.status))
+		httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	}
+}
+
+// Route liefert das Muster der Route, z.B. /api/trainings/{trainingId}. Die Parameter der Route
+// werden wieder durch ihre Namen ersetzt, damit nicht jede Id eine eigene Zeitreihe erzeugt.
+func Route(r *http.Request) string {
+	parameters := routing.GetParameters(r.Context())
+	if len(parameters) == 0 {
+		return r.URL.Path
+	}
+	segments := strings.Split(r.URL.Path, "/")
+	for i, segment := range segments {
+		for name, value := range parameters {
+			if segment != "" && segment == value {
+				segments[i] = "{" + name + "}"
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
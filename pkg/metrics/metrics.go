@@ -0,0 +1,294 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector liefert beim Abruf die aktuellen Werte einer Metrik
+type Collector interface {
+	Name() string
+	write(out *bufio.Writer)
+}
+
+// Registry hält alle Metriken, die unter /metrics im Prometheus-Textformat ausgegeben werden
+type Registry struct {
+	collectors map[string]Collector
+	mutex      *sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector), mutex: &sync.RWMutex{}}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register fügt eine Metrik hinzu. Ein doppelter Name ist ein Programmierfehler und führt zu
+// einem panic.
+func (registry *Registry) Register(collector Collector) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if _, exists := registry.collectors[collector.Name()]; exists {
+		panic(fmt.Sprintf("metric %s is already registered", collector.Name()))
+	}
+	registry.collectors[collector.Name()] = collector
+}
+
+// Write gibt alle Metriken sortiert nach Namen aus
+func (registry *Registry) Write(out io.Writer) error {
+	registry.mutex.RLock()
+	names := make([]string, 0, len(registry.collectors))
+	for name := range registry.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]Collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, registry.collectors[name])
+	}
+	registry.mutex.RUnlock()
+
+	writer := bufio.NewWriter(out)
+	for _, collector := range collectors {
+		collector.write(writer)
+	}
+	return writer.Flush()
+}
+
+func (registry *Registry) Handler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writer.WriteHeader(http.StatusOK)
+		_ = registry.Write(writer)
+	}
+}
+
+// Handler liefert die Metriken der Standard-Registry
+func Handler() http.HandlerFunc {
+	return defaultRegistry.Handler()
+}
+
+// Register fügt eine Metrik der Standard-Registry hinzu
+func Register(collector Collector) {
+	defaultRegistry.Register(collector)
+}
+
+type descriptor struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (desc descriptor) Name() string {
+	return desc.name
+}
+
+func (desc descriptor) writeHeader(out *bufio.Writer) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", desc.name, strings.ReplaceAll(desc.help, "\n", " "), desc.name, desc.kind)
+}
+
+func (desc descriptor) key(values []string) string {
+	if len(values) != len(desc.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", desc.name, len(desc.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func writeSample(out *bufio.Writer, name string, labels []string, values []string, value float64) {
+	out.WriteString(name)
+	if len(labels) > 0 {
+		out.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			fmt.Fprintf(out, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(' ')
+	out.WriteString(formatValue(value))
+	out.WriteByte('\n')
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[T any](series map[string]T) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func splitKey(key string, count int) []string {
+	if count == 0 {
+		return nil
+	}
+	return strings.Split(key, "\xff")
+}
+
+// Counter zählt Ereignisse je Kombination von Label-Werten
+type Counter struct {
+	descriptor
+	values map[string]float64
+	mutex  *sync.Mutex
+}
+
+// NewCounter erzeugt einen Counter und registriert ihn in der Standard-Registry
+func NewCounter(name string, help string, labels ...string) *Counter {
+	counter := &Counter{
+		descriptor: descriptor{name: name, help: help, kind: "counter", labels: labels},
+		values:     make(map[string]float64),
+		mutex:      &sync.Mutex{},
+	}
+	Register(counter)
+	return counter
+}
+
+func (counter *Counter) Inc(values ...string) {
+	counter.Add(1, values...)
+}
+
+func (counter *Counter) Add(delta float64, values ...string) {
+	key := counter.key(values)
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.values[key] += delta
+}
+
+// Value liefert den aktuellen Stand für die Label-Werte
+func (counter *Counter) Value(values ...string) float64 {
+	key := counter.key(values)
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	return counter.values[key]
+}
+
+func (counter *Counter) write(out *bufio.Writer) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.writeHeader(out)
+	for _, key := range sortedKeys(counter.values) {
+		writeSample(out, counter.name, counter.labels, splitKey(key, len(counter.labels)), counter.values[key])
+	}
+}
+
+// DefaultBuckets sind die Obergrenzen in Sekunden für Latenzen von HTTP-Requests
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Histogram verteilt beobachtete Werte auf Buckets mit festen Obergrenzen
+type Histogram struct {
+	descriptor
+	buckets []float64
+	series  map[string]*histogramSeries
+	mutex   *sync.Mutex
+}
+
+// NewHistogram erzeugt ein Histogram und registriert es in der Standard-Registry
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{
+		descriptor: descriptor{name: name, help: help, kind: "histogram", labels: labels},
+		buckets:    append([]float64{}, buckets...),
+		series:     make(map[string]*histogramSeries),
+		mutex:      &sync.Mutex{},
+	}
+	sort.Float64s(histogram.buckets)
+	Register(histogram)
+	return histogram
+}
+
+func (histogram *Histogram) Observe(value float64, values ...string) {
+	key := histogram.key(values)
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	series, exists := histogram.series[key]
+	if !exists {
+		series = &histogramSeries{counts: make([]uint64, len(histogram.buckets))}
+		histogram.series[key] = series
+	}
+	for i, bound := range histogram.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+	series.sum += value
+	series.count++
+}
+
+func (histogram *Histogram) write(out *bufio.Writer) {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	histogram.writeHeader(out)
+	labels := append(append([]string{}, histogram.labels...), "le")
+	for _, key := range sortedKeys(histogram.series) {
+		series := histogram.series[key]
+		values := splitKey(key, len(histogram.labels))
+		for i, bound := range histogram.buckets {
+			writeSample(out, histogram.name+"_bucket", labels, append(append([]string{}, values...), formatValue(bound)), float64(series.counts[i]))
+		}
+		writeSample(out, histogram.name+"_bucket", labels, append(append([]string{}, values...), "+Inf"), float64(series.count))
+		writeSample(out, histogram.name+"_sum", histogram.labels, values, series.sum)
+		writeSample(out, histogram.name+"_count", histogram.labels, values, float64(series.count))
+	}
+}
+
+// GaugeFunc ermittelt ihre Werte erst beim Abruf der Metriken. Mit einem Label liefert collect
+// einen Wert je Label-Wert, ohne Label einen Wert unter dem leeren Schlüssel.
+type GaugeFunc struct {
+	descriptor
+	collect func() map[string]float64
+}
+
+// NewGaugeFunc erzeugt eine GaugeFunc und registriert sie in der Standard-Registry
+func NewGaugeFunc(name string, help string, collect func() float64) *GaugeFunc {
+	return NewGaugeVecFunc(name, help, "", func() map[string]float64 {
+		return map[string]float64{"": collect()}
+	})
+}
+
+// NewGaugeVecFunc erzeugt eine GaugeFunc mit einem Label und registriert sie in der Standard-Registry
+func NewGaugeVecFunc(name string, help string, label string, collect func() map[string]float64) *GaugeFunc {
+	var labels []string
+	if label != "" {
+		labels = []string{label}
+	}
+	gauge := &GaugeFunc{
+		descriptor: descriptor{name: name, help: help, kind: "gauge", labels: labels},
+		collect:    collect,
+	}
+	Register(gauge)
+	return gauge
+}
+
+func (gauge *GaugeFunc) write(out *bufio.Writer) {
+	values := gauge.collect()
+	gauge.writeHeader(out)
+	for _, key := range sortedKeys(values) {
+		writeSample(out, gauge.name, gauge.labels, splitKey(key, len(gauge.labels)), values[key])
+	}
+}
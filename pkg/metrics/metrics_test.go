@@ -0,0 +1,74 @@
+package metrics_test
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/metrics"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func exposition(t *testing.T) string {
+	recorder := httptest.NewRecorder()
+	metrics.Handler()(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return recorder.Body.String()
+}
+
+func assertContains(t *testing.T, output string, lines ...string) {
+	for _, line := range lines {
+		utils.Assert(t, strings.Contains(output, line+"\n"), "missing %q in\n%s", line, output)
+	}
+}
+
+func TestExposition(t *testing.T) {
+	counter := metrics.NewCounter("test_events_total", "Number of test events.", "kind")
+	counter.Inc("a")
+	counter.Add(2, `quote"d`)
+	histogram := metrics.NewHistogram("test_duration_seconds", "Test durations.", []float64{1, 0.1})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	metrics.NewGaugeVecFunc("test_items", "Number of test items.", "tag", func() map[string]float64 {
+		return map[string]float64{"x": 2, "y": 1}
+	})
+
+	assertContains(t, exposition(t),
+		"# TYPE test_events_total counter",
+		`test_events_total{kind="a"} 1`,
+		`test_events_total{kind="quote\"d"} 2`,
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{le="0.1"} 1`,
+		`test_duration_seconds_bucket{le="1"} 2`,
+		`test_duration_seconds_bucket{le="+Inf"} 3`,
+		"test_duration_seconds_sum 3.55",
+		"test_duration_seconds_count 3",
+		`test_items{tag="x"} 2`,
+	)
+
+	defer func() {
+		utils.Assert(t, recover() != nil, "expected panic for duplicate metric")
+	}()
+	metrics.NewCounter("test_events_total", "duplicate")
+}
+
+func TestInstrumentUsesRoutePattern(t *testing.T) {
+	router := routing.NewRouter()
+	router.Route(routing.Filtering(metrics.Instrument()), func(router routing.Routing) {
+		router.HandleFunc(routing.Get("/api/items/{itemId}/parts"), func(w http.ResponseWriter, r *http.Request) {
+			httputils.NotFound(w, r)
+		})
+		router.HandleFunc(routing.Path("/"), httputils.NotFound)
+	})
+
+	for _, path := range []string{"/api/items/1/parts", "/api/items/2/parts", "/", "/"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	assertContains(t, exposition(t),
+		`ceh_http_requests_total{method="GET",route="/api/items/{itemId}/parts",status="404"} 2`,
+		`ceh_http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`ceh_http_request_duration_seconds_count{method="GET",route="unmatched"} 2`,
+	)
+}
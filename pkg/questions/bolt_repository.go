@@ -0,0 +1,108 @@
+package questions
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"go.etcd.io/bbolt"
+)
+
+var questionsBucket = []byte("questions")
+
+// boltRepository speichert die schreibbaren Fragen in der eingebetteten Datenbank
+type boltRepository struct {
+	*questionIndex
+	db *bbolt.DB
+}
+
+func CreateBoltRepository(db *bbolt.DB, sources ...Source) (Repository, error) {
+	repo := &boltRepository{questionIndex: newQuestionIndex(), db: db}
+	if err := storage.CreateBuckets(db, string(questionsBucket)); err != nil {
+		return nil, err
+	}
+	if err := repo.loadSources(sources); err != nil {
+		return nil, err
+	}
+	count := 0
+	err := db.View(func(tx *bbolt.Tx) error {
+		return storage.ForEachVersionedJson(tx.Bucket(questionsBucket), questionSchema, func(_ []byte, record questionRecord) error {
+			record.Question.init()
+			repo.putLocal(record)
+			count++
+			return nil
+		})
+	})
+	if err == nil {
+		repo.logger.Info("%d items loaded from database, %d in store", count, len(repo.values))
+		repo.logDivergedOverrides()
+	}
+	return repo, err
+}
+
+func (repo *boltRepository) Save(question *Question) (_ *Question, err error) {
+	if err = repo.checkWritable(question); err != nil {
+		return question, err
+	}
+	repo.mutex.Lock()
+	err = repo.store(repo.localRecord(question))
+	repo.mutex.Unlock()
+	if err != nil {
+		return question, err
+	}
+	question.emitEvents()
+	return question, err
+}
+
+// ResetToUpstream löscht die lokale Änderung, danach gilt wieder die Frage aus der Quelle
+func (repo *boltRepository) ResetToUpstream(id uuid.UUID) (*Question, error) {
+	repo.mutex.Lock()
+	record, upstream, err := repo.resetRecord(id)
+	if err == nil {
+		err = repo.store(record)
+	}
+	repo.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	upstream.init(updatedEvent(upstream)).emitEvents()
+	return upstream, nil
+}
+
+func (repo *boltRepository) store(record questionRecord) error {
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		if record.Reset {
+			return tx.Bucket(questionsBucket).Delete(record.Id[:])
+		}
+		return storage.PutVersionedJson(tx.Bucket(questionsBucket), record.Id[:], questionSchema, record)
+	})
+	if err == nil {
+		repo.putLocal(record)
+	}
+	return err
+}
+
+// Close schließt die Datenbank nicht, sie wird von allen Repositories gemeinsam genutzt
+func (repo *boltRepository) Close() error {
+	return nil
+}
+
+// CopyToBolt übernimmt alle Fragen aus der Log-Datei in die Datenbank
+func CopyToBolt(path string, db *bbolt.DB) (count int, err error) {
+	source, err := CreateRepo(path)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+	if err = storage.CreateBuckets(db, string(questionsBucket)); err != nil {
+		return 0, err
+	}
+	stored := source.stored()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, record := range stored {
+			if err := storage.PutVersionedJson(tx.Bucket(questionsBucket), record.Id[:], questionSchema, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(stored), err
+}
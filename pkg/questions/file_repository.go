@@ -0,0 +1,178 @@
+package questions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/dictionaray"
+)
+
+type FileLogRepository struct {
+	*questionIndex
+	file              utils.RecordLog
+	codec             utils.Codec
+	path              string
+	compactor         *utils.Compactor
+	writtenOperations int
+}
+
+func CreateRepo(path string, sources ...Source) (repo *FileLogRepository, err error) {
+	repo = &FileLogRepository{
+		questionIndex: newQuestionIndex(),
+		path:          path,
+	}
+	if err := repo.selectCodec(); err != nil {
+		return repo, err
+	}
+	if err := utils.CreateFileIfNotExists(repo.filepath()); err != nil {
+		return repo, err
+	}
+	if err := repo.recover(); err != nil {
+		return repo, err
+	}
+	if err = repo.loadSources(sources); err != nil {
+		return nil, err
+	}
+	if err := repo.load(); err != nil {
+		return repo, err
+	}
+	if err := repo.open(); err != nil {
+		return repo, err
+	}
+	repo.compactor = utils.NewCompactor("questions", repo, utils.ConfiguredCompactionThresholds())
+	repo.logDivergedOverrides()
+	return repo, err
+}
+
+func (repo *FileLogRepository) Save(question *Question) (_ *Question, err error) {
+	if err = repo.checkWritable(question); err != nil {
+		return question, err
+	}
+	repo.mutex.Lock()
+	err = repo.append(repo.localRecord(question))
+	repo.mutex.Unlock()
+	if err != nil {
+		return question, err
+	}
+	question.emitEvents()
+	repo.compactor.Notify()
+	return question, err
+}
+
+// ResetToUpstream verwirft die lokale Änderung, danach gilt wieder die Frage aus der Quelle
+func (repo *FileLogRepository) ResetToUpstream(id uuid.UUID) (*Question, error) {
+	repo.mutex.Lock()
+	record, upstream, err := repo.resetRecord(id)
+	if err == nil {
+		err = repo.append(record)
+	}
+	repo.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	upstream.init(updatedEvent(upstream)).emitEvents()
+	repo.compactor.Notify()
+	return upstream, nil
+}
+
+func (repo *FileLogRepository) append(record questionRecord) error {
+	if err := utils.Append(repo.file, record, encodeRecord); err != nil {
+		return err
+	}
+	repo.putLocal(record)
+	repo.writtenOperations = repo.writtenOperations + 1
+	return nil
+}
+
+func (repo *FileLogRepository) open() (err error) {
+	if !utils.FileExist(repo.filepath()) {
+		return fmt.Errorf("could not open log segment. File %s not found", repo.filepath())
+	}
+	repo.file, err = repo.codec.Open(repo.filepath(), utils.ConfiguredSyncPolicy())
+	return err
+}
+
+// bestehende Dateien behalten ihr Format, neue Dateien verwenden STORAGE_CODEC
+func (repo *FileLogRepository) selectCodec() error {
+	configured, err := utils.ConfiguredCodec()
+	if err == nil {
+		repo.codec, err = utils.CodecFor(repo.filepath(), configured)
+	}
+	return err
+}
+
+// nur die eigene Log-Datei wird repariert, die Preload-Dateien werden nicht verändert
+func (repo *FileLogRepository) recover() error {
+	report, err := repo.codec.Recover(repo.filepath())
+	if err == nil && report.Modified() {
+		repo.logger.Warn("recovered %s: %d records kept, %d corrupt records quarantined, %d bytes of an incomplete record truncated",
+			repo.filepath(), report.Records, report.Quarantined, report.TruncatedBytes)
+	}
+	return err
+}
+
+func (repo *FileLogRepository) load() (err error) {
+	return repo.loadFile(repo.filepath(), func(record questionRecord) {
+		record.Question.init()
+		repo.putLocal(record)
+		repo.writtenOperations = repo.writtenOperations + 1
+	})
+}
+
+func (repo *FileLogRepository) filepath() string {
+	return repo.path
+}
+
+// liefert die Datensätze, die in der eigenen Log-Datei gespeichert sind
+func (repo *FileLogRepository) stored() []questionRecord {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return dictionaray.Values(repo.local)
+}
+
+func (repo *FileLogRepository) CompactionStats() (total int, live int) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return repo.writtenOperations, len(repo.local)
+}
+
+// Compact schreibt die eigene Log-Datei mit dem aktuellen Stand der lokalen Änderungen neu.
+// Fragen aus den Quellen und verworfene Änderungen werden nicht übernommen.
+func (repo *FileLogRepository) Compact() (err error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	err = utils.CompactLogFile(repo.filepath(), repo.codec, func(log utils.RecordLog) error {
+		for _, record := range repo.local {
+			if err := utils.Append(log, record, encodeRecord); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	repo.logger.Info("%d records written to %s", len(repo.local), repo.filepath())
+	repo.writtenOperations = len(repo.local)
+	if err = repo.file.Close(); err != nil {
+		return err
+	}
+	return repo.open()
+}
+
+// Close beendet den Compactor, schreibt die Log-Datei auf die Platte und schließt sie
+func (repo *FileLogRepository) Close() error {
+	repo.compactor.Stop()
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return errors.Join(repo.file.Sync(), repo.file.Close())
+}
+
+// Snapshot übernimmt die eigene Log-Datei, solange keine Änderungen geschrieben werden
+func (repo *FileLogRepository) Snapshot(write storage.SnapshotWriter) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return storage.SnapshotFile(repo.filepath(), storage.QuestionsFile, write)
+}
@@ -0,0 +1,160 @@
+package questions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/collections"
+)
+
+// LocalSource ist die Herkunft von Fragen, die nur lokal gespeichert sind
+const LocalSource = "local"
+
+var ErrNotOverridden = utils.Conflict("question has no local changes")
+
+// questionRecord ist ein Datensatz der lokalen Schicht. Upstream ist der Fingerabdruck der
+// Frage aus der Quelle zum Zeitpunkt der Änderung, Reset verwirft die lokale Änderung.
+type questionRecord struct {
+	*Question
+	Upstream string `json:",omitempty"`
+	Reset    bool   `json:",omitempty"`
+}
+
+type Provenance struct {
+	Source     string `json:"source"`
+	Overridden bool   `json:"overridden"`
+}
+
+// Zustand einer lokalen Änderung im Vergleich zur Quelle
+type UpstreamState string
+
+const (
+	UpstreamUnchanged UpstreamState = "unchanged"
+	UpstreamChanged   UpstreamState = "changed"
+	UpstreamUnknown   UpstreamState = "unknown"
+)
+
+// Override beschreibt eine lokale Änderung an einer Frage aus einer Quelle
+type Override struct {
+	Local       *Question
+	Upstream    *Question
+	Source      string
+	State       UpstreamState
+	Differences []string
+}
+
+// Diverged ist wahr, wenn sich die Quelle seit der lokalen Änderung geändert hat
+func (override Override) Diverged() bool {
+	return override.State != UpstreamUnchanged
+}
+
+func fingerprint(question *Question) string {
+	content, _ := json.Marshal(struct {
+		Question  string
+		Options   []Option
+		AnswerIds []uuid.UUID
+		Media     []string
+	}{question.Question, question.Options, question.AnswerIds, question.Media})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+func differences(local *Question, upstream *Question) (fields []string) {
+	if local.Question != upstream.Question {
+		fields = append(fields, "text")
+	}
+	if fmt.Sprint(local.Options) != fmt.Sprint(upstream.Options) {
+		fields = append(fields, "choices")
+	}
+	if !collections.MutualContainment(local.AnswerIds, upstream.AnswerIds) {
+		fields = append(fields, "answer")
+	}
+	if fmt.Sprint(local.Media) != fmt.Sprint(upstream.Media) {
+		fields = append(fields, "media")
+	}
+	return fields
+}
+
+// fügt eine Frage einer Quelle hinzu, eine lokale Änderung bleibt gültig
+func (index *questionIndex) putUpstream(source *Source, question *Question) {
+	index.upstream[question.Id] = question
+	index.sources[question.Id] = source
+	if _, overridden := index.local[question.Id]; !overridden {
+		index.values[question.Id] = question
+	}
+}
+
+func (index *questionIndex) putLocal(record questionRecord) {
+	if !record.Reset {
+		index.local[record.Id] = record
+		index.values[record.Id] = record.Question
+	} else if upstream, exists := index.upstream[record.Id]; exists {
+		delete(index.local, record.Id)
+		index.values[record.Id] = upstream
+	} else {
+		delete(index.local, record.Id)
+		delete(index.values, record.Id)
+	}
+}
+
+func (index *questionIndex) localRecord(question *Question) questionRecord {
+	record := questionRecord{Question: question}
+	if upstream, exists := index.upstream[question.Id]; exists {
+		record.Upstream = fingerprint(upstream)
+	}
+	return record
+}
+
+// liefert den Datensatz, mit dem die lokale Änderung verworfen wird, und die Frage der Quelle
+func (index *questionIndex) resetRecord(id uuid.UUID) (questionRecord, *Question, error) {
+	upstream, exists := index.upstream[id]
+	if _, overridden := index.local[id]; !exists || !overridden {
+		return questionRecord{}, nil, fmt.Errorf("%w: %s", ErrNotOverridden, id)
+	}
+	return questionRecord{Question: &Question{Id: id}, Reset: true}, upstream, nil
+}
+
+func (index *questionIndex) Provenance(question *Question) Provenance {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	_, overridden := index.local[question.Id]
+	if source, exists := index.sources[question.Id]; exists {
+		return Provenance{Source: source.Name, Overridden: overridden}
+	}
+	return Provenance{Source: LocalSource}
+}
+
+// Overrides liefert alle lokalen Änderungen an Fragen aus den Quellen
+func (index *questionIndex) Overrides() (overrides []Override) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	for id, record := range index.local {
+		upstream, exists := index.upstream[id]
+		if !exists {
+			continue
+		}
+		state := UpstreamUnknown
+		if record.Upstream == fingerprint(upstream) {
+			state = UpstreamUnchanged
+		} else if record.Upstream != "" {
+			state = UpstreamChanged
+		}
+		overrides = append(overrides, Override{
+			Local:       record.Question,
+			Upstream:    upstream,
+			Source:      index.sources[id].Name,
+			State:       state,
+			Differences: differences(record.Question, upstream),
+		})
+	}
+	return overrides
+}
+
+func (index *questionIndex) logDivergedOverrides() {
+	if diverged := collections.Count(index.Overrides(), Override.Diverged); diverged > 0 {
+		index.logger.Warn("%d local changes diverge from their upstream source", diverged)
+	}
+}
@@ -0,0 +1,61 @@
+package questions
+
+import (
+	"errors"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalChangesOverlayUpstream(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath, localPath := filepath.Join(dir, "upstream.data"), filepath.Join(dir, "question.data")
+	original := createTestQuestion()
+	writeSource(t, sourcePath, original)
+	source := Source{Name: "upstream", Path: sourcePath}
+
+	repo, err := CreateRepo(localPath, source)
+	utils.AssertNoError(t, err, "create repository failed")
+	question, _ := repo.FindFirst(IdEquals(original.Id))
+	changed, err := question.clone().Update("changed", question.Options, question.AnswerIds)
+	utils.AssertNoError(t, err, "update failed")
+	_, err = repo.Save(changed)
+	utils.AssertNoError(t, err, "save failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	repo, err = CreateRepo(localPath, source)
+	utils.AssertNoError(t, err, "reload failed")
+	question, _ = repo.FindFirst(IdEquals(original.Id))
+	utils.Assert(t, question.Question == "changed", "expected local change, got %s", question.Question)
+	provenance := repo.Provenance(question)
+	utils.Assert(t, provenance.Source == "upstream" && provenance.Overridden, "unexpected provenance %v", provenance)
+	overrides := repo.Overrides()
+	utils.Assert(t, len(overrides) == 1 && overrides[0].State == UpstreamUnchanged, "unexpected overrides %v", overrides)
+	utils.Assert(t, len(overrides[0].Differences) == 1 && overrides[0].Differences[0] == "text", "unexpected differences %v", overrides[0].Differences)
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	shipped := original.clone()
+	shipped.Question = "fixed upstream"
+	writeSource(t, sourcePath, shipped)
+	repo, err = CreateRepo(localPath, source)
+	utils.AssertNoError(t, err, "reload failed")
+	overrides = repo.Overrides()
+	utils.Assert(t, len(overrides) == 1 && overrides[0].State == UpstreamChanged, "expected diverged override, got %v", overrides)
+
+	reset, err := repo.ResetToUpstream(original.Id)
+	utils.AssertNoError(t, err, "reset failed")
+	utils.Assert(t, reset.Question == "fixed upstream", "expected upstream question, got %s", reset.Question)
+	_, err = repo.ResetToUpstream(original.Id)
+	utils.Assert(t, errors.Is(err, ErrNotOverridden), "expected ErrNotOverridden, got %v", err)
+	utils.AssertNoError(t, repo.Compact(), "compaction failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	repo, err = CreateRepo(localPath, source)
+	utils.AssertNoError(t, err, "reload failed")
+	defer repo.Close()
+	question, _ = repo.FindFirst(IdEquals(original.Id))
+	utils.Assert(t, question.Question == "fixed upstream", "reset lost after reload, got %s", question.Question)
+	utils.Assert(t, !repo.Provenance(question).Overridden, "question still overridden")
+	total, live := repo.CompactionStats()
+	utils.Assert(t, total == 0 && live == 0, "expected empty local log, got %d/%d", total, live)
+}
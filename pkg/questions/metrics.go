@@ -0,0 +1,27 @@
+package questions
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/metrics"
+	"github.com/ohrenpiraten/go-collections/predicates"
+)
+
+// RegisterMetrics meldet die Anzahl der Fragen, gesamt und je Tag. Fragen ohne Tag werden unter
+// "untagged" gezählt.
+func RegisterMetrics(repo Repository) {
+	metrics.NewGaugeFunc("ceh_questions", "Number of questions.", func() float64 {
+		return float64(repo.CountAll())
+	})
+	metrics.NewGaugeVecFunc("ceh_questions_by_tag", "Number of questions by tag.", "tag", func() map[string]float64 {
+		counts := make(map[string]float64)
+		questions, _ := repo.FindAll(predicates.True[*Question]())
+		for _, question := range questions {
+			if len(question.Tags) == 0 {
+				counts["untagged"]++
+			}
+			for _, tag := range question.Tags {
+				counts[tag]++
+			}
+		}
+		return counts
+	})
+}
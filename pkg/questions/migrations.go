@@ -0,0 +1,20 @@
+package questions
+
+import "github.com/mwildt/ceh-utils/pkg/utils"
+
+// Migrationen der Datensätze in den Fragen-Logs und Quellen. Neue Versionen werden hier ergänzt,
+// die Migration bekommt den Datensatz als JSON-Objekt der Vorgängerversion.
+var questionSchema = utils.NewMigrationRegistry("questions", 1,
+	utils.Migration{
+		From:        0,
+		Description: "stamp schema version",
+		Apply: func(record utils.JsonObject) (utils.JsonObject, error) {
+			return record, nil
+		},
+	},
+)
+
+// Schema liefert die Migrationen der Fragen-Datensätze
+func Schema() *utils.MigrationRegistry {
+	return questionSchema
+}
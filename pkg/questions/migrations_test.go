@@ -0,0 +1,33 @@
+package questions
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnversionedRecordsAreMigrated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "question.data")
+	utils.AssertNoError(t, utils.CopyFile("testdata/questions-v0.data", path), "copy fixture failed")
+
+	repo, err := CreateRepo(path)
+	utils.AssertNoError(t, err, "load fixture failed")
+	question, found := repo.FindFirst(ByQuestionText("Which port does SSH use by default?"))
+	utils.Assert(t, found, "fixture question not found")
+	utils.Assert(t, len(question.Options) == 2 && question.Options[0].Option == "22", "unexpected options %v", question.Options)
+	utils.Assert(t, len(question.AnswerIds) == 1 && question.AnswerIds[0] == question.Options[0].Id, "unexpected answer %v", question.AnswerIds)
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	reports, err := utils.MigrateLogFile(path, Schema(), false)
+	utils.AssertNoError(t, err, "migration failed")
+	utils.Assert(t, len(reports) == 1 && reports[0].From == 0, "unexpected reports %v", reports)
+	reports, err = utils.MigrateLogFile(path, Schema(), true)
+	utils.AssertNoError(t, err, "check failed")
+	utils.Assert(t, len(reports) == 0, "records left after migration: %v", reports)
+
+	repo, err = CreateRepo(path)
+	utils.AssertNoError(t, err, "reload failed")
+	defer repo.Close()
+	again, _ := repo.FindFirst(IdEquals(question.Id))
+	utils.Assert(t, again.Question == question.Question && utils.Contains(again.Tags, "fixture"), "question changed by migration")
+}
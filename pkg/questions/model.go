@@ -1,9 +1,9 @@
 package questions
 
 import (
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/mwildt/ceh-utils/pkg/events"
+	"github.com/mwildt/ceh-utils/pkg/utils"
 	"github.com/ohrenpiraten/go-collections/collections"
 	"github.com/ohrenpiraten/go-collections/predicates"
 )
@@ -39,28 +39,50 @@ func (q *Question) init(events ...event) *Question {
 	return q
 }
 
-func (q *Question) Update(text string, options []Option, answer []uuid.UUID) (updated *Question, err error) {
+// clone liefert eine Kopie, die geändert werden kann, ohne die Frage der Quelle zu verändern
+func (q *Question) clone() *Question {
+	clone := *q
+	clone.Options = append([]Option{}, q.Options...)
+	clone.AnswerIds = append([]uuid.UUID{}, q.AnswerIds...)
+	clone.Tags = append([]string{}, q.Tags...)
+	clone.Media = append([]string{}, q.Media...)
+	return clone.init()
+}
+
+// ErrDuplicateText meldet eine neue Frage, deren Text bereits vorhanden ist
+var ErrDuplicateText = utils.Conflict("a question with this text already exists")
+
+// ValidateQuestion prüft Text, Optionen und Antworten einer neuen oder geänderten Frage
+func ValidateQuestion(text string, options []Option, answer []uuid.UUID) error {
 	if len(text) == 0 {
-		return updated, fmt.Errorf("text must not be empty")
+		return utils.Invalid("text must not be empty")
 	}
 
 	if len(options) < 2 {
-		return updated, fmt.Errorf("options must be min 2")
+		return utils.Invalid("options must be min 2")
 	}
 
 	if collections.AnyMatch(options, func(o Option) bool {
 		return len(o.Option) == 0
 	}) {
-		return updated, fmt.Errorf("options must not be empty")
+		return utils.Invalid("options must not be empty")
+	}
+
+	optionAnswerIds := collections.Map(options, func(opt Option) uuid.UUID {
+		return opt.Id
+	})
+	if !collections.ContainsAll(optionAnswerIds, answer) {
+		return utils.Invalid("answers must all exist in options")
+	}
+	return nil
+}
+
+func (q *Question) Update(text string, options []Option, answer []uuid.UUID) (updated *Question, err error) {
+	if err = ValidateQuestion(text, options, answer); err != nil {
+		return updated, err
 	}
 
 	if len(answer) > 0 {
-		optionAnswerIds := collections.Map(options, func(opt Option) uuid.UUID {
-			return opt.Id
-		})
-		if !collections.ContainsAll(optionAnswerIds, answer) {
-			return updated, fmt.Errorf("answers must all exist in options")
-		}
 		q.AnswerIds = answer
 		q.events = append(q.events, updatedEvent(q))
 	}
@@ -7,83 +7,93 @@ import (
 	"github.com/ohrenpiraten/go-collections/dictionaray"
 	"github.com/ohrenpiraten/go-collections/predicates"
 	"math/rand"
-	"os"
 	"sync"
 	"time"
 )
 
-type FileLogRepository struct {
-	file   *os.File
-	path   string
-	values map[uuid.UUID]*Question
-	rand   *rand.Rand
-	logger utils.Logger
-	mutex  *sync.Mutex
+type Repository interface {
+	Save(question *Question) (*Question, error)
+	// FindRandom liefert utils.ErrNotFound, wenn keine Frage passt
+	FindRandom(predicate predicates.Predicate[*Question]) (*Question, error)
+	FindAll(predicate predicates.Predicate[*Question]) ([]*Question, error)
+	FindFirst(predicate predicates.Predicate[*Question]) (*Question, bool)
+	Contains(predicate predicates.Predicate[*Question]) bool
+	CountAll() int
+	SourceOf(question *Question) (Source, bool)
+	Provenance(question *Question) Provenance
+	ResetToUpstream(id uuid.UUID) (*Question, error)
+	Overrides() []Override
+	Close() error
 }
 
-func CreateRepo(path string, preloadFiles ...string) (repo *FileLogRepository, err error) {
-	repo = &FileLogRepository{
-		path:   path,
-		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
-		logger: utils.NewStdLogger("questions.repository"),
-		values: make(map[uuid.UUID]*Question),
-		mutex:  &sync.Mutex{},
+type QuestionPredicate func(q Question) bool
+
+func IdNotIn(uuids []uuid.UUID) predicates.Predicate[*Question] {
+	keyMap := make(map[uuid.UUID]uuid.UUID)
+	for _, id := range uuids {
+		keyMap[id] = id
 	}
-	if err := utils.CreateFileIfNotExists(repo.filepath()); err != nil {
-		return repo, err
+	return func(q *Question) bool {
+		_, exists := keyMap[q.Id]
+		return !exists
 	}
+}
 
-	for _, preloadFile := range preloadFiles {
-		if err = repo.loadFile(preloadFile); err != nil {
-			return nil, err
+// HasAnyTag trifft auf Fragen mit mindestens einem der Tags zu, ohne Tags auf alle Fragen
+func HasAnyTag(tags []string) predicates.Predicate[*Question] {
+	return func(q *Question) bool {
+		if len(tags) == 0 {
+			return true
 		}
+		for _, tag := range tags {
+			if utils.Contains(q.Tags, tag) {
+				return true
+			}
+		}
+		return false
 	}
-
-	if err := repo.load(); err != nil {
-		return repo, err
-	}
-	if err := repo.open(); err != nil {
-		return repo, err
-	}
-	return repo, err
 }
 
-func (repo *FileLogRepository) FindRandom(predicate predicates.Predicate[*Question]) (question *Question, err error) {
-	candidates := dictionaray.FilterValues(repo.values, predicate)
-	if len(candidates) == 0 {
-		return question, err
-	}
-	randomIndex := repo.rand.Intn(len(candidates))
-	return candidates[randomIndex], nil
+// questionIndex hält alle Fragen im Speicher. Die Fragen der Quellen (upstream) werden von
+// lokalen Änderungen (local) überlagert, values enthält den jeweils gültigen Stand. Die
+// Repositories unterscheiden sich nur darin, wie die lokalen Änderungen persistiert werden.
+type questionIndex struct {
+	values   map[uuid.UUID]*Question
+	upstream map[uuid.UUID]*Question
+	sources  map[uuid.UUID]*Source
+	local    map[uuid.UUID]questionRecord
+	rand     *rand.Rand
+	logger   utils.Logger
+	mutex    *sync.Mutex
 }
 
-func (repo *FileLogRepository) Save(question *Question) (_ *Question, err error) {
-	repo.mutex.Lock()
-	defer repo.mutex.Unlock()
-	err = utils.Append(repo.file, question, repo.encodeRecord)
-	if err != nil {
-		return question, err
+func newQuestionIndex() *questionIndex {
+	return &questionIndex{
+		values:   make(map[uuid.UUID]*Question),
+		upstream: make(map[uuid.UUID]*Question),
+		sources:  make(map[uuid.UUID]*Source),
+		local:    make(map[uuid.UUID]questionRecord),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		logger:   utils.NewStdLogger("questions.repository"),
+		mutex:    &sync.Mutex{},
 	}
-	repo.values[question.Id] = question
-	question.emitEvents()
-	return question, err
 }
 
-type QuestionPredicate func(q Question) bool
-
-func IdNotIn(uuids []uuid.UUID) predicates.Predicate[*Question] {
-	keyMap := make(map[uuid.UUID]uuid.UUID)
-	for _, id := range uuids {
-		keyMap[id] = id
-	}
-	return func(q *Question) bool {
-		_, exists := keyMap[q.Id]
-		return !exists
+func (index *questionIndex) FindRandom(predicate predicates.Predicate[*Question]) (question *Question, err error) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	candidates := dictionaray.FilterValues(index.values, predicate)
+	if len(candidates) == 0 {
+		return question, utils.ErrNotFound
 	}
+	randomIndex := index.rand.Intn(len(candidates))
+	return candidates[randomIndex], nil
 }
 
-func (repo *FileLogRepository) FindAll(predicate predicates.Predicate[*Question]) (list []*Question, err error) {
-	for _, question := range repo.values {
+func (index *questionIndex) FindAll(predicate predicates.Predicate[*Question]) (list []*Question, err error) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	for _, question := range index.values {
 		if predicate(question) {
 			list = append(list, question)
 		}
@@ -91,8 +101,10 @@ func (repo *FileLogRepository) FindAll(predicate predicates.Predicate[*Question]
 	return list, err
 }
 
-func (repo *FileLogRepository) FindFirst(predicate predicates.Predicate[*Question]) (question *Question, exists bool) {
-	for _, question := range repo.values {
+func (index *questionIndex) FindFirst(predicate predicates.Predicate[*Question]) (question *Question, exists bool) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	for _, question := range index.values {
 		if predicate(question) {
 			return question, true
 		}
@@ -100,51 +112,59 @@ func (repo *FileLogRepository) FindFirst(predicate predicates.Predicate[*Questio
 	return question, false
 }
 
-func (repo *FileLogRepository) Contains(predicate predicates.Predicate[*Question]) bool {
-	_, exists := repo.FindFirst(predicate)
+func (index *questionIndex) Contains(predicate predicates.Predicate[*Question]) bool {
+	_, exists := index.FindFirst(predicate)
 	return exists
 }
 
-func (repo *FileLogRepository) open() (err error) {
-	if !utils.FileExist(repo.filepath()) {
-		return fmt.Errorf("could not open log segment. File %s not found", repo.filepath())
+func (index *questionIndex) CountAll() int {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	return len(index.values)
+}
+
+// liefert die Quelle, aus der die Frage beim Start geladen wurde
+func (index *questionIndex) SourceOf(question *Question) (Source, bool) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	if source, exists := index.sources[question.Id]; exists {
+		return *source, true
 	}
-	repo.file, err = os.OpenFile(repo.filepath(), os.O_APPEND|os.O_WRONLY, 0644)
-	return err
+	return Source{}, false
 }
 
-func (repo *FileLogRepository) loadFile(path string) (err error) {
-	repo.logger.Info("start load items from file-system (%s)", path)
-	count, err := utils.LoadFromFile(path, func(buffer []byte) error {
-		value, err := repo.decodeRecord(buffer)
+// lädt die Datensätze aus einer Log-Datei im erkannten Format, consumer wird für jeden geladenen Datensatz aufgerufen
+func (index *questionIndex) loadFile(path string, consumer func(questionRecord)) (err error) {
+	index.logger.Info("start load items from file-system (%s)", path)
+	migrated := 0
+	count, err := utils.LoadRecords(path, func(buffer []byte) error {
+		record, from, err := decodeRecord(buffer)
 		if err != nil {
 			return err
 		}
-		repo.values[value.Id] = value
+		if from != questionSchema.Current() {
+			migrated++
+		}
+		consumer(record)
 		return nil
 	})
 	if err == nil {
-		repo.logger.Info("%d items loaded from %s, %d in store", count, path, len(repo.values))
+		index.logger.Info("%d items loaded from %s, %d in store", count, path, len(index.values))
+	}
+	if migrated > 0 {
+		index.logger.Info("%d records of %s migrated to schema version %d on load", migrated, path, questionSchema.Current())
 	}
 	return err
 }
 
-func (repo *FileLogRepository) load() (err error) {
-	return repo.loadFile(repo.filepath())
-}
-
-func (repo *FileLogRepository) filepath() string {
-	return repo.path
-}
-
-func (repo *FileLogRepository) decodeRecord(record []byte) (question *Question, err error) {
-	return utils.B64JsonDecoder[*Question](record)
-}
-
-func (repo *FileLogRepository) encodeRecord(value *Question) (encoded []byte, err error) {
-	return utils.B64JsonEncoder(value)
+func decodeRecord(buffer []byte) (record questionRecord, from int, err error) {
+	record, from, err = utils.VersionedJsonDecoder[questionRecord](questionSchema)(buffer)
+	if err == nil && record.Question == nil {
+		err = fmt.Errorf("question record without question")
+	}
+	return record, from, err
 }
 
-func (repo *FileLogRepository) CountAll() int {
-	return len(repo.values)
+func encodeRecord(record questionRecord) (encoded []byte, err error) {
+	return utils.VersionedJsonEncoder[questionRecord](questionSchema)(record)
 }
@@ -1,7 +1,6 @@
 package questions
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"github.com/google/uuid"
 	"github.com/mwildt/ceh-utils/pkg/utils"
@@ -12,42 +11,85 @@ import (
 	"net/http"
 )
 
+// MediaPath ist das Verzeichnis mit den Bildern der Fragen
+const MediaPath = "config/ceh-12-cehtest.org/media"
+
 type Controller struct {
-	repo *FileLogRepository
+	repo    Repository
+	actions map[string]http.HandlerFunc
 }
 
-func NewRestController(repo *FileLogRepository) *Controller {
-	return &Controller{
+func NewRestController(repo Repository) *Controller {
+	controller := &Controller{
 		repo: repo,
 	}
+	controller.actions = map[string]http.HandlerFunc{
+		"reset": utils.Secured(controller.ResetById),
+	}
+	return controller
+}
+
+// WithAction hängt einen weiteren Handler unter POST /api/questions/{questionId}/{name} ein.
+// Andere Pakete können die Route nicht selbst registrieren, weil sie bereits hier liegt.
+func (controller *Controller) WithAction(name string, handler http.HandlerFunc) *Controller {
+	controller.actions[name] = handler
+	return controller
 }
 
 func (controller *Controller) Routing(router routing.Routing) {
-	mediaPath := "config/ceh-12-cehtest.org/media"
-	router.Handle(routing.Get("/api/media/**"), http.StripPrefix("/api/media", http.FileServer(http.Dir(mediaPath))))
+	router.Handle(routing.Get("/api/media/**"), http.StripPrefix("/api/media", http.FileServer(http.Dir(MediaPath))))
 
 	router.HandleFunc(routing.Get("/api/questions/"), controller.GetAll)
+	router.HandleFunc(routing.Post("/api/questions/").Filter(utils.ApiSecured()), controller.Post)
+	router.HandleFunc(routing.Get("/api/questions/overrides").Filter(utils.ApiSecured()), controller.GetOverrides)
 	router.HandleFunc(routing.Get("/api/questions/{questionId}"), controller.GetById)
-	router.HandleFunc(routing.Patch("/api/questions/{questionId}").Filter(apiSecured()), controller.PatchById)
+	router.HandleFunc(routing.Patch("/api/questions/{questionId}").Filter(utils.ApiSecured()), controller.PatchById)
+	router.HandleFunc(routing.Post("/api/questions/{questionId}/{action}"), utils.SubResources("action", controller.actions))
 
 }
 
 func (controller *Controller) GetById(writer http.ResponseWriter, request *http.Request) {
 	if questionId, err := readUuid("questionId", request); err != nil {
-		httputils.BadRequest(writer, request)
+		utils.BadRequest(writer, request, "invalid question id")
 	} else if question, exists := controller.repo.FindFirst(IdEquals(questionId)); !exists {
-		httputils.NotFound(writer, request)
+		utils.NotFound(writer, request, "question not found")
 	} else {
-		httputils.OkJson(writer, request, mapToResponse(question))
+		httputils.OkJson(writer, request, controller.mapToResponse(question))
 	}
 }
 
 func (controller *Controller) GetAll(writer http.ResponseWriter, request *http.Request) {
 	questions, err := controller.repo.FindAll(predicates.True[*Question]())
 	if err != nil {
-		httputils.InternalServerError(writer, request)
+		utils.InternalServerError(writer, request, err)
+	} else {
+		httputils.OkJson(writer, request, collections.Map(questions, controller.mapToResponse))
+	}
+}
+
+// Post legt eine neue lokale Frage an. Eine Frage mit demselben Text wird nicht erneut angelegt
+// (409), damit Loader ihre Quellen wiederholt übertragen können.
+func (controller *Controller) Post(writer http.ResponseWriter, request *http.Request) {
+	type postRequestDTO struct {
+		Text    string           `json:"text"`
+		Choices []answerResponse `json:"choices"`
+		Answer  []uuid.UUID      `json:"answer"`
+		Media   []string         `json:"media"`
+		Tags    []string         `json:"tags"`
+	}
+
+	if requestDTO, err := readJsonPayload[postRequestDTO](request); err != nil {
+		utils.BadRequest(writer, request, "invalid request body")
+	} else if len(requestDTO.Answer) == 0 {
+		utils.SendError(writer, request, utils.Invalid("answer must not be empty"))
+	} else if err := ValidateQuestion(requestDTO.Text, collections.Map(requestDTO.Choices, mapToOption), requestDTO.Answer); err != nil {
+		utils.SendError(writer, request, err)
+	} else if controller.repo.Contains(ByQuestionText(requestDTO.Text)) {
+		utils.SendError(writer, request, ErrDuplicateText)
+	} else if question, err := controller.repo.Save(CreateQuestion(requestDTO.Text, collections.Map(requestDTO.Choices, mapToOption), requestDTO.Answer, requestDTO.Media, requestDTO.Tags)); err != nil {
+		utils.SendError(writer, request, err)
 	} else {
-		httputils.OkJson(writer, request, collections.Map(questions, mapToResponse))
+		httputils.CreatedJson(writer, request, controller.mapToResponse(question))
 	}
 }
 
@@ -59,26 +101,66 @@ func (controller *Controller) PatchById(writer http.ResponseWriter, request *htt
 	}
 
 	if questionId, err := readUuid("questionId", request); err != nil {
-		httputils.BadRequest(writer, request)
+		utils.BadRequest(writer, request, "invalid question id")
 	} else if requestDTO, err := readJsonPayload[patchByIdRequestDTO](request); err != nil {
-		httputils.BadRequest(writer, request)
+		utils.BadRequest(writer, request, "invalid request body")
 	} else if question, exists := controller.repo.FindFirst(IdEquals(questionId)); !exists {
-		httputils.NotFound(writer, request)
+		utils.NotFound(writer, request, "question not found")
 	} else {
-		updated, err := question.Update(requestDTO.Text, collections.Map(requestDTO.Choices, func(c answerResponse) Option {
-			return Option{Option: c.Text, Id: c.Id}
-		}), requestDTO.Answer)
+		updated, err := question.clone().Update(requestDTO.Text, collections.Map(requestDTO.Choices, mapToOption), requestDTO.Answer)
 
 		if err != nil {
-			httputils.InternalServerError(writer, request)
+			utils.SendError(writer, request, err)
 		} else if updated, err := controller.repo.Save(updated); err != nil {
-			httputils.InternalServerError(writer, request)
+			utils.SendError(writer, request, err)
 		} else {
-			httputils.OkJson(writer, request, mapToResponse(updated))
+			httputils.OkJson(writer, request, controller.mapToResponse(updated))
 		}
 	}
 }
 
+func (controller *Controller) ResetById(writer http.ResponseWriter, request *http.Request) {
+	if questionId, err := readUuid("questionId", request); err != nil {
+		utils.BadRequest(writer, request, "invalid question id")
+	} else if _, exists := controller.repo.FindFirst(IdEquals(questionId)); !exists {
+		utils.NotFound(writer, request, "question not found")
+	} else if question, err := controller.repo.ResetToUpstream(questionId); err != nil {
+		utils.SendError(writer, request, err)
+	} else {
+		httputils.OkJson(writer, request, controller.mapToResponse(question))
+	}
+}
+
+// GetOverrides liefert die lokalen Änderungen an Fragen aus den Quellen, mit ?diverged=true nur
+// die, deren Quelle sich seit der Änderung geändert hat oder bei denen das unbekannt ist
+func (controller *Controller) GetOverrides(writer http.ResponseWriter, request *http.Request) {
+	type overrideResponse struct {
+		Id          uuid.UUID     `json:"id"`
+		Source      string        `json:"source"`
+		Upstream    UpstreamState `json:"upstream"`
+		Diverged    bool          `json:"diverged"`
+		Differences []string      `json:"differences"`
+		Local       response      `json:"local"`
+		Original    response      `json:"original"`
+	}
+
+	overrides := controller.repo.Overrides()
+	if request.URL.Query().Get("diverged") == "true" {
+		overrides = collections.Filter(overrides, Override.Diverged)
+	}
+	httputils.OkJson(writer, request, collections.Map(overrides, func(override Override) overrideResponse {
+		return overrideResponse{
+			Id:          override.Local.Id,
+			Source:      override.Source,
+			Upstream:    override.State,
+			Diverged:    override.Diverged(),
+			Differences: append([]string{}, override.Differences...),
+			Local:       mapToResponse(override.Local, Provenance{Source: override.Source, Overridden: true}),
+			Original:    mapToResponse(override.Upstream, Provenance{Source: override.Source}),
+		}
+	}))
+}
+
 func readUuid(parameterName string, request *http.Request) (id uuid.UUID, err error) {
 	if strId, exists := routing.GetParameter(request.Context(), parameterName); !exists {
 		return id, err
@@ -101,35 +183,31 @@ type answerResponse struct {
 }
 
 type response struct {
-	Id      uuid.UUID        `json:"id"`
-	Text    string           `json:"text"`
-	Choices []answerResponse `json:"choices"`
-	Media   []string         `json:"media"`
+	Id         uuid.UUID        `json:"id"`
+	Text       string           `json:"text"`
+	Choices    []answerResponse `json:"choices"`
+	Media      []string         `json:"media"`
+	Source     string           `json:"source"`
+	Overridden bool             `json:"overridden"`
+}
+
+func mapToOption(choice answerResponse) Option {
+	return Option{Option: choice.Text, Id: choice.Id}
 }
 
-func mapToResponse(question *Question) response {
+func (controller *Controller) mapToResponse(question *Question) response {
+	return mapToResponse(question, controller.repo.Provenance(question))
+}
+
+func mapToResponse(question *Question, provenance Provenance) response {
 	return response{
 		Id:   question.Id,
 		Text: question.Question,
 		Choices: collections.Map(question.Options, func(opt Option) answerResponse {
 			return answerResponse{opt.Id, opt.Option}
 		}),
-		Media: question.Media,
-	}
-}
-
-func apiSecured() routing.Filter {
-
-	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-		apiToken := r.Header.Get("x-api-key")
-		apiKey := utils.GetEnvOrDefault("API_KEY", "")
-		if apiKey == "" {
-			utils.NewStdLogger("apiSecurity").Warn("Unable to find apiKey, operation denied")
-		}
-		if apiKey == "" || apiToken != base64.StdEncoding.EncodeToString([]byte(apiKey)) {
-			httputils.Unauthorized(w, r)
-		} else {
-			next(w, r)
-		}
+		Media:      question.Media,
+		Source:     provenance.Source,
+		Overridden: provenance.Overridden,
 	}
 }
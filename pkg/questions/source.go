@@ -0,0 +1,46 @@
+package questions
+
+import (
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+)
+
+var ErrReadOnly = utils.Forbidden("question belongs to a read-only source")
+
+// Source beschreibt eine Datei, aus der beim Start Fragen geladen werden. Fragen einer
+// schreibgeschützten Quelle können nicht geändert werden, die Tags werden jeder Frage der
+// Quelle zusätzlich zugewiesen.
+type Source struct {
+	Name     string   `yaml:"name"`
+	Path     string   `yaml:"path"`
+	ReadOnly bool     `yaml:"readOnly"`
+	Tags     []string `yaml:"tags"`
+}
+
+func (source Source) apply(question *Question) *Question {
+	for _, tag := range source.Tags {
+		if !utils.Contains(question.Tags, tag) {
+			question.Tags = append(question.Tags, tag)
+		}
+	}
+	return question
+}
+
+func (index *questionIndex) loadSources(sources []Source) error {
+	for _, source := range sources {
+		source := source
+		if err := index.loadFile(source.Path, func(record questionRecord) {
+			index.putUpstream(&source, source.apply(record.Question.init()))
+		}); err != nil {
+			return fmt.Errorf("load source %s: %w", source.Name, err)
+		}
+	}
+	return nil
+}
+
+func (index *questionIndex) checkWritable(question *Question) error {
+	if source, exists := index.SourceOf(question); exists && source.ReadOnly {
+		return fmt.Errorf("%w (%s)", ErrReadOnly, source.Name)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package questions
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+func writeSource(t *testing.T, path string, questions ...*Question) {
+	log, err := utils.JsonLinesCodec.Create(path, utils.SyncNever)
+	utils.AssertNoError(t, err, "create source failed")
+	defer log.Close()
+	for _, question := range questions {
+		utils.AssertNoError(t, utils.Append(log, questionRecord{Question: question}, encodeRecord), "write source failed")
+	}
+}
+
+func createTestQuestion(tags ...string) *Question {
+	options := []Option{{Id: uuid.New(), Option: "a"}, {Id: uuid.New(), Option: "b"}}
+	return CreateQuestion("question", options, []uuid.UUID{options[0].Id}, nil, tags)
+}
+
+func TestSourcesApplyTagsAndReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	upstream, custom := createTestQuestion("ceh"), createTestQuestion()
+	writeSource(t, filepath.Join(dir, "upstream.data"), upstream)
+	writeSource(t, filepath.Join(dir, "custom.data"), custom)
+
+	repo, err := CreateRepo(filepath.Join(dir, "question.data"),
+		Source{Name: "upstream", Path: filepath.Join(dir, "upstream.data"), ReadOnly: true, Tags: []string{"ceh", "upstream"}},
+		Source{Name: "custom", Path: filepath.Join(dir, "custom.data"), Tags: []string{"custom"}})
+	utils.AssertNoError(t, err, "create repository failed")
+	defer repo.Close()
+
+	loaded, _ := repo.FindFirst(IdEquals(upstream.Id))
+	utils.Assert(t, len(loaded.Tags) == 2, "expected tags ceh and upstream, got %v", loaded.Tags)
+	source, found := repo.SourceOf(loaded)
+	utils.Assert(t, found && source.Name == "upstream", "expected source upstream, got %v", source)
+
+	_, err = repo.Save(loaded)
+	utils.Assert(t, errors.Is(err, ErrReadOnly), "expected ErrReadOnly, got %v", err)
+
+	loaded, _ = repo.FindFirst(IdEquals(custom.Id))
+	utils.Assert(t, utils.Contains(loaded.Tags, "custom"), "expected tag custom, got %v", loaded.Tags)
+	_, err = repo.Save(loaded)
+	utils.AssertNoError(t, err, "save of writable question failed")
+}
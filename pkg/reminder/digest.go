@@ -0,0 +1,69 @@
+package reminder
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"sort"
+	"time"
+)
+
+// Overdue sind die überfälligen Challenges eines Trainings, Since ist die älteste Fälligkeit
+type Overdue struct {
+	TrainingId uuid.UUID `json:"trainingId"`
+	Count      int       `json:"count"`
+	Since      time.Time `json:"since"`
+}
+
+// Digest ist die tägliche Erinnerung an einen Benutzer
+type Digest struct {
+	User      string    `json:"user"`
+	Date      string    `json:"date"`
+	Total     int       `json:"total"`
+	Trainings []Overdue `json:"trainings"`
+}
+
+// Collect liefert je Benutzer mit überfälligen Challenges einen Digest. Trainings ohne Besitzer
+// sowie abgeschlossene, pausierte und archivierte Trainings werden nicht berücksichtigt.
+func Collect(ctx context.Context, repo training.Repository, now time.Time) ([]Digest, error) {
+	trainings, err := repo.FindAllBy(ctx, predicates.True[*training.Training]())
+	if err != nil {
+		return nil, err
+	}
+	digests := make(map[string]*Digest)
+	for _, t := range trainings {
+		if t.Owner == "" || t.IsPaused() {
+			continue
+		}
+		overdue := Overdue{TrainingId: t.Id}
+		for _, challenge := range t.DueQueue() {
+			if challenge.Timestamp.After(now) {
+				break
+			} else if overdue.Count == 0 {
+				overdue.Since = challenge.Timestamp
+			}
+			overdue.Count++
+		}
+		if overdue.Count == 0 {
+			continue
+		}
+		if digests[t.Owner] == nil {
+			digests[t.Owner] = &Digest{User: t.Owner, Date: now.Format(time.DateOnly)}
+		}
+		digests[t.Owner].Total += overdue.Count
+		digests[t.Owner].Trainings = append(digests[t.Owner].Trainings, overdue)
+	}
+
+	result := make([]Digest, 0, len(digests))
+	for _, digest := range digests {
+		sort.Slice(digest.Trainings, func(i, j int) bool {
+			return digest.Trainings[i].Since.Before(digest.Trainings[j].Since)
+		})
+		result = append(result, *digest)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].User < result[j].User
+	})
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package reminder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier stellt einen Digest zu
+type Notifier interface {
+	Notify(digest Digest) error
+}
+
+type mailboxNotifier struct {
+	path  string
+	mutex *sync.Mutex
+}
+
+// MailboxNotifier hängt jeden Digest als Nachricht im mbox-Format an die Datei path an, die
+// sich mit jedem Mail-Programm lesen lässt
+func MailboxNotifier(path string) Notifier {
+	return mailboxNotifier{path: path, mutex: &sync.Mutex{}}
+}
+
+func (notifier mailboxNotifier) Notify(digest Digest) error {
+	now := time.Now()
+	var message strings.Builder
+	fmt.Fprintf(&message, "From ceh-trainer %s\n", now.Format(time.ANSIC))
+	fmt.Fprintf(&message, "From: ceh-trainer\nTo: %s\nDate: %s\n", digest.User, now.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Subject: %d overdue reviews\nContent-Type: text/plain; charset=utf-8\n\n", digest.Total)
+	for _, overdue := range digest.Trainings {
+		fmt.Fprintf(&message, "training %s: %d overdue since %s\n", overdue.TrainingId, overdue.Count, overdue.Since.Format(time.RFC3339))
+	}
+	message.WriteString("\n")
+
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	file, err := os.OpenFile(notifier.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.WriteString(message.String()); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// WebhookNotifier sendet jeden Digest als JSON per POST an url
+func WebhookNotifier(url string, client *http.Client) Notifier {
+	return webhookNotifier{url: url, client: client}
+}
+
+func (notifier webhookNotifier) Notify(digest Digest) error {
+	body, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+	response, err := notifier.client.Post(notifier.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with %s", notifier.url, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config beschreibt die Erinnerungen: REMINDER_TARGET ist eine Mailbox-Datei oder eine
+// http(s)-URL (leer = keine Erinnerungen), REMINDER_INTERVAL der Abstand der Prüfung
+type Config struct {
+	Target   string
+	Interval time.Duration
+	// State ist die Datei, in der je Benutzer der Tag des letzten Digests steht (leer = nur im Speicher)
+	State string
+}
+
+func ConfiguredReminders() (config Config, err error) {
+	config.Target = utils.GetEnvOrDefault("REMINDER_TARGET", "")
+	if config.Interval, err = time.ParseDuration(utils.GetEnvOrDefault("REMINDER_INTERVAL", "1h")); err != nil {
+		return config, fmt.Errorf("invalid REMINDER_INTERVAL: %w", err)
+	} else if config.Interval <= 0 {
+		return config, fmt.Errorf("invalid REMINDER_INTERVAL: must be positive")
+	}
+	return config, nil
+}
+
+func (config Config) Enabled() bool {
+	return config.Target != ""
+}
+
+func (config Config) Notifier() Notifier {
+	if strings.HasPrefix(config.Target, "http://") || strings.HasPrefix(config.Target, "https://") {
+		return WebhookNotifier(config.Target, &http.Client{Timeout: 10 * time.Second})
+	}
+	return MailboxNotifier(config.Target)
+}
+
+// Scheduler prüft regelmäßig auf überfällige Challenges und erinnert jeden Benutzer höchstens
+// einmal am Tag
+type Scheduler struct {
+	repo     training.Repository
+	notifier Notifier
+	interval time.Duration
+	sent     map[string]string
+	state    string
+	logger   utils.Logger
+	mutex    *sync.Mutex
+	done     chan struct{}
+	stopped  chan struct{}
+	once     *sync.Once
+}
+
+func NewScheduler(config Config, repo training.Repository) *Scheduler {
+	scheduler := &Scheduler{
+		repo:     repo,
+		notifier: config.Notifier(),
+		interval: config.Interval,
+		state:    config.State,
+		logger:   utils.NewStdLogger("reminder.scheduler"),
+		mutex:    &sync.Mutex{},
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		once:     &sync.Once{},
+	}
+	var err error
+	if scheduler.sent, err = loadSent(config.State); err != nil {
+		scheduler.logger.Warn("unable to read %s, digests of today may be sent again: %s", config.State, err.Error())
+	}
+	go scheduler.run()
+	return scheduler
+}
+
+func (scheduler *Scheduler) run() {
+	defer close(scheduler.stopped)
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-scheduler.done:
+			return
+		case <-ticker.C:
+			if _, err := scheduler.Remind(time.Now()); err != nil {
+				scheduler.logger.Error("reminder failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Remind stellt die Digests aller Benutzer zu, die an diesem Tag noch keinen erhalten haben.
+// Schlägt die Zustellung fehl, wird es bei der nächsten Prüfung erneut versucht.
+func (scheduler *Scheduler) Remind(now time.Time) (sent int, err error) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	digests, err := Collect(context.Background(), scheduler.repo, now)
+	if err != nil {
+		return sent, err
+	}
+	for _, digest := range digests {
+		if scheduler.sent[digest.User] == digest.Date {
+			continue
+		} else if notifyErr := scheduler.notifier.Notify(digest); notifyErr != nil {
+			err = errors.Join(err, fmt.Errorf("reminder for %s: %w", digest.User, notifyErr))
+			continue
+		}
+		scheduler.sent[digest.User] = digest.Date
+		scheduler.logger.Info("reminded %s of %d overdue reviews", digest.User, digest.Total)
+		sent++
+	}
+	if sent > 0 {
+		err = errors.Join(err, scheduler.saveSent())
+	}
+	return sent, err
+}
+
+func loadSent(path string) (map[string]string, error) {
+	sent := make(map[string]string)
+	if path == "" {
+		return sent, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sent, nil
+	} else if err != nil {
+		return sent, err
+	} else if err = json.Unmarshal(data, &sent); err != nil {
+		return make(map[string]string), err
+	}
+	return sent, nil
+}
+
+// saveSent ersetzt die Datei atomar, damit nach einem Neustart kein Digest doppelt zugestellt wird
+func (scheduler *Scheduler) saveSent() error {
+	if scheduler.state == "" {
+		return nil
+	}
+	data, err := json.Marshal(scheduler.sent)
+	if err != nil {
+		return err
+	}
+	temp := scheduler.state + ".tmp"
+	if err = os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	} else if err = os.Rename(temp, scheduler.state); err != nil {
+		return err
+	}
+	return utils.SyncDir(filepath.Dir(scheduler.state))
+}
+
+// Snapshot schreibt den Tag des letzten Digests je Benutzer als storage.RemindersFile ins Backup
+func (scheduler *Scheduler) Snapshot(write storage.SnapshotWriter) error {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	data, err := json.Marshal(scheduler.sent)
+	if err != nil {
+		return err
+	}
+	return write(storage.RemindersFile, int64(len(data)), bytes.NewReader(data))
+}
+
+// Stop beendet die Goroutine und wartet auf eine ggf. laufende Prüfung
+func (scheduler *Scheduler) Stop() {
+	scheduler.once.Do(func() {
+		close(scheduler.done)
+		<-scheduler.stopped
+	})
+}
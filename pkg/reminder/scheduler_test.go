@@ -0,0 +1,112 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createTrainingRepo(t *testing.T, owners ...string) training.Repository {
+	repo, err := training.CreateFileRepository(filepath.Join(t.TempDir(), "trainings.data"))
+	utils.AssertNoError(t, err, "create training repository failed")
+	t.Cleanup(func() { _ = repo.Close() })
+	for _, owner := range owners {
+		challenge := training.Challenge{Id: uuid.New(), Answer: []uuid.UUID{uuid.New()}}
+		provider := func(training.ChallengeQuery) (training.Challenge, error) { return challenge, nil }
+		created, err := training.CreateTrainingWithSettings(provider, training.Settings{OnExhausted: training.CompleteOnExhausted, Owner: owner})
+		utils.AssertNoError(t, err, "create training failed")
+		_, err = repo.Save(context.Background(), created)
+		utils.AssertNoError(t, err, "save training failed")
+	}
+	return repo
+}
+
+func TestCollectGroupsOverdueByOwner(t *testing.T) {
+	repo := createTrainingRepo(t, "alice", "alice", "", "bob")
+	digests, err := Collect(context.Background(), repo, time.Now().Add(time.Minute))
+	utils.AssertNoError(t, err, "collect failed")
+	utils.Assert(t, len(digests) == 2 && digests[0].User == "alice" && digests[1].User == "bob", "expected digests for alice and bob, got %+v", digests)
+	utils.Assert(t, digests[0].Total == 2 && len(digests[0].Trainings) == 2, "expected 2 overdue trainings for alice, got %+v", digests[0])
+
+	digests, err = Collect(context.Background(), repo, time.Now().Add(-time.Minute))
+	utils.AssertNoError(t, err, "collect failed")
+	utils.Assert(t, len(digests) == 0, "expected nothing overdue in the past, got %+v", digests)
+}
+
+func TestRemindOncePerDay(t *testing.T) {
+	mailbox := filepath.Join(t.TempDir(), "mailbox")
+	reminders := NewScheduler(Config{Target: mailbox, Interval: time.Hour}, createTrainingRepo(t, "alice"))
+	defer reminders.Stop()
+
+	now := time.Now().Add(time.Minute)
+	sent, err := reminders.Remind(now)
+	utils.AssertNoError(t, err, "remind failed")
+	utils.Assert(t, sent == 1, "expected 1 digest, got %d", sent)
+	sent, err = reminders.Remind(now.Add(time.Minute))
+	utils.AssertNoError(t, err, "second remind failed")
+	utils.Assert(t, sent == 0, "expected no second digest on the same day, got %d", sent)
+	sent, err = reminders.Remind(now.AddDate(0, 0, 1))
+	utils.AssertNoError(t, err, "remind next day failed")
+	utils.Assert(t, sent == 1, "expected a digest on the next day, got %d", sent)
+
+	content, err := os.ReadFile(mailbox)
+	utils.AssertNoError(t, err, "read mailbox failed")
+	utils.Assert(t, strings.Count(string(content), "To: alice\n") == 2, "expected 2 messages to alice, got:\n%s", content)
+	utils.Assert(t, strings.Contains(string(content), "Subject: 1 overdue reviews"), "missing subject:\n%s", content)
+}
+
+func TestRemindOncePerDayAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{Target: filepath.Join(dir, "mailbox"), Interval: time.Hour, State: filepath.Join(dir, "reminders.json")}
+	repo := createTrainingRepo(t, "alice")
+	now := time.Now().Add(time.Minute)
+
+	reminders := NewScheduler(config, repo)
+	sent, err := reminders.Remind(now)
+	utils.AssertNoError(t, err, "remind failed")
+	utils.Assert(t, sent == 1, "expected 1 digest, got %d", sent)
+	reminders.Stop()
+
+	reminders = NewScheduler(config, repo)
+	defer reminders.Stop()
+	sent, err = reminders.Remind(now.Add(time.Minute))
+	utils.AssertNoError(t, err, "remind after restart failed")
+	utils.Assert(t, sent == 0, "expected no second digest after a restart, got %d", sent)
+	sent, err = reminders.Remind(now.AddDate(0, 0, 1))
+	utils.AssertNoError(t, err, "remind next day failed")
+	utils.Assert(t, sent == 1, "expected a digest on the next day, got %d", sent)
+}
+
+func TestWebhookRetriesAfterFailure(t *testing.T) {
+	var received []Digest
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var digest Digest
+		utils.AssertNoError(t, json.NewDecoder(r.Body).Decode(&digest), "decode digest failed")
+		received = append(received, digest)
+	}))
+	defer server.Close()
+	reminders := NewScheduler(Config{Target: server.URL, Interval: time.Hour}, createTrainingRepo(t, "alice"))
+	defer reminders.Stop()
+
+	now := time.Now().Add(time.Minute)
+	_, err := reminders.Remind(now)
+	utils.Assert(t, err != nil, "expected error from failing webhook")
+	failing = false
+	sent, err := reminders.Remind(now)
+	utils.AssertNoError(t, err, "remind failed")
+	utils.Assert(t, sent == 1 && len(received) == 1 && received[0].User == "alice", "expected retried digest for alice, got %+v", received)
+}
@@ -0,0 +1,14 @@
+package reports
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"go.etcd.io/bbolt"
+)
+
+func CreateBoltRepository(db *bbolt.DB) (Repository, error) {
+	repo, err := storage.CreateKeyedBoltRepository(db, reportStore)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
@@ -0,0 +1,89 @@
+package reports
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"strings"
+	"time"
+)
+
+type Category string
+
+const (
+	WrongAnswer Category = "wrong-answer"
+	Typo        Category = "typo"
+	Outdated    Category = "outdated"
+	Unclear     Category = "unclear"
+)
+
+func ParseCategory(value string) (Category, error) {
+	switch category := Category(value); category {
+	case WrongAnswer, Typo, Outdated, Unclear:
+		return category, nil
+	default:
+		return category, utils.Invalid("unknown category %q, expected wrong-answer, typo, outdated or unclear", value)
+	}
+}
+
+type Status string
+
+const (
+	Open     Status = "open"
+	Resolved Status = "resolved"
+)
+
+func ParseStatus(value string) (Status, error) {
+	switch status := Status(value); status {
+	case Open, Resolved:
+		return status, nil
+	default:
+		return status, utils.Invalid("unknown status %q, expected open or resolved", value)
+	}
+}
+
+const maxTextLength = 2000
+
+var ErrResolved = utils.Conflict("report is already resolved")
+
+// Report ist die Meldung eines Lernenden zu einer Frage. TrainingId ist uuid.Nil, wenn die
+// Meldung nicht aus einem Training stammt.
+type Report struct {
+	Id         uuid.UUID
+	QuestionId uuid.UUID
+	TrainingId uuid.UUID
+	Category   Category
+	Text       string
+	Reporter   string
+	Status     Status
+	Created    time.Time
+	Resolved   time.Time
+	Resolution string
+}
+
+func CreateReport(questionId uuid.UUID, trainingId uuid.UUID, category Category, text string, reporter string) (Report, error) {
+	text = strings.TrimSpace(text)
+	if len(text) > maxTextLength {
+		return Report{}, utils.Invalid("text must be max %d characters", maxTextLength)
+	}
+	return Report{
+		Id:         uuid.New(),
+		QuestionId: questionId,
+		TrainingId: trainingId,
+		Category:   category,
+		Text:       text,
+		Reporter:   reporter,
+		Status:     Open,
+		Created:    time.Now(),
+	}, nil
+}
+
+// Resolve schließt die Meldung, resolution beschreibt ggf. die Änderung an der Frage
+func (report *Report) Resolve(resolution string, now time.Time) error {
+	if report.Status != Open {
+		return ErrResolved
+	}
+	report.Status = Resolved
+	report.Resolution = strings.TrimSpace(resolution)
+	report.Resolved = now
+	return nil
+}
@@ -0,0 +1,23 @@
+package reports
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateReportRejectsLongText(t *testing.T) {
+	_, err := CreateReport(uuid.New(), uuid.Nil, Unclear, strings.Repeat("x", maxTextLength+1), "")
+	utils.Assert(t, err != nil, "expected error for text longer than %d characters", maxTextLength)
+}
+
+func TestResolveTwiceFails(t *testing.T) {
+	report, err := CreateReport(uuid.New(), uuid.Nil, Typo, " typo in option b ", "alice")
+	utils.AssertNoError(t, err, "create report failed")
+	utils.Assert(t, report.Text == "typo in option b", "expected trimmed text, got %q", report.Text)
+	utils.AssertNoError(t, report.Resolve("fixed", time.Now()), "resolve failed")
+	utils.Assert(t, report.Resolve("again", time.Now()) == ErrResolved, "expected resolved report to stay resolved")
+	utils.Assert(t, report.Resolution == "fixed", "resolution overwritten: %q", report.Resolution)
+}
@@ -0,0 +1,29 @@
+package reports
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/events"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+)
+
+const reportedEventType = "question.reported"
+
+type ReportedEvent struct {
+	ReportId   uuid.UUID `json:"reportId"`
+	QuestionId uuid.UUID `json:"questionId"`
+	TrainingId uuid.UUID `json:"trainingId"`
+	Category   Category  `json:"category"`
+	Text       string    `json:"text"`
+}
+
+func reportedEvent(report Report) ReportedEvent {
+	return ReportedEvent{report.Id, report.QuestionId, report.TrainingId, report.Category, report.Text}
+}
+
+// Notify meldet jede neue Meldung im Log, damit die Redaktion darauf aufmerksam wird
+func Notify(logger utils.Logger) error {
+	return events.Subscribe(reportedEventType, func(event ReportedEvent) error {
+		logger.Info("question %s reported as %s: %s", event.QuestionId, event.Category, event.Text)
+		return nil
+	})
+}
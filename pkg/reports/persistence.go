@@ -0,0 +1,27 @@
+package reports
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type reportRecord struct {
+	Id         uuid.UUID `json:"id"`
+	QuestionId uuid.UUID `json:"questionId"`
+	TrainingId uuid.UUID `json:"trainingId"`
+	Category   Category  `json:"category"`
+	Text       string    `json:"text"`
+	Reporter   string    `json:"reporter,omitempty"`
+	Status     Status    `json:"status"`
+	Created    time.Time `json:"created"`
+	Resolved   time.Time `json:"resolved,omitempty"`
+	Resolution string    `json:"resolution,omitempty"`
+}
+
+func toReportRecord(report Report) reportRecord {
+	return reportRecord(report)
+}
+
+func (record reportRecord) toDomain() Report {
+	return Report(record)
+}
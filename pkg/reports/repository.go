@@ -0,0 +1,46 @@
+package reports
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/ohrenpiraten/go-collections/predicates"
+)
+
+type Repository interface {
+	Save(report Report) (Report, error)
+	FindById(id uuid.UUID) (Report, bool)
+	// FindAll liefert die passenden Meldungen, die älteste zuerst
+	FindAll(predicate predicates.Predicate[Report]) []Report
+	Close() error
+}
+
+func HasStatus(status Status) predicates.Predicate[Report] {
+	return func(report Report) bool {
+		return report.Status == status
+	}
+}
+
+func ForQuestion(questionId uuid.UUID) predicates.Predicate[Report] {
+	return func(report Report) bool {
+		return report.QuestionId == questionId
+	}
+}
+
+// reportStore legt fest, wie die Meldungen gespeichert werden, es gilt der letzte Datensatz einer Meldung
+var reportStore = storage.Keyed[Report, reportRecord]{
+	Name:     "reports",
+	File:     storage.ReportsFile,
+	Schema:   reportSchema,
+	Key:      func(value Report) uuid.UUID { return value.Id },
+	ToRecord: toReportRecord,
+	ToDomain: reportRecord.toDomain,
+	Less:     func(a Report, b Report) bool { return a.Created.Before(b.Created) },
+}
+
+func CreateFileRepository(path string) (Repository, error) {
+	repo, err := storage.CreateKeyedFileRepository(path, reportStore)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
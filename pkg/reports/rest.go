@@ -0,0 +1,166 @@
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+	"github.com/ohrenpiraten/go-collections/collections"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"io"
+	"net/http"
+	"time"
+)
+
+type Controller struct {
+	repo         Repository
+	questionRepo questions.Repository
+	trainingRepo training.Repository
+}
+
+func NewRestController(repo Repository, questionRepo questions.Repository, trainingRepo training.Repository) *Controller {
+	return &Controller{
+		repo:         repo,
+		questionRepo: questionRepo,
+		trainingRepo: trainingRepo,
+	}
+}
+
+// Routing enthält nur die Moderation. POST /api/questions/{questionId}/reports teilt sich die
+// Route mit den Aktionen der Fragen und wird dort mit PostForQuestion eingehängt.
+func (controller *Controller) Routing(router routing.Routing) {
+	router.HandleFunc(routing.Get("/api/reports/").Filter(utils.ApiSecured()), controller.GetAll)
+	router.HandleFunc(routing.Post("/api/reports/{reportId}/{action}").Filter(utils.ApiSecured()), utils.SubResources("action", map[string]http.HandlerFunc{
+		"resolve": controller.Resolve,
+	}))
+}
+
+type reportDTO struct {
+	Id         uuid.UUID  `json:"id"`
+	QuestionId uuid.UUID  `json:"questionId"`
+	Question   string     `json:"question"`
+	TrainingId *uuid.UUID `json:"trainingId,omitempty"`
+	Category   Category   `json:"category"`
+	Text       string     `json:"text"`
+	Reporter   string     `json:"reporter,omitempty"`
+	Status     Status     `json:"status"`
+	Created    string     `json:"created"`
+	Resolved   string     `json:"resolved,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+}
+
+func (controller *Controller) mapReportDTO(report Report) reportDTO {
+	dto := reportDTO{
+		Id:         report.Id,
+		QuestionId: report.QuestionId,
+		Category:   report.Category,
+		Text:       report.Text,
+		Reporter:   report.Reporter,
+		Status:     report.Status,
+		Created:    report.Created.Format(time.RFC3339),
+		Resolution: report.Resolution,
+	}
+	if report.TrainingId != uuid.Nil {
+		dto.TrainingId = &report.TrainingId
+	}
+	if !report.Resolved.IsZero() {
+		dto.Resolved = report.Resolved.Format(time.RFC3339)
+	}
+	if question, exists := controller.questionRepo.FindFirst(questions.IdEquals(report.QuestionId)); exists {
+		dto.Question = question.Question
+	}
+	return dto
+}
+
+// PostForQuestion nimmt die Meldung eines Lernenden entgegen. Ist trainingId angegeben, muss die
+// Frage in diesem Training vorgekommen sein. Der Header x-user wird als Absender gespeichert.
+func (controller *Controller) PostForQuestion(w http.ResponseWriter, r *http.Request) {
+	var requestDTO struct {
+		Category   string     `json:"category"`
+		Text       string     `json:"text"`
+		TrainingId *uuid.UUID `json:"trainingId"`
+	}
+	trainingId := uuid.Nil
+
+	if idString, exists := routing.GetParameter(r.Context(), "questionId"); !exists {
+		utils.BadRequest(w, r, "missing question id")
+	} else if questionId, err := uuid.Parse(idString); err != nil {
+		utils.BadRequest(w, r, "invalid question id")
+	} else if err := json.NewDecoder(r.Body).Decode(&requestDTO); err != nil {
+		utils.BadRequest(w, r, "invalid request body")
+	} else if _, exists := controller.questionRepo.FindFirst(questions.IdEquals(questionId)); !exists {
+		utils.NotFound(w, r, "question not found")
+	} else if category, err := ParseCategory(requestDTO.Category); err != nil {
+		utils.SendError(w, r, err)
+	} else if requestDTO.TrainingId != nil && !controller.askedInTraining(r, *requestDTO.TrainingId, questionId) {
+		utils.SendError(w, r, utils.Invalid("question was not asked in training %s", *requestDTO.TrainingId))
+	} else {
+		if requestDTO.TrainingId != nil {
+			trainingId = *requestDTO.TrainingId
+		}
+		if report, err := CreateReport(questionId, trainingId, category, requestDTO.Text, r.Header.Get("x-user")); err != nil {
+			utils.SendError(w, r, err)
+		} else if report, err = Submit(controller.repo, report); err != nil {
+			utils.SendError(w, r, err)
+		} else {
+			httputils.CreatedJson(w, r, controller.mapReportDTO(report))
+		}
+	}
+}
+
+func (controller *Controller) askedInTraining(r *http.Request, trainingId uuid.UUID, questionId uuid.UUID) bool {
+	found, exists := controller.trainingRepo.FindFirst(r.Context(), training.IdEquals(trainingId))
+	return exists && training.ContainsChallenge(questionId)(found)
+}
+
+// GetAll liefert die Meldungen mit dem Status aus ?status= (Default open, all für alle),
+// optional nur zu einer Frage (?questionId=)
+func (controller *Controller) GetAll(w http.ResponseWriter, r *http.Request) {
+	predicate := HasStatus(Open)
+	if value := r.URL.Query().Get("status"); value == "all" {
+		predicate = predicates.True[Report]()
+	} else if value != "" {
+		status, err := ParseStatus(value)
+		if err != nil {
+			utils.BadRequest(w, r, err.Error())
+			return
+		}
+		predicate = HasStatus(status)
+	}
+	if value := r.URL.Query().Get("questionId"); value != "" {
+		questionId, err := uuid.Parse(value)
+		if err != nil {
+			utils.BadRequest(w, r, "invalid question id")
+			return
+		}
+		predicate = predicates.And(predicate, ForQuestion(questionId))
+	}
+	httputils.OkJson(w, r, collections.Map(controller.repo.FindAll(predicate), controller.mapReportDTO))
+}
+
+// Resolve schließt eine Meldung, der Body {"resolution": "..."} ist optional
+func (controller *Controller) Resolve(w http.ResponseWriter, r *http.Request) {
+	var requestDTO struct {
+		Resolution string `json:"resolution"`
+	}
+
+	if idString, exists := routing.GetParameter(r.Context(), "reportId"); !exists {
+		utils.BadRequest(w, r, "missing report id")
+	} else if reportId, err := uuid.Parse(idString); err != nil {
+		utils.BadRequest(w, r, "invalid report id")
+	} else if err := json.NewDecoder(r.Body).Decode(&requestDTO); err != nil && !errors.Is(err, io.EOF) {
+		utils.BadRequest(w, r, "invalid request body")
+	} else if report, exists := controller.repo.FindById(reportId); !exists {
+		utils.NotFound(w, r, "report not found")
+	} else if err := report.Resolve(requestDTO.Resolution, time.Now()); err != nil {
+		utils.SendError(w, r, err)
+	} else if report, err := controller.repo.Save(report); err != nil {
+		utils.SendError(w, r, err)
+	} else {
+		httputils.OkJson(w, r, controller.mapReportDTO(report))
+	}
+}
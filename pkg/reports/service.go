@@ -0,0 +1,13 @@
+package reports
+
+import "github.com/mwildt/ceh-utils/pkg/events"
+
+// Submit speichert eine neue Meldung und verteilt question.reported
+func Submit(repo Repository, report Report) (Report, error) {
+	report, err := repo.Save(report)
+	if err != nil {
+		return report, err
+	}
+	_ = events.Emit(reportedEventType, reportedEvent(report))
+	return report, nil
+}
@@ -0,0 +1,56 @@
+package reports
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/events"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSubmitEmitsReportedEvent(t *testing.T) {
+	received := make(chan ReportedEvent, 1)
+	utils.AssertNoError(t, events.Subscribe(reportedEventType, func(event ReportedEvent) error {
+		received <- event
+		return nil
+	}), "subscribe failed")
+
+	repo, err := CreateFileRepository(filepath.Join(t.TempDir(), "reports.data"))
+	utils.AssertNoError(t, err, "create repository failed")
+	defer repo.Close()
+	report, err := CreateReport(uuid.New(), uuid.New(), WrongAnswer, "b is right", "")
+	utils.AssertNoError(t, err, "create report failed")
+	_, err = Submit(repo, report)
+	utils.AssertNoError(t, err, "submit failed")
+
+	select {
+	case event := <-received:
+		utils.Assert(t, event.ReportId == report.Id && event.Category == WrongAnswer, "unexpected event %+v", event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("question.reported not received")
+	}
+	_, found := repo.FindById(report.Id)
+	utils.Assert(t, found, "report not saved")
+}
+
+func TestFileRepositoryKeepsResolutionAfterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.data")
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "create repository failed")
+	report, err := CreateReport(uuid.New(), uuid.Nil, Outdated, "", "")
+	utils.AssertNoError(t, err, "create report failed")
+	_, err = repo.Save(report)
+	utils.AssertNoError(t, err, "save failed")
+	utils.AssertNoError(t, report.Resolve("updated to v12", time.Now()), "resolve failed")
+	_, err = repo.Save(report)
+	utils.AssertNoError(t, err, "save resolution failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	repo, err = CreateFileRepository(path)
+	utils.AssertNoError(t, err, "reopen repository failed")
+	defer repo.Close()
+	loaded, found := repo.FindById(report.Id)
+	utils.Assert(t, found && loaded.Status == Resolved && loaded.Resolution == "updated to v12", "resolution lost: %+v", loaded)
+	utils.Assert(t, len(repo.FindAll(ForQuestion(report.QuestionId))) == 1, "expected one report for the question")
+}
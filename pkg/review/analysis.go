@@ -0,0 +1,149 @@
+package review
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/stats"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Thresholds legen fest, wann eine Frage markiert wird: nach mindestens MinAttempts Antworten
+// wurde eine falsche Option mindestens Ratio mal so oft gewählt wie die richtige Antwort.
+// Interval ist der Abstand der regelmäßigen Analyse, 0 = nur auf Anforderung.
+type Thresholds struct {
+	MinAttempts int
+	Ratio       float64
+	Interval    time.Duration
+}
+
+// ConfiguredThresholds liest REVIEW_MIN_ATTEMPTS, REVIEW_RATIO und REVIEW_INTERVAL
+func ConfiguredThresholds() (thresholds Thresholds, err error) {
+	if thresholds.MinAttempts, err = strconv.Atoi(utils.GetEnvOrDefault("REVIEW_MIN_ATTEMPTS", "20")); err != nil || thresholds.MinAttempts < 1 {
+		return thresholds, fmt.Errorf("invalid REVIEW_MIN_ATTEMPTS: must be a positive number")
+	}
+	if thresholds.Ratio, err = strconv.ParseFloat(utils.GetEnvOrDefault("REVIEW_RATIO", "2"), 64); err != nil || thresholds.Ratio <= 0 {
+		return thresholds, fmt.Errorf("invalid REVIEW_RATIO: must be a positive number")
+	}
+	if thresholds.Interval, err = time.ParseDuration(utils.GetEnvOrDefault("REVIEW_INTERVAL", "1h")); err != nil {
+		return thresholds, fmt.Errorf("invalid REVIEW_INTERVAL: %w", err)
+	}
+	return thresholds, nil
+}
+
+// suspicious prüft, ob eine falsche Option auffällig häufiger gewählt wurde als die richtige
+// Antwort. Die richtige Antwort wurde so oft gewählt, wie die Frage richtig beantwortet wurde.
+func (thresholds Thresholds) suspicious(questionStats *stats.QuestionStats, question *questions.Question) (Flag, bool) {
+	choice, count, found := questionStats.MostCommonWrongChoice(question.AnswerIds)
+	if !found || questionStats.Attempts < thresholds.MinAttempts || float64(count) < thresholds.Ratio*float64(max(questionStats.Passed, 1)) {
+		return Flag{}, false
+	}
+	return Flag{
+		QuestionId:  question.Id,
+		AnswerIds:   append(make([]uuid.UUID, 0, len(question.AnswerIds)), question.AnswerIds...),
+		WrongChoice: choice,
+		WrongCount:  count,
+		KeyedCount:  questionStats.Passed,
+		Attempts:    questionStats.Attempts,
+		Status:      Open,
+	}, true
+}
+
+// Analyze prüft die Statistik aller Fragen und markiert neue Auffälligkeiten. Offene
+// Markierungen werden mit den aktuellen Zahlen fortgeschrieben, erledigte Befunde nicht erneut
+// gemeldet.
+func Analyze(repo Repository, statsRepo stats.Repository, questionRepo questions.Repository, thresholds Thresholds, now time.Time) (flagged []Flag, err error) {
+	for _, questionStats := range statsRepo.FindAll() {
+		question, exists := questionRepo.FindFirst(questions.IdEquals(questionStats.QuestionId))
+		if !exists {
+			continue
+		}
+		flag, suspicious := thresholds.suspicious(questionStats, question)
+		if !suspicious {
+			continue
+		}
+		if existing, exists := repo.FindById(question.Id); exists && existing.Status != Open && existing.Covers(flag.AnswerIds, flag.WrongChoice) {
+			continue
+		} else if exists && existing.Status == Open {
+			flag.Flagged = existing.Flagged
+		} else {
+			flag.Flagged = now
+			flagged = append(flagged, flag)
+		}
+		if _, err = repo.Save(flag); err != nil {
+			return flagged, err
+		}
+	}
+	return flagged, nil
+}
+
+// Analyzer führt die Analyse regelmäßig aus
+type Analyzer struct {
+	repo         Repository
+	statsRepo    stats.Repository
+	questionRepo questions.Repository
+	thresholds   Thresholds
+	logger       utils.Logger
+	mutex        *sync.Mutex
+	done         chan struct{}
+	stopped      chan struct{}
+	once         *sync.Once
+}
+
+func NewAnalyzer(repo Repository, statsRepo stats.Repository, questionRepo questions.Repository, thresholds Thresholds) *Analyzer {
+	analyzer := &Analyzer{
+		repo:         repo,
+		statsRepo:    statsRepo,
+		questionRepo: questionRepo,
+		thresholds:   thresholds,
+		logger:       utils.NewStdLogger("reviews.analyzer"),
+		mutex:        &sync.Mutex{},
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+		once:         &sync.Once{},
+	}
+	if thresholds.Interval > 0 {
+		go analyzer.run()
+	} else {
+		close(analyzer.stopped)
+	}
+	return analyzer
+}
+
+func (analyzer *Analyzer) run() {
+	defer close(analyzer.stopped)
+	ticker := time.NewTicker(analyzer.thresholds.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-analyzer.done:
+			return
+		case <-ticker.C:
+			if _, err := analyzer.Analyze(); err != nil {
+				analyzer.logger.Error("analysis failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Analyze führt die Analyse sofort aus, parallele Aufrufe warten aufeinander
+func (analyzer *Analyzer) Analyze() ([]Flag, error) {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+	flagged, err := Analyze(analyzer.repo, analyzer.statsRepo, analyzer.questionRepo, analyzer.thresholds, time.Now())
+	for _, flag := range flagged {
+		analyzer.logger.Info("question %s flagged: option %s chosen %d times, answer key %d times", flag.QuestionId, flag.WrongChoice, flag.WrongCount, flag.KeyedCount)
+	}
+	return flagged, err
+}
+
+// Stop beendet die Goroutine und wartet auf eine ggf. laufende Analyse
+func (analyzer *Analyzer) Stop() {
+	analyzer.once.Do(func() {
+		close(analyzer.done)
+		<-analyzer.stopped
+	})
+}
@@ -0,0 +1,86 @@
+package review
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/stats"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testThresholds = Thresholds{MinAttempts: 4, Ratio: 2}
+
+// createMiskeyedQuestion legt eine Frage an, bei der die Option b dreimal und der Schlüssel a
+// einmal gewählt wurde
+func createMiskeyedQuestion(t *testing.T) (questions.Repository, stats.Repository, *questions.Question) {
+	questionRepo, err := questions.CreateRepo(filepath.Join(t.TempDir(), "question.data"))
+	utils.AssertNoError(t, err, "create question repository failed")
+	t.Cleanup(func() { _ = questionRepo.Close() })
+	options := []questions.Option{{Id: uuid.New(), Option: "a"}, {Id: uuid.New(), Option: "b"}}
+	question, err := questionRepo.Save(questions.CreateQuestion("miskeyed", options, []uuid.UUID{options[0].Id}, nil, nil))
+	utils.AssertNoError(t, err, "save question failed")
+
+	statsRepo := stats.CreateRepo()
+	for i := 0; i < 3; i++ {
+		statsRepo.Record(stats.Answer{TrainingId: uuid.New(), QuestionId: question.Id, AnswerIds: []uuid.UUID{options[1].Id}})
+	}
+	statsRepo.Record(stats.Answer{TrainingId: uuid.New(), QuestionId: question.Id, AnswerIds: []uuid.UUID{options[0].Id}, Passed: true})
+	return questionRepo, statsRepo, question
+}
+
+func createRepo(t *testing.T) Repository {
+	repo, err := CreateFileRepository(filepath.Join(t.TempDir(), "reviews.data"))
+	utils.AssertNoError(t, err, "create review repository failed")
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+func TestAnalyzeFlagsDominantWrongChoice(t *testing.T) {
+	questionRepo, statsRepo, question := createMiskeyedQuestion(t)
+	repo := createRepo(t)
+
+	flagged, err := Analyze(repo, statsRepo, questionRepo, testThresholds, time.Now())
+	utils.AssertNoError(t, err, "analyze failed")
+	utils.Assert(t, len(flagged) == 1, "expected 1 flag, got %d", len(flagged))
+	utils.Assert(t, flagged[0].WrongChoice == question.Options[1].Id && flagged[0].WrongCount == 3 && flagged[0].KeyedCount == 1, "wrong flag %+v", flagged[0])
+
+	flagged, err = Analyze(repo, statsRepo, questionRepo, testThresholds, time.Now())
+	utils.AssertNoError(t, err, "second analyze failed")
+	utils.Assert(t, len(flagged) == 0, "expected open flag not to be reported again, got %d", len(flagged))
+}
+
+func TestAnalyzeRespectsThresholds(t *testing.T) {
+	questionRepo, statsRepo, _ := createMiskeyedQuestion(t)
+	flagged, err := Analyze(createRepo(t), statsRepo, questionRepo, Thresholds{MinAttempts: 5, Ratio: 2}, time.Now())
+	utils.AssertNoError(t, err, "analyze failed")
+	utils.Assert(t, len(flagged) == 0, "expected no flag below min attempts")
+	flagged, err = Analyze(createRepo(t), statsRepo, questionRepo, Thresholds{MinAttempts: 4, Ratio: 4}, time.Now())
+	utils.AssertNoError(t, err, "analyze failed")
+	utils.Assert(t, len(flagged) == 0, "expected no flag below ratio")
+}
+
+func TestAnalyzeSkipsDismissedUntilKeyChanges(t *testing.T) {
+	questionRepo, statsRepo, question := createMiskeyedQuestion(t)
+	repo := createRepo(t)
+	flagged, err := Analyze(repo, statsRepo, questionRepo, testThresholds, time.Now())
+	utils.AssertNoError(t, err, "analyze failed")
+	utils.AssertNoError(t, flagged[0].Dismiss("", time.Now()), "dismiss failed")
+	_, err = repo.Save(flagged[0])
+	utils.AssertNoError(t, err, "save failed")
+
+	flagged, err = Analyze(repo, statsRepo, questionRepo, testThresholds, time.Now())
+	utils.AssertNoError(t, err, "analyze failed")
+	utils.Assert(t, len(flagged) == 0, "expected dismissed finding not to be reported again")
+
+	// ein dritter Schlüssel ändert den Befund, b bleibt die auffällige Option
+	third := questions.Option{Id: uuid.New(), Option: "c"}
+	question.Options = append(question.Options, third)
+	question.AnswerIds = []uuid.UUID{third.Id}
+	_, err = questionRepo.Save(question)
+	utils.AssertNoError(t, err, "update question failed")
+	flagged, err = Analyze(repo, statsRepo, questionRepo, testThresholds, time.Now())
+	utils.AssertNoError(t, err, "analyze failed")
+	utils.Assert(t, len(flagged) == 1 && flagged[0].Status == Open, "expected new finding after key change, got %+v", flagged)
+}
@@ -0,0 +1,14 @@
+package review
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"go.etcd.io/bbolt"
+)
+
+func CreateBoltRepository(db *bbolt.DB) (Repository, error) {
+	repo, err := storage.CreateKeyedBoltRepository(db, flagStore)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
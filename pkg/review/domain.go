@@ -0,0 +1,74 @@
+package review
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"time"
+)
+
+type Status string
+
+const (
+	Open      Status = "open"
+	Confirmed Status = "confirmed"
+	Dismissed Status = "dismissed"
+)
+
+func ParseStatus(value string) (Status, error) {
+	switch status := Status(value); status {
+	case Open, Confirmed, Dismissed:
+		return status, nil
+	default:
+		return status, utils.Invalid("unknown status %q, expected open, confirmed or dismissed", value)
+	}
+}
+
+var ErrResolved = utils.Conflict("flag is already resolved")
+
+// Flag markiert eine Frage, deren Lösungsschlüssel vermutlich falsch ist: eine falsche Option
+// wurde deutlich häufiger gewählt als die richtige Antwort
+type Flag struct {
+	QuestionId uuid.UUID
+	// AnswerIds ist der Lösungsschlüssel zum Zeitpunkt der Markierung
+	AnswerIds   []uuid.UUID
+	WrongChoice uuid.UUID
+	WrongCount  int
+	KeyedCount  int
+	Attempts    int
+	Status      Status
+	Flagged     time.Time
+	Resolved    time.Time
+	Note        string
+}
+
+func (flag *Flag) Confirm(note string, now time.Time) error {
+	return flag.resolve(Confirmed, note, now)
+}
+
+func (flag *Flag) Dismiss(note string, now time.Time) error {
+	return flag.resolve(Dismissed, note, now)
+}
+
+func (flag *Flag) resolve(status Status, note string, now time.Time) error {
+	if flag.Status != Open {
+		return ErrResolved
+	}
+	flag.Status = status
+	flag.Note = note
+	flag.Resolved = now
+	return nil
+}
+
+// Covers prüft, ob die Markierung denselben Befund betrifft. Ein erledigter Befund wird nicht
+// erneut gemeldet, wohl aber, wenn sich der Lösungsschlüssel oder die auffällige Option ändert.
+func (flag *Flag) Covers(answerIds []uuid.UUID, wrongChoice uuid.UUID) bool {
+	if flag.WrongChoice != wrongChoice || len(flag.AnswerIds) != len(answerIds) {
+		return false
+	}
+	for _, id := range answerIds {
+		if !utils.Contains(flag.AnswerIds, id) {
+			return false
+		}
+	}
+	return true
+}
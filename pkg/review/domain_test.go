@@ -0,0 +1,23 @@
+package review
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"testing"
+	"time"
+)
+
+func TestResolveTwiceFails(t *testing.T) {
+	flag := Flag{QuestionId: uuid.New(), Status: Open}
+	utils.AssertNoError(t, flag.Dismiss("key is right", time.Now()), "dismiss failed")
+	utils.Assert(t, flag.Status == Dismissed && flag.Note == "key is right", "flag not dismissed: %+v", flag)
+	utils.Assert(t, flag.Confirm("", time.Now()) == ErrResolved, "expected resolved flag to stay dismissed")
+}
+
+func TestCoversIgnoresAnswerOrder(t *testing.T) {
+	a, b, wrong := uuid.New(), uuid.New(), uuid.New()
+	flag := Flag{AnswerIds: []uuid.UUID{a, b}, WrongChoice: wrong}
+	utils.Assert(t, flag.Covers([]uuid.UUID{b, a}, wrong), "expected same finding")
+	utils.Assert(t, !flag.Covers([]uuid.UUID{a}, wrong), "expected changed key to be a new finding")
+	utils.Assert(t, !flag.Covers([]uuid.UUID{a, b}, uuid.New()), "expected other option to be a new finding")
+}
@@ -0,0 +1,17 @@
+package review
+
+import "github.com/mwildt/ceh-utils/pkg/utils"
+
+var flagSchema = utils.NewMigrationRegistry("reviews", 1,
+	utils.Migration{
+		From:        0,
+		Description: "stamp schema version",
+		Apply: func(record utils.JsonObject) (utils.JsonObject, error) {
+			return record, nil
+		},
+	},
+)
+
+func Schema() *utils.MigrationRegistry {
+	return flagSchema
+}
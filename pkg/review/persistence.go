@@ -0,0 +1,27 @@
+package review
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type flagRecord struct {
+	QuestionId  uuid.UUID   `json:"questionId"`
+	AnswerIds   []uuid.UUID `json:"answerIds"`
+	WrongChoice uuid.UUID   `json:"wrongChoice"`
+	WrongCount  int         `json:"wrongCount"`
+	KeyedCount  int         `json:"keyedCount"`
+	Attempts    int         `json:"attempts"`
+	Status      Status      `json:"status"`
+	Flagged     time.Time   `json:"flagged"`
+	Resolved    time.Time   `json:"resolved,omitempty"`
+	Note        string      `json:"note,omitempty"`
+}
+
+func toFlagRecord(flag Flag) flagRecord {
+	return flagRecord(flag)
+}
+
+func (record flagRecord) toDomain() Flag {
+	return Flag(record)
+}
@@ -0,0 +1,42 @@
+package review
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/ohrenpiraten/go-collections/predicates"
+)
+
+// Repository hält je Frage die letzte Markierung, die Entscheidungen der Redaktion bleiben so
+// über einen Neustart hinaus erhalten
+type Repository interface {
+	Save(flag Flag) (Flag, error)
+	FindById(questionId uuid.UUID) (Flag, bool)
+	// FindAll liefert die passenden Markierungen, die älteste zuerst
+	FindAll(predicate predicates.Predicate[Flag]) []Flag
+	Close() error
+}
+
+func HasStatus(status Status) predicates.Predicate[Flag] {
+	return func(flag Flag) bool {
+		return flag.Status == status
+	}
+}
+
+// flagStore legt fest, wie die Markierungen gespeichert werden, es gilt der letzte Datensatz einer Frage
+var flagStore = storage.Keyed[Flag, flagRecord]{
+	Name:     "reviews",
+	File:     storage.ReviewsFile,
+	Schema:   flagSchema,
+	Key:      func(value Flag) uuid.UUID { return value.QuestionId },
+	ToRecord: toFlagRecord,
+	ToDomain: flagRecord.toDomain,
+	Less:     func(a Flag, b Flag) bool { return a.Flagged.Before(b.Flagged) },
+}
+
+func CreateFileRepository(path string) (Repository, error) {
+	repo, err := storage.CreateKeyedFileRepository(path, flagStore)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
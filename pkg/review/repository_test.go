@@ -0,0 +1,30 @@
+package review
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRepositoryKeepsDecisionAfterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reviews.data")
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "create repository failed")
+	flag := Flag{QuestionId: uuid.New(), AnswerIds: []uuid.UUID{uuid.New()}, WrongChoice: uuid.New(), Status: Open, Flagged: time.Now()}
+	_, err = repo.Save(flag)
+	utils.AssertNoError(t, err, "save failed")
+	utils.AssertNoError(t, flag.Confirm("fixed", time.Now()), "confirm failed")
+	_, err = repo.Save(flag)
+	utils.AssertNoError(t, err, "save decision failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	repo, err = CreateFileRepository(path)
+	utils.AssertNoError(t, err, "reopen repository failed")
+	defer repo.Close()
+	loaded, found := repo.FindById(flag.QuestionId)
+	utils.Assert(t, found, "flag not loaded")
+	utils.Assert(t, loaded.Status == Confirmed && loaded.Note == "fixed" && !loaded.Resolved.IsZero(), "decision lost: %+v", loaded)
+	utils.Assert(t, len(repo.FindAll(HasStatus(Open))) == 0, "expected no open flag")
+}
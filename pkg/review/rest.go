@@ -0,0 +1,134 @@
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+	"github.com/ohrenpiraten/go-collections/collections"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"io"
+	"net/http"
+	"time"
+)
+
+type Controller struct {
+	repo         Repository
+	questionRepo questions.Repository
+	analyzer     *Analyzer
+}
+
+func NewRestController(repo Repository, questionRepo questions.Repository, analyzer *Analyzer) *Controller {
+	return &Controller{
+		repo:         repo,
+		questionRepo: questionRepo,
+		analyzer:     analyzer,
+	}
+}
+
+func (controller *Controller) Routing(router routing.Routing) {
+	router.HandleFunc(routing.Get("/api/reviews/").Filter(utils.ApiSecured()), controller.GetAll)
+	router.HandleFunc(routing.Post("/api/reviews/analysis").Filter(utils.ApiSecured()), controller.PostAnalysis)
+	router.HandleFunc(routing.Post("/api/reviews/{questionId}/{action}").Filter(utils.ApiSecured()), utils.SubResources("action", map[string]http.HandlerFunc{
+		"confirm": controller.resolve((*Flag).Confirm),
+		"dismiss": controller.resolve((*Flag).Dismiss),
+	}))
+}
+
+type choiceDTO struct {
+	Id    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Count int       `json:"count"`
+}
+
+type flagDTO struct {
+	QuestionId  uuid.UUID   `json:"questionId"`
+	Text        string      `json:"text"`
+	Answer      []uuid.UUID `json:"answer"`
+	WrongChoice choiceDTO   `json:"wrongChoice"`
+	KeyedCount  int         `json:"keyedCount"`
+	Attempts    int         `json:"attempts"`
+	Status      Status      `json:"status"`
+	Flagged     string      `json:"flagged"`
+	Resolved    string      `json:"resolved,omitempty"`
+	Note        string      `json:"note,omitempty"`
+}
+
+func (controller *Controller) mapFlagDTO(flag Flag) flagDTO {
+	dto := flagDTO{
+		QuestionId:  flag.QuestionId,
+		Answer:      flag.AnswerIds,
+		WrongChoice: choiceDTO{Id: flag.WrongChoice, Count: flag.WrongCount},
+		KeyedCount:  flag.KeyedCount,
+		Attempts:    flag.Attempts,
+		Status:      flag.Status,
+		Flagged:     flag.Flagged.Format(time.RFC3339),
+		Note:        flag.Note,
+	}
+	if !flag.Resolved.IsZero() {
+		dto.Resolved = flag.Resolved.Format(time.RFC3339)
+	}
+	if question, exists := controller.questionRepo.FindFirst(questions.IdEquals(flag.QuestionId)); exists {
+		dto.Text = question.Question
+		for _, option := range question.Options {
+			if option.Id == flag.WrongChoice {
+				dto.WrongChoice.Text = option.Option
+			}
+		}
+	}
+	return dto
+}
+
+// GetAll liefert die Markierungen mit dem Status aus ?status= (Default open), ?status=all alle
+func (controller *Controller) GetAll(w http.ResponseWriter, r *http.Request) {
+	predicate := HasStatus(Open)
+	if value := r.URL.Query().Get("status"); value == "all" {
+		predicate = predicates.True[Flag]()
+	} else if value != "" {
+		status, err := ParseStatus(value)
+		if err != nil {
+			utils.BadRequest(w, r, err.Error())
+			return
+		}
+		predicate = HasStatus(status)
+	}
+	httputils.OkJson(w, r, collections.Map(controller.repo.FindAll(predicate), controller.mapFlagDTO))
+}
+
+// PostAnalysis führt die Analyse sofort aus und liefert die neu markierten Fragen
+func (controller *Controller) PostAnalysis(w http.ResponseWriter, r *http.Request) {
+	if flagged, err := controller.analyzer.Analyze(); err != nil {
+		utils.SendError(w, r, err)
+	} else {
+		httputils.OkJson(w, r, collections.Map(append(make([]Flag, 0), flagged...), controller.mapFlagDTO))
+	}
+}
+
+// resolve bestätigt oder verwirft eine Markierung, der Body {"note": "..."} ist optional. Den
+// Lösungsschlüssel korrigiert die Redaktion wie bisher über PATCH /api/questions/{id}.
+func (controller *Controller) resolve(decide func(*Flag, string, time.Time) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestDTO struct {
+			Note string `json:"note"`
+		}
+
+		if idString, exists := routing.GetParameter(r.Context(), "questionId"); !exists {
+			utils.BadRequest(w, r, "missing question id")
+		} else if questionId, err := uuid.Parse(idString); err != nil {
+			utils.BadRequest(w, r, "invalid question id")
+		} else if err := json.NewDecoder(r.Body).Decode(&requestDTO); err != nil && !errors.Is(err, io.EOF) {
+			utils.BadRequest(w, r, "invalid request body")
+		} else if flag, exists := controller.repo.FindById(questionId); !exists {
+			utils.NotFound(w, r, "flag not found")
+		} else if err := decide(&flag, requestDTO.Note, time.Now()); err != nil {
+			utils.SendError(w, r, err)
+		} else if flag, err := controller.repo.Save(flag); err != nil {
+			utils.SendError(w, r, err)
+		} else {
+			httputils.OkJson(w, r, controller.mapFlagDTO(flag))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"sort"
+)
+
+// QuestionStats sind die Antworten auf eine Frage über alle Trainings. Ein Durchgang umfasst
+// alle Antworten eines Trainings auf die Frage bis zur ersten richtigen Antwort.
+type QuestionStats struct {
+	QuestionId uuid.UUID
+	Attempts   int
+	Passed     int
+	// Runs zählt die begonnenen, Solved die mit einer richtigen Antwort beendeten Durchgänge
+	Runs           int
+	Solved         int
+	FirstTryPassed int
+	// AttemptsToPass ist die Summe der Antworten aller beendeten Durchgänge
+	AttemptsToPass int
+	// WrongChoices zählt, wie oft eine Option in einer falschen Antwort gewählt wurde
+	WrongChoices map[uuid.UUID]int
+}
+
+func CreateQuestionStats(questionId uuid.UUID) *QuestionStats {
+	return &QuestionStats{QuestionId: questionId, WrongChoices: make(map[uuid.UUID]int)}
+}
+
+// Answer ist eine in einem Training gegebene Antwort
+type Answer struct {
+	TrainingId uuid.UUID
+	QuestionId uuid.UUID
+	AnswerIds  []uuid.UUID
+	Passed     bool
+}
+
+// add zählt eine Antwort. attempt ist die Nummer der Antwort im laufenden Durchgang (ab 1).
+func (stats *QuestionStats) add(answer Answer, attempt int) {
+	stats.Attempts++
+	if attempt == 1 {
+		stats.Runs++
+	}
+	if answer.Passed {
+		stats.Passed++
+		stats.Solved++
+		stats.AttemptsToPass += attempt
+		if attempt == 1 {
+			stats.FirstTryPassed++
+		}
+	} else {
+		for _, id := range answer.AnswerIds {
+			stats.WrongChoices[id]++
+		}
+	}
+}
+
+func (stats *QuestionStats) Failed() int {
+	return stats.Attempts - stats.Passed
+}
+
+// FailureRate ist der Anteil falscher Antworten, für unbeantwortete Fragen 0
+func (stats *QuestionStats) FailureRate() float64 {
+	if stats.Attempts == 0 {
+		return 0
+	}
+	return float64(stats.Failed()) / float64(stats.Attempts)
+}
+
+// FirstTrySuccessRate ist der Anteil der Durchgänge, die mit der ersten Antwort beendet wurden
+func (stats *QuestionStats) FirstTrySuccessRate() float64 {
+	if stats.Runs == 0 {
+		return 0
+	}
+	return float64(stats.FirstTryPassed) / float64(stats.Runs)
+}
+
+// AverageAttemptsToPass ist die durchschnittliche Anzahl Antworten bis zur richtigen Antwort
+func (stats *QuestionStats) AverageAttemptsToPass() float64 {
+	if stats.Solved == 0 {
+		return 0
+	}
+	return float64(stats.AttemptsToPass) / float64(stats.Solved)
+}
+
+// MostCommonWrongChoice liefert die in falschen Antworten am häufigsten gewählte Option, die nicht
+// zu den richtigen Antworten gehört. Der Lösungsschlüssel wird übergeben, weil er sich ändern kann.
+func (stats *QuestionStats) MostCommonWrongChoice(answerIds []uuid.UUID) (choice uuid.UUID, count int, found bool) {
+	ids := make([]uuid.UUID, 0, len(stats.WrongChoices))
+	for id := range stats.WrongChoices {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	for _, id := range ids {
+		if !utils.Contains(answerIds, id) && stats.WrongChoices[id] > count {
+			choice, count, found = id, stats.WrongChoices[id], true
+		}
+	}
+	return choice, count, found
+}
+
+// Harder ordnet Fragen mit geringerer Erfolgsquote im ersten Versuch und bei Gleichstand mit mehr
+// Antworten bis zur richtigen zuerst
+func Harder(a *QuestionStats, b *QuestionStats) bool {
+	if a.FirstTrySuccessRate() != b.FirstTrySuccessRate() {
+		return a.FirstTrySuccessRate() < b.FirstTrySuccessRate()
+	} else if a.AverageAttemptsToPass() != b.AverageAttemptsToPass() {
+		return a.AverageAttemptsToPass() > b.AverageAttemptsToPass()
+	}
+	return a.Attempts > b.Attempts
+}
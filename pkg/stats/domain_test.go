@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"testing"
+)
+
+func TestRecordCountsRunsPerTraining(t *testing.T) {
+	repo := CreateRepo()
+	questionId, correct, wrong := uuid.New(), uuid.New(), uuid.New()
+	first, second := uuid.New(), uuid.New()
+
+	// erstes Training: falsch, falsch, richtig; zweites Training: sofort richtig
+	repo.Record(Answer{TrainingId: first, QuestionId: questionId, AnswerIds: []uuid.UUID{wrong}})
+	repo.Record(Answer{TrainingId: second, QuestionId: questionId, AnswerIds: []uuid.UUID{correct}, Passed: true})
+	repo.Record(Answer{TrainingId: first, QuestionId: questionId, AnswerIds: []uuid.UUID{wrong, correct}})
+	repo.Record(Answer{TrainingId: first, QuestionId: questionId, AnswerIds: []uuid.UUID{correct}, Passed: true})
+
+	stats, found := repo.FindById(questionId)
+	utils.Assert(t, found, "stats not found")
+	utils.Assert(t, stats.Attempts == 4 && stats.Passed == 2 && stats.Failed() == 2, "wrong counts %+v", stats)
+	utils.Assert(t, stats.FirstTrySuccessRate() == 0.5, "expected first try rate 0.5, got %f", stats.FirstTrySuccessRate())
+	utils.Assert(t, stats.AverageAttemptsToPass() == 2, "expected 2 attempts to pass, got %f", stats.AverageAttemptsToPass())
+	utils.Assert(t, repo.FailureRate(questionId) == 0.5, "expected failure rate 0.5, got %f", repo.FailureRate(questionId))
+
+	choice, count, found := stats.MostCommonWrongChoice([]uuid.UUID{correct})
+	utils.Assert(t, found && choice == wrong && count == 2, "expected wrong option chosen twice, got %s %d", choice, count)
+}
+
+func TestHardestOrdersByFirstTrySuccess(t *testing.T) {
+	repo := CreateRepo()
+	easy, hard, unanswered := uuid.New(), uuid.New(), uuid.New()
+	training := uuid.New()
+	repo.Record(Answer{TrainingId: training, QuestionId: easy, Passed: true})
+	repo.Record(Answer{TrainingId: training, QuestionId: hard})
+	repo.Record(Answer{TrainingId: training, QuestionId: hard, Passed: true})
+
+	hardest := repo.Hardest(10, 1)
+	utils.Assert(t, len(hardest) == 2, "expected 2 answered questions, got %d", len(hardest))
+	utils.Assert(t, hardest[0].QuestionId == hard && hardest[1].QuestionId == easy, "wrong order")
+	utils.Assert(t, len(repo.Hardest(1, 1)) == 1, "limit ignored")
+	utils.Assert(t, len(repo.Hardest(10, 2)) == 1, "minAttempts ignored")
+	_, found := repo.FindById(unanswered)
+	utils.Assert(t, !found, "unanswered question must not have stats")
+}
@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+)
+
+// Repository hält die Statistiken aller Fragen. Sie werden nicht gespeichert, sondern beim Start
+// aus den Trainings aufgebaut (siehe Rebuild).
+type Repository interface {
+	Record(answer Answer)
+	FindById(questionId uuid.UUID) (*QuestionStats, bool)
+	FindAll() []*QuestionStats
+	// Hardest liefert höchstens limit Fragen, die mindestens minAttempts mal beantwortet wurden,
+	// die schwierigste zuerst
+	Hardest(limit int, minAttempts int) []*QuestionStats
+	FailureRate(questionId uuid.UUID) float64
+}
+
+type runKey struct {
+	trainingId uuid.UUID
+	questionId uuid.UUID
+}
+
+type memoryRepository struct {
+	values map[uuid.UUID]*QuestionStats
+	// runs enthält die Anzahl der Antworten im laufenden Durchgang je Training und Frage
+	runs  map[runKey]int
+	mutex *sync.Mutex
+}
+
+func CreateRepo() Repository {
+	return &memoryRepository{
+		values: make(map[uuid.UUID]*QuestionStats),
+		runs:   make(map[runKey]int),
+		mutex:  &sync.Mutex{},
+	}
+}
+
+func (repo *memoryRepository) Record(answer Answer) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	stats, exists := repo.values[answer.QuestionId]
+	if !exists {
+		stats = CreateQuestionStats(answer.QuestionId)
+		repo.values[answer.QuestionId] = stats
+	}
+	key := runKey{answer.TrainingId, answer.QuestionId}
+	repo.runs[key]++
+	stats.add(answer, repo.runs[key])
+	if answer.Passed {
+		delete(repo.runs, key)
+	}
+}
+
+// FindById liefert eine Kopie, damit der Aufrufer nicht mit laufenden Änderungen konkurriert
+func (repo *memoryRepository) FindById(questionId uuid.UUID) (*QuestionStats, bool) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	if stats, exists := repo.values[questionId]; exists {
+		return stats.copy(), true
+	}
+	return nil, false
+}
+
+func (repo *memoryRepository) FindAll() []*QuestionStats {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	result := make([]*QuestionStats, 0, len(repo.values))
+	for _, stats := range repo.values {
+		result = append(result, stats.copy())
+	}
+	return result
+}
+
+func (repo *memoryRepository) Hardest(limit int, minAttempts int) []*QuestionStats {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	result := make([]*QuestionStats, 0)
+	for _, stats := range repo.values {
+		if stats.Attempts >= minAttempts && stats.Attempts > 0 {
+			result = append(result, stats.copy())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if Harder(result[i], result[j]) != Harder(result[j], result[i]) {
+			return Harder(result[i], result[j])
+		}
+		return result[i].QuestionId.String() < result[j].QuestionId.String()
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func (repo *memoryRepository) FailureRate(questionId uuid.UUID) float64 {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	if stats, exists := repo.values[questionId]; exists {
+		return stats.FailureRate()
+	}
+	return 0
+}
+
+func (stats *QuestionStats) copy() *QuestionStats {
+	result := *stats
+	result.WrongChoices = make(map[uuid.UUID]int, len(stats.WrongChoices))
+	for id, count := range stats.WrongChoices {
+		result.WrongChoices[id] = count
+	}
+	return &result
+}
@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+	"strconv"
+)
+
+type Controller struct {
+	repo         Repository
+	questionRepo questions.Repository
+}
+
+func NewRestController(repo Repository, questionRepo questions.Repository) *Controller {
+	return &Controller{
+		repo:         repo,
+		questionRepo: questionRepo,
+	}
+}
+
+func (controller *Controller) Routing(router routing.Routing) {
+	router.HandleFunc(routing.Get("/api/questions/{questionId}/{resource}"), utils.SubResources("resource", map[string]http.HandlerFunc{
+		"stats": controller.GetByQuestionId,
+	}))
+	router.HandleFunc(routing.Get("/api/stats/hardest-questions").Filter(utils.ApiSecured()), controller.GetHardest)
+}
+
+type wrongChoiceDTO struct {
+	Id    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Count int       `json:"count"`
+}
+
+type questionStatsDTO struct {
+	QuestionId            uuid.UUID       `json:"questionId"`
+	Text                  string          `json:"text"`
+	Attempts              int             `json:"attempts"`
+	Passed                int             `json:"passed"`
+	Failed                int             `json:"failed"`
+	FirstTrySuccessRate   float64         `json:"firstTrySuccessRate"`
+	AverageAttemptsToPass float64         `json:"averageAttemptsToPass"`
+	MostCommonWrongChoice *wrongChoiceDTO `json:"mostCommonWrongChoice,omitempty"`
+}
+
+func mapStatsDTO(stats *QuestionStats, question *questions.Question) questionStatsDTO {
+	dto := questionStatsDTO{
+		QuestionId:            question.Id,
+		Text:                  question.Question,
+		Attempts:              stats.Attempts,
+		Passed:                stats.Passed,
+		Failed:                stats.Failed(),
+		FirstTrySuccessRate:   stats.FirstTrySuccessRate(),
+		AverageAttemptsToPass: stats.AverageAttemptsToPass(),
+	}
+	if choice, count, found := stats.MostCommonWrongChoice(question.AnswerIds); found {
+		dto.MostCommonWrongChoice = &wrongChoiceDTO{Id: choice, Count: count}
+		for _, option := range question.Options {
+			if option.Id == choice {
+				dto.MostCommonWrongChoice.Text = option.Option
+			}
+		}
+	}
+	return dto
+}
+
+func (controller *Controller) GetByQuestionId(w http.ResponseWriter, r *http.Request) {
+	if idString, exists := routing.GetParameter(r.Context(), "questionId"); !exists {
+		utils.BadRequest(w, r, "missing question id")
+	} else if questionId, err := uuid.Parse(idString); err != nil {
+		utils.BadRequest(w, r, "invalid question id")
+	} else if question, exists := controller.questionRepo.FindFirst(questions.IdEquals(questionId)); !exists {
+		utils.NotFound(w, r, "question not found")
+	} else if stats, exists := controller.repo.FindById(questionId); !exists {
+		httputils.OkJson(w, r, mapStatsDTO(CreateQuestionStats(questionId), question))
+	} else {
+		httputils.OkJson(w, r, mapStatsDTO(stats, question))
+	}
+}
+
+// GetHardest liefert die schwierigsten Fragen für die Redaktion, limit (Default 20) und
+// minAttempts (Default 1) grenzen die Liste ein
+func (controller *Controller) GetHardest(w http.ResponseWriter, r *http.Request) {
+	if limit, err := intParameter(r, "limit", 20); err != nil || limit < 1 {
+		utils.BadRequest(w, r, "invalid limit")
+	} else if minAttempts, err := intParameter(r, "minAttempts", 1); err != nil {
+		utils.BadRequest(w, r, "invalid minAttempts")
+	} else {
+		result := make([]questionStatsDTO, 0)
+		for _, stats := range controller.repo.Hardest(limit, minAttempts) {
+			if question, exists := controller.questionRepo.FindFirst(questions.IdEquals(stats.QuestionId)); exists {
+				result = append(result, mapStatsDTO(stats, question))
+			}
+		}
+		httputils.OkJson(w, r, result)
+	}
+}
+
+func intParameter(r *http.Request, name string, defaultValue int) (int, error) {
+	if value := r.URL.Query().Get(name); value != "" {
+		return strconv.Atoi(value)
+	}
+	return defaultValue, nil
+}
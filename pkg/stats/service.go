@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"context"
+	"github.com/mwildt/ceh-utils/pkg/events"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/predicates"
+)
+
+// Rebuild zählt alle Antworten aus den Timelines der gespeicherten Trainings
+func Rebuild(ctx context.Context, repository Repository, trainings training.Repository) error {
+	all, err := trainings.FindAllBy(ctx, predicates.True[*training.Training]())
+	if err != nil {
+		return err
+	}
+	for _, t := range all {
+		changes, _ := trainings.Timeline(ctx, t.Id)
+		for _, change := range changes {
+			if change.Type == training.AnswerGiven {
+				repository.Record(Answer{TrainingId: t.Id, QuestionId: change.ChallengeId, AnswerIds: change.AnswerIds, Passed: change.Passed})
+			}
+		}
+	}
+	return nil
+}
+
+func Subscribe(repository Repository) error {
+
+	logger := utils.NewStdLogger("stats.service")
+	eventType := "training.updated"
+
+	err := events.Subscribe(eventType, func(event training.UpdatedEvent) error {
+		repository.Record(Answer{TrainingId: event.TrainingId, QuestionId: event.ChallengeId, AnswerIds: event.AnswerIds, Passed: event.Passed})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("successfully registered to %s", eventType)
+	return nil
+}
@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/training"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestRebuildCountsAnswersFromTimelines(t *testing.T) {
+	trainingRepo, err := training.CreateFileRepository(filepath.Join(t.TempDir(), "trainings.data"))
+	utils.AssertNoError(t, err, "create training repository failed")
+	defer trainingRepo.Close()
+
+	challenges := []training.Challenge{{Id: uuid.New(), Answer: []uuid.UUID{uuid.New()}}, {Id: uuid.New(), Answer: []uuid.UUID{uuid.New()}}}
+	index := 0
+	provider := func(training.ChallengeQuery) (training.Challenge, error) {
+		challenge := challenges[index%len(challenges)]
+		index++
+		return challenge, nil
+	}
+	t1, err := training.CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+	_, _ = t1.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+	_, _ = t1.Next(context.Background(), challenges[0].Answer, provider)
+	_, err = trainingRepo.Save(context.Background(), t1)
+	utils.AssertNoError(t, err, "save training failed")
+
+	repo := CreateRepo()
+	utils.AssertNoError(t, Rebuild(context.Background(), repo, trainingRepo), "rebuild failed")
+	stats, found := repo.FindById(challenges[0].Id)
+	utils.Assert(t, found, "stats not rebuilt")
+	utils.Assert(t, stats.Attempts == 2 && stats.Solved == 1 && stats.FirstTryPassed == 0, "wrong counts %+v", stats)
+}
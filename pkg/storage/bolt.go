@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+// OpenBolt öffnet die eingebettete Datenbank. Ein anderer Prozess, der die Datei bereits
+// geöffnet hat, führt nach einer Sekunde zu einem Fehler.
+func OpenBolt(path string) (*bbolt.DB, error) {
+	return bbolt.Open(path, 0644, &bbolt.Options{Timeout: time.Second})
+}
+
+func CreateBuckets(db *bbolt.DB, names ...string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func PutJson[T any](bucket *bbolt.Bucket, key []byte, value T) error {
+	if data, err := json.Marshal(value); err != nil {
+		return err
+	} else {
+		return bucket.Put(key, data)
+	}
+}
+
+func GetJson[T any](bucket *bbolt.Bucket, key []byte) (value T, found bool, err error) {
+	data := bucket.Get(key)
+	if data == nil {
+		return value, false, nil
+	}
+	err = json.Unmarshal(data, &value)
+	return value, err == nil, err
+}
+
+func ForEachJson[T any](bucket *bbolt.Bucket, consumer func(key []byte, value T) error) error {
+	return bucket.ForEach(func(key, data []byte) error {
+		if data == nil {
+			// verschachtelte Buckets werden übersprungen
+			return nil
+		}
+		var value T
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		return consumer(key, value)
+	})
+}
+
+// PutVersionedJson speichert den Wert mit der aktuellen Schema-Version der Registry
+func PutVersionedJson[T any](bucket *bbolt.Bucket, key []byte, registry *utils.MigrationRegistry, value T) error {
+	if data, err := registry.Stamp(value); err != nil {
+		return err
+	} else {
+		return bucket.Put(key, data)
+	}
+}
+
+// ForEachVersionedJson migriert jeden Wert vor dem Lesen auf die aktuelle Schema-Version
+func ForEachVersionedJson[T any](bucket *bbolt.Bucket, registry *utils.MigrationRegistry, consumer func(key []byte, value T) error) error {
+	return bucket.ForEach(func(key, data []byte) error {
+		if data == nil {
+			return nil
+		}
+		migrated, _, err := registry.Migrate(data)
+		if err != nil {
+			return err
+		}
+		var value T
+		if err := json.Unmarshal(migrated, &value); err != nil {
+			return err
+		}
+		return consumer(key, value)
+	})
+}
+
+// SequenceKey erzeugt einen Schlüssel, dessen Sortierung der numerischen Reihenfolge entspricht
+func SequenceKey(sequence int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(sequence))
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Aufbau des Datenverzeichnisses
+const (
+	QuestionsFile = "question.data"
+	TrainingsFile = "trainings.data"
+	ReviewsFile   = "reviews.data"
+	ReportsFile   = "reports.data"
+	RemindersFile = "reminders.json"
+	BoltFile      = "ceh.db"
+	LockFile      = ".lock"
+)
+
+var ErrLocked = errors.New("data directory is locked by another instance")
+
+// DataDir ist ein exklusiv gesperrtes Datenverzeichnis. Die Sperre gilt bis Close oder
+// bis zum Ende des Prozesses.
+type DataDir struct {
+	path string
+	lock *os.File
+}
+
+func OpenDataDir(dir string) (*DataDir, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path.Join(dir, LockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(file); errors.Is(err, ErrLocked) {
+		owner, _ := os.ReadFile(file.Name())
+		file.Close()
+		return nil, fmt.Errorf("%w: %s (%s)", ErrLocked, dir, strings.TrimSpace(string(owner)))
+	} else if err != nil {
+		file.Close()
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	if err = file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(fmt.Sprintf("pid %d on %s\n", os.Getpid(), hostname)), 0)
+	}
+	if err != nil {
+		return nil, errors.Join(err, unlockFile(file), file.Close())
+	}
+	return &DataDir{path: dir, lock: file}, nil
+}
+
+func (dir *DataDir) Path() string {
+	return dir.path
+}
+
+func (dir *DataDir) File(name string) string {
+	return path.Join(dir.path, name)
+}
+
+func (dir *DataDir) Close() error {
+	return errors.Join(unlockFile(dir.lock), dir.lock.Close())
+}
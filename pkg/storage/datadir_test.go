@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"errors"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"testing"
+)
+
+func TestDataDirIsLockedExclusively(t *testing.T) {
+	path := t.TempDir()
+	dir, err := OpenDataDir(path)
+	utils.AssertNoError(t, err, "open failed")
+
+	_, err = OpenDataDir(path)
+	utils.Assert(t, errors.Is(err, ErrLocked), "expected ErrLocked, got %v", err)
+
+	utils.AssertNoError(t, dir.Close(), "close failed")
+	dir, err = OpenDataDir(path)
+	utils.AssertNoError(t, err, "open after close failed")
+	utils.AssertNoError(t, dir.Close(), "close failed")
+}
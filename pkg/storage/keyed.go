@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"go.etcd.io/bbolt"
+	"sort"
+	"sync"
+)
+
+// Keyed beschreibt, wie die Werte eines Repositories mit einem Datensatz je Schlüssel gespeichert
+// werden. T ist der Wert der Domäne, R der gespeicherte Datensatz.
+type Keyed[T any, R any] struct {
+	// Name des Logs, des Buckets und des Compactors
+	Name string
+	// File ist der Name der Log-Datei im Snapshot
+	File     string
+	Schema   *utils.MigrationRegistry
+	Key      func(value T) uuid.UUID
+	ToRecord func(value T) R
+	ToDomain func(record R) T
+	// Less legt die Reihenfolge von FindAll fest
+	Less func(a T, b T) bool
+}
+
+// keyedValues hält je Schlüssel den letzten Wert im Speicher
+type keyedValues[T any, R any] struct {
+	keyed  Keyed[T, R]
+	values map[uuid.UUID]T
+	mutex  *sync.Mutex
+}
+
+func newKeyedValues[T any, R any](keyed Keyed[T, R]) *keyedValues[T, R] {
+	return &keyedValues[T, R]{keyed: keyed, values: make(map[uuid.UUID]T), mutex: &sync.Mutex{}}
+}
+
+func (repo *keyedValues[T, R]) FindById(id uuid.UUID) (T, bool) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	value, exists := repo.values[id]
+	return value, exists
+}
+
+func (repo *keyedValues[T, R]) FindAll(predicate predicates.Predicate[T]) []T {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	result := make([]T, 0)
+	for _, value := range repo.values {
+		if predicate(value) {
+			result = append(result, value)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return repo.keyed.Less(result[i], result[j])
+	})
+	return result
+}
+
+// KeyedFileRepository schreibt jede Änderung an das Log, beim Laden gilt der letzte Datensatz
+// eines Schlüssels. Überholte Datensätze entfernt der Compactor.
+type KeyedFileRepository[T any, R any] struct {
+	*keyedValues[T, R]
+	path      string
+	codec     utils.Codec
+	file      utils.RecordLog
+	compactor *utils.Compactor
+	written   int
+	logger    utils.Logger
+}
+
+func CreateKeyedFileRepository[T any, R any](path string, keyed Keyed[T, R]) (*KeyedFileRepository[T, R], error) {
+	repo := &KeyedFileRepository[T, R]{
+		keyedValues: newKeyedValues(keyed),
+		path:        path,
+		logger:      utils.NewStdLogger(keyed.Name + ".repository"),
+	}
+	configured, err := utils.ConfiguredCodec()
+	if err != nil {
+		return nil, err
+	} else if repo.codec, err = utils.CodecFor(path, configured); err != nil {
+		return nil, err
+	} else if err = utils.CreateFileIfNotExists(path); err != nil {
+		return nil, err
+	} else if report, err := repo.codec.Recover(path); err != nil {
+		return nil, err
+	} else if report.Modified() {
+		repo.logger.Warn("recovered %s: %d records kept, %d corrupt records quarantined, %d bytes of an incomplete record truncated",
+			path, report.Records, report.Quarantined, report.TruncatedBytes)
+	}
+
+	decode := utils.VersionedJsonDecoder[R](keyed.Schema)
+	count, err := repo.codec.Load(path, func(data []byte) error {
+		record, _, err := decode(data)
+		if err == nil {
+			value := keyed.ToDomain(record)
+			repo.values[keyed.Key(value)] = value
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	repo.written = count
+	repo.logger.Info("%d records loaded from %s, %d in store", count, path, len(repo.values))
+	if repo.file, err = repo.codec.Open(path, utils.ConfiguredSyncPolicy()); err != nil {
+		return nil, err
+	}
+	repo.compactor = utils.NewCompactor(keyed.Name, repo, utils.ConfiguredCompactionThresholds())
+	return repo, nil
+}
+
+func (repo *KeyedFileRepository[T, R]) Save(value T) (T, error) {
+	repo.mutex.Lock()
+	err := utils.Append(repo.file, repo.keyed.ToRecord(value), utils.VersionedJsonEncoder[R](repo.keyed.Schema))
+	if err == nil {
+		repo.values[repo.keyed.Key(value)] = value
+		repo.written++
+	}
+	repo.mutex.Unlock()
+	if err != nil {
+		return value, err
+	}
+	repo.compactor.Notify()
+	return value, nil
+}
+
+func (repo *KeyedFileRepository[T, R]) CompactionStats() (total int, live int) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return repo.written, len(repo.values)
+}
+
+// Compact schreibt je Schlüssel nur den aktuellen Datensatz in eine neue Log-Datei
+func (repo *KeyedFileRepository[T, R]) Compact() error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	encode := utils.VersionedJsonEncoder[R](repo.keyed.Schema)
+	err := utils.CompactLogFile(repo.path, repo.codec, func(log utils.RecordLog) error {
+		for _, value := range repo.values {
+			if err := utils.Append(log, repo.keyed.ToRecord(value), encode); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	repo.logger.Info("%d records written to %s", len(repo.values), repo.path)
+	repo.written = len(repo.values)
+	if err = repo.file.Close(); err != nil {
+		return err
+	}
+	repo.file, err = repo.codec.Open(repo.path, utils.ConfiguredSyncPolicy())
+	return err
+}
+
+// Close beendet den Compactor, schreibt die Log-Datei auf die Platte und schließt sie
+func (repo *KeyedFileRepository[T, R]) Close() error {
+	repo.compactor.Stop()
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return errors.Join(repo.file.Sync(), repo.file.Close())
+}
+
+// Snapshot übernimmt die Log-Datei, solange keine Änderungen geschrieben werden
+func (repo *KeyedFileRepository[T, R]) Snapshot(write SnapshotWriter) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return SnapshotFile(repo.path, repo.keyed.File, write)
+}
+
+// KeyedBoltRepository speichert je Schlüssel einen Datensatz im Bucket Keyed.Name
+type KeyedBoltRepository[T any, R any] struct {
+	*keyedValues[T, R]
+	db *bbolt.DB
+}
+
+func CreateKeyedBoltRepository[T any, R any](db *bbolt.DB, keyed Keyed[T, R]) (*KeyedBoltRepository[T, R], error) {
+	repo := &KeyedBoltRepository[T, R]{keyedValues: newKeyedValues(keyed), db: db}
+	if err := CreateBuckets(db, keyed.Name); err != nil {
+		return nil, err
+	}
+	err := db.View(func(tx *bbolt.Tx) error {
+		return ForEachVersionedJson(tx.Bucket([]byte(keyed.Name)), keyed.Schema, func(_ []byte, record R) error {
+			value := keyed.ToDomain(record)
+			repo.values[keyed.Key(value)] = value
+			return nil
+		})
+	})
+	return repo, err
+}
+
+func (repo *KeyedBoltRepository[T, R]) Save(value T) (T, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	key := repo.keyed.Key(value)
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		return PutVersionedJson(tx.Bucket([]byte(repo.keyed.Name)), key[:], repo.keyed.Schema, repo.keyed.ToRecord(value))
+	})
+	if err != nil {
+		return value, err
+	}
+	repo.values[key] = value
+	return value, nil
+}
+
+// Close schließt die Datenbank nicht, sie wird von allen Repositories gemeinsam genutzt
+func (repo *KeyedBoltRepository[T, R]) Close() error {
+	return nil
+}
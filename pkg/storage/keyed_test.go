@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+type keyedValue struct {
+	Id    uuid.UUID `json:"id"`
+	Count int       `json:"count"`
+}
+
+var testValues = Keyed[keyedValue, keyedValue]{
+	Name:     "values",
+	File:     "values.data",
+	Schema:   utils.NewMigrationRegistry("values", 0),
+	Key:      func(value keyedValue) uuid.UUID { return value.Id },
+	ToRecord: func(value keyedValue) keyedValue { return value },
+	ToDomain: func(record keyedValue) keyedValue { return record },
+	Less:     func(a keyedValue, b keyedValue) bool { return a.Count < b.Count },
+}
+
+func TestKeyedFileRepositoryCompactsToLatestValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.data")
+	repo, err := CreateKeyedFileRepository(path, testValues)
+	utils.AssertNoError(t, err, "create repository failed")
+	first, second := keyedValue{Id: uuid.New()}, keyedValue{Id: uuid.New(), Count: 10}
+	for i := 1; i <= 3; i++ {
+		first.Count = i
+		_, err = repo.Save(first)
+		utils.AssertNoError(t, err, "save %d failed", i)
+	}
+	_, err = repo.Save(second)
+	utils.AssertNoError(t, err, "save failed")
+
+	total, live := repo.CompactionStats()
+	utils.Assert(t, total == 4 && live == 2, "unexpected stats %d/%d", total, live)
+	utils.AssertNoError(t, repo.Compact(), "compact failed")
+	_, err = repo.Save(keyedValue{Id: uuid.New(), Count: 5})
+	utils.AssertNoError(t, err, "save after compaction failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	repo, err = CreateKeyedFileRepository(path, testValues)
+	utils.AssertNoError(t, err, "reopen repository failed")
+	defer repo.Close()
+	total, live = repo.CompactionStats()
+	utils.Assert(t, total == 3 && live == 3, "unexpected stats after reload %d/%d", total, live)
+	values := repo.FindAll(func(keyedValue) bool { return true })
+	utils.Assert(t, len(values) == 3 && values[0].Count == 3 && values[2].Count == 10, "unexpected values %v", values)
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// ohne flock wird das Datenverzeichnis nicht gesperrt
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"go.etcd.io/bbolt"
+	"io"
+	"os"
+)
+
+// SnapshotWriter übernimmt eine Datei des Datenverzeichnisses in ein Backup
+type SnapshotWriter func(name string, size int64, content io.Reader) error
+
+// Snapshotter liefert einen konsistenten Stand seiner Dateien, auch während der Server läuft
+type Snapshotter interface {
+	Snapshot(write SnapshotWriter) error
+}
+
+// SnapshotFile übernimmt den aktuellen Inhalt einer Datei. Der Aufrufer muss sicherstellen, dass
+// währenddessen nicht in die Datei geschrieben wird.
+func SnapshotFile(path string, name string, write SnapshotWriter) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return write(name, info.Size(), io.LimitReader(file, info.Size()))
+}
+
+type boltSnapshotter struct {
+	db *bbolt.DB
+}
+
+// BoltSnapshotter schreibt die Datenbank innerhalb einer lesenden Transaktion
+func BoltSnapshotter(db *bbolt.DB) Snapshotter {
+	return boltSnapshotter{db}
+}
+
+func (snapshotter boltSnapshotter) Snapshot(write SnapshotWriter) error {
+	return snapshotter.db.View(func(tx *bbolt.Tx) error {
+		reader, writer := io.Pipe()
+		written := make(chan error, 1)
+		go func() {
+			_, err := tx.WriteTo(writer)
+			writer.CloseWithError(err)
+			written <- err
+		}()
+		err := write(BoltFile, tx.Size(), reader)
+		reader.CloseWithError(err)
+		return errors.Join(err, <-written)
+	})
+}
@@ -0,0 +1,228 @@
+package training
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"go.etcd.io/bbolt"
+	"sync"
+	"time"
+)
+
+var (
+	// aktueller Zustand je Training
+	trainingsBucket = []byte("trainings")
+	// Ausgangszustand von Trainings, die aus dem alten Format übernommen wurden
+	trainingBasesBucket = []byte("training-bases")
+	// je Training ein Bucket mit den Changes, Schlüssel ist die Version
+	trainingChangesBucket = []byte("training-changes")
+)
+
+// boltRepository speichert Changes und den aktuellen Zustand in der eingebetteten Datenbank.
+// Beides wird in einer Transaktion geschrieben, eine Kompaktierung ist daher nicht nötig.
+type boltRepository struct {
+	values map[uuid.UUID]*Training
+	db     *bbolt.DB
+	logger utils.Logger
+	mutex  *sync.Mutex
+}
+
+func CreateBoltRepository(db *bbolt.DB) (Repository, error) {
+	repo := &boltRepository{
+		values: make(map[uuid.UUID]*Training),
+		db:     db,
+		logger: utils.NewStdLogger("trainings.repository"),
+		mutex:  &sync.Mutex{},
+	}
+	if err := storage.CreateBuckets(db, string(trainingsBucket), string(trainingBasesBucket), string(trainingChangesBucket)); err != nil {
+		return nil, err
+	}
+	err := db.View(func(tx *bbolt.Tx) error {
+		return storage.ForEachJson(tx.Bucket(trainingsBucket), func(_ []byte, record *trainingRecord) error {
+			repo.values[record.Id] = record.toDomain()
+			return nil
+		})
+	})
+	if err == nil {
+		repo.logger.Info("%d trainings loaded from database", len(repo.values))
+	}
+	return repo, err
+}
+
+func (repo *boltRepository) Save(ctx context.Context, training *Training) (_ *Training, err error) {
+	if err = repo.store(training); err != nil {
+		return training, err
+	}
+	training.emitEvents()
+	return training, err
+}
+
+func (repo *boltRepository) store(training *Training) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		return putTraining(tx, toTrainingRecord(training), training.uncommittedChanges())
+	})
+	if err != nil {
+		return err
+	}
+	training.commitChanges()
+	repo.values[training.Id] = training
+	return nil
+}
+
+func putTraining(tx *bbolt.Tx, record *trainingRecord, changes []Change) error {
+	changesBucket, err := tx.Bucket(trainingChangesBucket).CreateBucketIfNotExists(record.Id[:])
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if err = storage.PutJson(changesBucket, storage.SequenceKey(change.Version), change); err != nil {
+			return err
+		}
+	}
+	return storage.PutJson(tx.Bucket(trainingsBucket), record.Id[:], record)
+}
+
+// Delete entfernt den Zustand und alle Changes, ein Tombstone ist in der Datenbank nicht nötig
+func (repo *boltRepository) Delete(ctx context.Context, training *Training) error {
+	if err := repo.remove(training.Id); err != nil {
+		return err
+	}
+	training.events = append(training.events, deletedEvent(training.Id))
+	training.emitEvents()
+	return nil
+}
+
+func (repo *boltRepository) remove(id uuid.UUID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(trainingsBucket).Delete(id[:]); err != nil {
+			return err
+		} else if err = tx.Bucket(trainingBasesBucket).Delete(id[:]); err != nil {
+			return err
+		} else if changes := tx.Bucket(trainingChangesBucket); changes.Bucket(id[:]) != nil {
+			return changes.DeleteBucket(id[:])
+		}
+		return nil
+	})
+	if err == nil {
+		delete(repo.values, id)
+	}
+	return err
+}
+
+func (repo *boltRepository) FindAllBy(ctx context.Context, predicate predicates.Predicate[*Training]) (list []*Training, err error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	for _, training := range repo.values {
+		if predicate(training) {
+			list = append(list, training)
+		}
+	}
+	return list, err
+}
+
+func (repo *boltRepository) FindFirst(ctx context.Context, predicate predicates.Predicate[*Training]) (*Training, bool) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	for _, training := range repo.values {
+		if predicate(training) {
+			return training, true
+		}
+	}
+	return nil, false
+}
+
+// liest den Ausgangszustand und die passenden Changes eines Trainings
+func (repo *boltRepository) readStream(id uuid.UUID, include predicates.Predicate[Change]) (base *trainingRecord, changes []Change, exists bool, err error) {
+	err = repo.db.View(func(tx *bbolt.Tx) error {
+		if exists = tx.Bucket(trainingsBucket).Get(id[:]) != nil; !exists {
+			return nil
+		}
+		if record, found, err := storage.GetJson[*trainingRecord](tx.Bucket(trainingBasesBucket), id[:]); err != nil {
+			return err
+		} else if found {
+			base = record
+		}
+		changes = make([]Change, 0)
+		if changesBucket := tx.Bucket(trainingChangesBucket).Bucket(id[:]); changesBucket != nil {
+			return storage.ForEachJson(changesBucket, func(_ []byte, change Change) error {
+				if include(change) {
+					changes = append(changes, change)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return base, changes, exists, err
+}
+
+func (repo *boltRepository) Timeline(ctx context.Context, id uuid.UUID) ([]Change, bool) {
+	_, changes, exists, err := repo.readStream(id, predicates.True[Change]())
+	if err != nil {
+		repo.logger.Error("unable to read timeline of training %s: %s", id, err.Error())
+		return nil, false
+	}
+	return changes, exists
+}
+
+func (repo *boltRepository) FindAt(ctx context.Context, id uuid.UUID, at time.Time) (*Training, bool) {
+	base, changes, exists, err := repo.readStream(id, func(change Change) bool {
+		return !change.Timestamp.After(at)
+	})
+	if err != nil {
+		repo.logger.Error("unable to read changes of training %s: %s", id, err.Error())
+		return nil, false
+	} else if !exists {
+		return nil, false
+	} else if base == nil && len(changes) == 0 {
+		return nil, false
+	} else if base != nil && base.Created.After(at) {
+		return nil, false
+	}
+	training, err := replay(id, base, changes)
+	if err != nil {
+		repo.logger.Error("unable to replay training %s: %s", id, err.Error())
+		return nil, false
+	}
+	return training, true
+}
+
+// Close schließt die Datenbank nicht, sie wird von allen Repositories gemeinsam genutzt
+func (repo *boltRepository) Close() error {
+	return nil
+}
+
+// CopyToBolt übernimmt alle Trainings mit ihrer vollständigen Historie aus der Log-Datei in die Datenbank
+func CopyToBolt(path string, db *bbolt.DB) (count int, err error) {
+	repository, err := CreateFileRepository(path)
+	if err != nil {
+		return 0, err
+	}
+	defer repository.Close()
+	if err = storage.CreateBuckets(db, string(trainingsBucket), string(trainingBasesBucket), string(trainingChangesBucket)); err != nil {
+		return 0, err
+	}
+	source := repository.(*fileRepository)
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for id, s := range source.streams {
+			if s.base != nil {
+				if err := storage.PutJson(tx.Bucket(trainingBasesBucket), id[:], s.base); err != nil {
+					return err
+				}
+			}
+			if err := putTraining(tx, toTrainingRecord(source.values[id]), s.changes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(source.streams), err
+}
@@ -0,0 +1,76 @@
+package training
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyToBoltKeepsStateAndTimeline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trainings.data")
+	provider := sequenceProvider(createChallenges(4)...)
+
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "create repository failed")
+	training, _ := CreateTraining(provider)
+	for i := 0; i < 3; i++ {
+		_, _ = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+		_, _ = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+	}
+	_, err = repo.Save(context.Background(), training)
+	utils.AssertNoError(t, err, "save failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	db, err := storage.OpenBolt(filepath.Join(dir, "ceh.db"))
+	utils.AssertNoError(t, err, "open database failed")
+	defer db.Close()
+
+	count, err := CopyToBolt(path, db)
+	utils.AssertNoError(t, err, "copy failed")
+	utils.Assert(t, count == 1, "expected 1 copied training, got %d", count)
+
+	boltRepo, err := CreateBoltRepository(db)
+	utils.AssertNoError(t, err, "create bolt repository failed")
+	loaded, found := boltRepo.FindFirst(context.Background(), IdEquals(training.Id))
+	utils.Assert(t, found, "training not found in database")
+	assertSameState(t, training, loaded)
+
+	timeline, found := boltRepo.Timeline(context.Background(), training.Id)
+	utils.Assert(t, found, "timeline not found")
+	utils.Assert(t, len(timeline) == training.Version, "expected %d changes, got %d", training.Version, len(timeline))
+
+	_, _ = loaded.Next(context.Background(), loaded.CurrentChallenge.Answer, provider)
+	_, err = boltRepo.Save(context.Background(), loaded)
+	utils.AssertNoError(t, err, "save to database failed")
+	reopened, err := CreateBoltRepository(db)
+	utils.AssertNoError(t, err, "reopen bolt repository failed")
+	reloaded, found := reopened.FindFirst(context.Background(), IdEquals(training.Id))
+	utils.Assert(t, found, "training not found after reopen")
+	assertSameState(t, loaded, reloaded)
+}
+
+func TestBoltRepositoryDeletesTraining(t *testing.T) {
+	db, err := storage.OpenBolt(filepath.Join(t.TempDir(), "ceh.db"))
+	utils.AssertNoError(t, err, "open database failed")
+	defer db.Close()
+
+	provider := sequenceProvider(createChallenges(2)...)
+	repo, err := CreateBoltRepository(db)
+	utils.AssertNoError(t, err, "create bolt repository failed")
+	training, _ := CreateTraining(provider)
+	_, _ = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+	_, err = repo.Save(context.Background(), training)
+	utils.AssertNoError(t, err, "save failed")
+	utils.AssertNoError(t, repo.Delete(context.Background(), training), "delete failed")
+
+	reopened, err := CreateBoltRepository(db)
+	utils.AssertNoError(t, err, "reopen bolt repository failed")
+	_, found := reopened.FindFirst(context.Background(), IdEquals(training.Id))
+	utils.Assert(t, !found, "deleted training found after reopen")
+	_, found = reopened.Timeline(context.Background(), training.Id)
+	utils.Assert(t, !found, "timeline of deleted training found after reopen")
+}
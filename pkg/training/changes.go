@@ -0,0 +1,216 @@
+package training
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ohrenpiraten/go-collections/collections"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"time"
+)
+
+type ChangeType string
+
+const (
+	TrainingCreated   ChangeType = "training.created"
+	AnswerGiven       ChangeType = "answer.given"
+	ChallengeAdvanced ChangeType = "challenge.advanced"
+	ChallengeReset    ChangeType = "challenge.reset"
+	ChallengeAdded    ChangeType = "challenge.added"
+	ChallengeSelected ChangeType = "challenge.selected"
+	AnswerKeyChanged  ChangeType = "answer-key.changed"
+	FilterWidened     ChangeType = "filter.widened"
+	ChallengeRecycled ChangeType = "challenge.recycled"
+	TrainingCompleted ChangeType = "training.completed"
+	TrainingPaused    ChangeType = "training.paused"
+	TrainingResumed   ChangeType = "training.resumed"
+	TrainingReset     ChangeType = "training.reset"
+	TrainingArchived  ChangeType = "training.archived"
+	ChallengeSkipped  ChangeType = "challenge.skipped"
+	BookmarkAdded     ChangeType = "bookmark.added"
+	BookmarkRemoved   ChangeType = "bookmark.removed"
+)
+
+// Change ist ein Event im Lebenszyklus eines Trainings. Der Zustand eines Trainings ergibt sich
+// vollständig aus der Abfolge seiner Changes, daher enthalten diese alle berechneten Werte
+// (z.B. Fälligkeiten), die beim erneuten Anwenden nicht neu bestimmt werden dürfen.
+type Change struct {
+	Type        ChangeType      `json:"type"`
+	Version     int             `json:"version"`
+	Timestamp   time.Time       `json:"timestamp"`
+	ChallengeId uuid.UUID       `json:"challengeId"`
+	AnswerIds   []uuid.UUID     `json:"answerIds,omitempty"`
+	Passed      bool            `json:"passed,omitempty"`
+	Level       int             `json:"level,omitempty"`
+	Due         time.Time       `json:"due,omitempty"`
+	Done        bool            `json:"done,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Policy      ExhaustedPolicy `json:"policy,omitempty"`
+	Owner       string          `json:"owner,omitempty"`
+}
+
+// record wendet einen neuen Change auf das Training an und merkt ihn zum Speichern vor
+func (training *Training) record(change Change) error {
+	change.Version = training.Version + 1
+	if change.Timestamp.IsZero() {
+		change.Timestamp = time.Now()
+	}
+	if err := training.apply(change); err != nil {
+		return err
+	}
+	training.changes = append(training.changes, change)
+	return nil
+}
+
+func (training *Training) apply(change Change) error {
+	switch change.Type {
+	case TrainingCreated:
+		training.CurrentChallenge = createTrainingChallenge(change.ChallengeId, change.AnswerIds, change.Timestamp)
+		training.currentChallengeFailed = false
+		training.Created = change.Timestamp
+		training.Updated = change.Timestamp
+		training.Challenges = []*TrainingChallenge{training.CurrentChallenge}
+		training.Tags = change.Tags
+		training.OnExhausted = change.Policy
+		training.Owner = change.Owner
+		training.Stats = &Stats{
+			totalChallenges:          1,
+			passedChallenges:         0,
+			failedChallenges:         0,
+			currentChallengeAttempts: 0,
+		}
+	case AnswerGiven:
+		if change.Passed {
+			training.Stats.pass(training.currentChallengeFailed)
+		} else {
+			training.Stats.fail(training.currentChallengeFailed)
+			training.currentChallengeFailed = true
+			training.CurrentChallenge.Failed = true
+		}
+	case ChallengeAdvanced:
+		if challenge, found := training.findChallenge(change.ChallengeId); !found {
+			return unknownChallenge(training, change)
+		} else {
+			challenge.proceed(change.Level, change.Due, change.Done)
+		}
+	case ChallengeReset:
+		if challenge, found := training.findChallenge(change.ChallengeId); !found {
+			return unknownChallenge(training, change)
+		} else {
+			challenge.reset(change.Due)
+		}
+	case ChallengeAdded:
+		challenge := createTrainingChallenge(change.ChallengeId, change.AnswerIds, change.Timestamp)
+		training.Challenges = append(training.Challenges, challenge)
+		training.setCurrentChallenge(challenge)
+		training.Updated = change.Timestamp
+	case ChallengeSelected:
+		if challenge, found := training.findChallenge(change.ChallengeId); !found {
+			return unknownChallenge(training, change)
+		} else {
+			training.track(training.CurrentChallenge)
+			training.setCurrentChallenge(challenge)
+			training.Updated = change.Timestamp
+		}
+	case FilterWidened:
+		training.Tags = nil
+	case ChallengeRecycled:
+		if challenge, found := training.findChallenge(change.ChallengeId); !found {
+			return unknownChallenge(training, change)
+		} else {
+			challenge.recycle(change.Timestamp)
+			training.setCurrentChallenge(challenge)
+			training.Updated = change.Timestamp
+		}
+	case TrainingCompleted:
+		training.Completed = true
+		training.Updated = change.Timestamp
+	case TrainingPaused:
+		training.Paused = change.Timestamp
+		training.Updated = change.Timestamp
+	case TrainingResumed:
+		// die Fälligkeiten werden um die Dauer der Pause verschoben
+		pause := change.Timestamp.Sub(training.Paused)
+		for _, challenge := range collections.Filter(training.AllChallenges(), predicates.Not(Done())) {
+			challenge.Timestamp = challenge.Timestamp.Add(pause)
+		}
+		training.Paused = time.Time{}
+		training.Updated = change.Timestamp
+	case TrainingReset:
+		// in einer Pause sind alle Challenges mit dem Fortsetzen fällig
+		due := change.Timestamp
+		if training.IsPaused() {
+			due = training.Paused
+		}
+		for _, challenge := range training.AllChallenges() {
+			challenge.recycle(due)
+		}
+		training.currentChallengeFailed = false
+		training.Stats.currentChallengeAttempts = 0
+		training.Completed = false
+		training.Updated = change.Timestamp
+	case TrainingArchived:
+		training.Archived = true
+		training.Updated = change.Timestamp
+	case ChallengeSkipped:
+		if challenge, found := training.findChallenge(change.ChallengeId); !found {
+			return unknownChallenge(training, change)
+		} else {
+			training.track(challenge)
+			challenge.Timestamp = change.Due
+			training.Stats.skip()
+			training.Updated = change.Timestamp
+		}
+	case BookmarkAdded, BookmarkRemoved:
+		if challenge, found := training.findChallenge(change.ChallengeId); !found {
+			return unknownChallenge(training, change)
+		} else {
+			training.track(challenge)
+			challenge.Bookmarked = change.Type == BookmarkAdded
+		}
+	case AnswerKeyChanged:
+		if training.CurrentChallenge.Id == change.ChallengeId {
+			training.CurrentChallenge.Answer = change.AnswerIds
+		}
+		for _, tq := range training.Challenges {
+			if tq.Id == change.ChallengeId {
+				tq.Answer = change.AnswerIds
+				tq.reset(change.Due)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown change type %s in training %s", change.Type, training.Id)
+	}
+	training.Version = change.Version
+	return nil
+}
+
+func unknownChallenge(training *Training, change Change) error {
+	return fmt.Errorf("unable to apply %s: challenge %s not found in training %s", change.Type, change.ChallengeId, training.Id)
+}
+
+// replay baut ein Training aus einem optionalen Snapshot und den folgenden Changes auf.
+// Changes, die bereits im Snapshot enthalten sind, werden übersprungen.
+func replay(id uuid.UUID, base *trainingRecord, changes []Change) (training *Training, err error) {
+	if base != nil {
+		training = base.toDomain()
+	} else {
+		training = (&Training{Id: id}).init()
+	}
+	for _, change := range changes {
+		if change.Version <= training.Version {
+			continue
+		}
+		if err = training.apply(change); err != nil {
+			return training, err
+		}
+	}
+	return training, nil
+}
+
+func (training *Training) uncommittedChanges() []Change {
+	return training.changes
+}
+
+func (training *Training) commitChanges() {
+	training.changes = training.changes[:0]
+}
@@ -0,0 +1,170 @@
+package training
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func sequenceProvider(challenges ...Challenge) ChallengeProvider {
+	index := 0
+	return func(query ChallengeQuery) (Challenge, error) {
+		challenge := challenges[index%len(challenges)]
+		index++
+		return challenge, nil
+	}
+}
+
+func createChallenges(count int) (challenges []Challenge) {
+	for i := 0; i < count; i++ {
+		challenges = append(challenges, Challenge{Id: uuid.New(), Answer: []uuid.UUID{uuid.New()}})
+	}
+	return challenges
+}
+
+func assertSameState(t *testing.T, expected *Training, actual *Training) {
+	utils.Assert(t, expected.Id == actual.Id, "id differs")
+	utils.Assert(t, expected.Version == actual.Version, "version differs %d != %d", expected.Version, actual.Version)
+	utils.Assert(t, expected.CurrentChallenge.Id == actual.CurrentChallenge.Id, "current challenge differs")
+	utils.Assert(t, expected.CurrentChallenge.Level == actual.CurrentChallenge.Level, "current level differs")
+	utils.Assert(t, expected.currentChallengeFailed == actual.currentChallengeFailed, "failed flag differs")
+	utils.Assert(t, *expected.Stats == *actual.Stats, "stats differ %v != %v", *expected.Stats, *actual.Stats)
+	utils.Assert(t, len(expected.Challenges) == len(actual.Challenges), "challenge count differs")
+	for i := range expected.Challenges {
+		e, a := expected.Challenges[i], actual.Challenges[i]
+		utils.Assert(t, e.Id == a.Id && e.Level == a.Level && e.Count == a.Count && e.Done == a.Done &&
+			e.Bookmarked == a.Bookmarked && e.Failed == a.Failed && e.Timestamp.Equal(a.Timestamp), "challenge %d differs", i)
+	}
+}
+
+func TestReplayRestoresState(t *testing.T) {
+	challenges := createChallenges(3)
+	provider := sequenceProvider(challenges...)
+
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create failed")
+	_, err = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+	utils.AssertNoError(t, err, "wrong answer failed")
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "correct answer failed")
+	_, err = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+	utils.AssertNoError(t, err, "wrong answer failed")
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+	utils.Assert(t, replayed.currentChallengeFailed, "failed flag not replayed")
+}
+
+func TestFileRepositoryReloadsChangesAndSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	challenges := createChallenges(5)
+	provider := sequenceProvider(challenges...)
+
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "create repository failed")
+	repo.(*fileRepository).snapshotInterval = 3
+
+	training, _ := CreateTraining(provider)
+	_, err = repo.Save(context.Background(), training)
+	utils.AssertNoError(t, err, "save failed")
+	createdAt := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		_, _ = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+		_, _ = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+		_, err = repo.Save(context.Background(), training)
+		utils.AssertNoError(t, err, "save failed")
+	}
+	utils.AssertNoError(t, repo.(*fileRepository).Compact(), "compaction failed")
+
+	reloaded, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "reload failed")
+	loaded, found := reloaded.FindFirst(context.Background(), IdEquals(training.Id))
+	utils.Assert(t, found, "training not found after reload")
+	assertSameState(t, training, loaded)
+
+	timeline, found := reloaded.Timeline(context.Background(), training.Id)
+	utils.Assert(t, found, "timeline not found")
+	utils.Assert(t, len(timeline) == training.Version, "expected %d changes, got %d", training.Version, len(timeline))
+
+	initial, found := reloaded.FindAt(context.Background(), training.Id, createdAt)
+	utils.Assert(t, found, "initial state not found")
+	utils.Assert(t, initial.Version == 1, "expected initial version 1, got %d", initial.Version)
+	utils.Assert(t, initial.CurrentChallenge.Id == challenges[0].Id, "wrong initial challenge")
+
+	_, found = reloaded.FindAt(context.Background(), training.Id, createdAt.Add(-time.Hour))
+	utils.Assert(t, !found, "training found before creation")
+}
+
+func TestCompactionSnapshotsOnlySavedChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	provider := sequenceProvider(createChallenges(5)...)
+
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "create repository failed")
+	repo.(*fileRepository).snapshotInterval = 2
+	training, _ := CreateTraining(provider)
+	for i := 0; i < 2; i++ {
+		_, _ = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+		_, err = repo.Save(context.Background(), training)
+		utils.AssertNoError(t, err, "save failed")
+	}
+	saved, _ := replay(training.Id, nil, mustTimeline(t, repo, training.Id))
+
+	// ungespeicherte Änderungen am Training dürfen nicht in den Snapshot der Kompaktierung gelangen
+	_, _ = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+	utils.AssertNoError(t, repo.(*fileRepository).Compact(), "compaction failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	reloaded, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "reload failed")
+	defer reloaded.Close()
+	loaded, found := reloaded.FindFirst(context.Background(), IdEquals(training.Id))
+	utils.Assert(t, found, "training not found after reload")
+	assertSameState(t, saved, loaded)
+}
+
+func mustTimeline(t *testing.T, repo Repository, id uuid.UUID) []Change {
+	timeline, found := repo.Timeline(context.Background(), id)
+	utils.Assert(t, found, "timeline of %s not found", id)
+	return timeline
+}
+
+func TestFileRepositoryDropsDeletedTrainingsOnCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	provider := sequenceProvider(createChallenges(3)...)
+
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "create repository failed")
+	kept, _ := CreateTraining(provider)
+	deleted, _ := CreateTraining(provider)
+	for _, training := range []*Training{kept, deleted} {
+		_, _ = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+		_, err = repo.Save(context.Background(), training)
+		utils.AssertNoError(t, err, "save failed")
+	}
+	utils.AssertNoError(t, repo.Delete(context.Background(), deleted), "delete failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	reloaded, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "reload failed")
+	_, found := reloaded.FindFirst(context.Background(), IdEquals(deleted.Id))
+	utils.Assert(t, !found, "deleted training found after reload")
+	_, found = reloaded.Timeline(context.Background(), deleted.Id)
+	utils.Assert(t, !found, "timeline of deleted training found after reload")
+
+	file := reloaded.(*fileRepository)
+	total, live := file.CompactionStats()
+	utils.Assert(t, live == kept.Version && total > live, "expected only the kept training to be live, got %d of %d", live, total)
+	utils.AssertNoError(t, file.Compact(), "compaction failed")
+	total, _ = file.CompactionStats()
+	utils.Assert(t, total == kept.Version, "expected %d records after compaction, got %d", kept.Version, total)
+	loaded, found := file.FindFirst(context.Background(), IdEquals(kept.Id))
+	utils.Assert(t, found, "kept training lost")
+	assertSameState(t, kept, loaded)
+}
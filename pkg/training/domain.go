@@ -1,7 +1,8 @@
 package training
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/mwildt/ceh-utils/pkg/events"
 	"github.com/mwildt/ceh-utils/pkg/utils"
@@ -11,18 +12,24 @@ import (
 	"time"
 )
 
+// alle Trainings teilen sich einen Logger, die Id wird als Feld mitgeschrieben (siehe logger)
+var domainLogger = utils.NewStdLogger("training")
+
 type Challenge struct {
 	Id     uuid.UUID
 	Answer []uuid.UUID
 }
 
 type TrainingChallenge struct {
-	Id        uuid.UUID
-	Answer    []uuid.UUID
-	Level     int
-	Timestamp time.Time
-	Done      bool
-	Count     int
+	Id         uuid.UUID
+	Answer     []uuid.UUID
+	Level      int
+	Timestamp  time.Time
+	Done       bool
+	Count      int
+	Bookmarked bool
+	// Failed bleibt gesetzt, bis die Challenge nach einem Fehler zurückgesetzt wurde
+	Failed bool
 }
 
 func TrainingChallengeIdEquals(id uuid.UUID) predicates.Predicate[*TrainingChallenge] {
@@ -31,7 +38,57 @@ func TrainingChallengeIdEquals(id uuid.UUID) predicates.Predicate[*TrainingChall
 	}
 }
 
-type ChallengeProvider func(excludeIds []uuid.UUID) (Challenge, error)
+// ChallengeQuery beschreibt, welche Fragen für die nächste Challenge in Frage kommen
+type ChallengeQuery struct {
+	ExcludeIds []uuid.UUID
+	Tags       []string
+	Owner      string
+}
+
+// ChallengeProvider liefert eine neue Challenge oder ErrPoolExhausted, wenn keine Frage mehr
+// zur Anfrage passt
+type ChallengeProvider func(query ChallengeQuery) (Challenge, error)
+
+var (
+	ErrPoolExhausted = utils.Unavailable("no question available")
+	ErrCompleted     = utils.Conflict("training is completed")
+	ErrNothingDue    = utils.Conflict("no other open challenge")
+	ErrArchived      = utils.Conflict("training is archived")
+	ErrPaused        = utils.Conflict("training is paused")
+	ErrNotPaused     = utils.Conflict("training is not paused")
+	ErrNoChallenge   = utils.Invalid("challenge is not part of the training")
+)
+
+// ExhaustedPolicy legt fest, wie ein Training fortgesetzt wird, wenn keine neue Frage mehr
+// verfügbar ist und keine Wiederholung ansteht. Offene Challenges werden in jedem Fall vorgezogen.
+type ExhaustedPolicy string
+
+const (
+	// CompleteOnExhausted schließt das Training ab
+	CompleteOnExhausted ExhaustedPolicy = "complete"
+	// RecycleOnExhausted stellt die am längsten abgeschlossene Challenge erneut
+	RecycleOnExhausted ExhaustedPolicy = "recycle"
+	// WidenOnExhausted verwirft den Tag-Filter und sucht unter allen Fragen weiter
+	WidenOnExhausted ExhaustedPolicy = "widen"
+)
+
+func ParseExhaustedPolicy(value string) (ExhaustedPolicy, error) {
+	switch policy := ExhaustedPolicy(value); policy {
+	case "":
+		return CompleteOnExhausted, nil
+	case CompleteOnExhausted, RecycleOnExhausted, WidenOnExhausted:
+		return policy, nil
+	default:
+		return policy, utils.Invalid("unknown policy %q, expected complete, recycle or widen", value)
+	}
+}
+
+// Settings sind die beim Anlegen gewählten Einstellungen eines Trainings
+type Settings struct {
+	Tags        []string
+	OnExhausted ExhaustedPolicy
+	Owner       string
+}
 
 func Initial() predicates.Predicate[*TrainingChallenge] {
 	return func(q *TrainingChallenge) bool {
@@ -51,45 +108,69 @@ func Done() predicates.Predicate[*TrainingChallenge] {
 	}
 }
 
+func Bookmarked() predicates.Predicate[*TrainingChallenge] {
+	return func(q *TrainingChallenge) bool {
+		return q.Bookmarked
+	}
+}
+
 func notPending(cutoff time.Time) predicates.Predicate[*TrainingChallenge] {
 	return func(q *TrainingChallenge) bool {
 		return q.Timestamp.Before(cutoff) && !q.Done
 	}
 }
 
-func (tc *TrainingChallenge) reset() {
-
+func (tc *TrainingChallenge) reset(due time.Time) {
 	tc.Count = tc.Count + 1
 	tc.Level = 0
-	tc.Timestamp = time.Now().Add(time.Minute * 10)
+	tc.Timestamp = due
+	tc.Failed = false
 }
 
-func (tc *TrainingChallenge) proceed() {
+func (tc *TrainingChallenge) proceed(level int, due time.Time, done bool) {
 	tc.Count = tc.Count + 1
-	tc.Level = tc.Level + 1
-	switch tc.Level {
+	tc.Level = level
+	tc.Timestamp = due
+	tc.Done = done
+	tc.Failed = false
+}
+
+// recycle stellt eine abgeschlossene Challenge wieder von vorne
+func (tc *TrainingChallenge) recycle(now time.Time) {
+	tc.Level = 0
+	tc.Timestamp = now
+	tc.Done = false
+	tc.Failed = false
+}
+
+func resetDue(now time.Time) time.Time {
+	return now.Add(time.Minute * 10)
+}
+
+// eine übersprungene Challenge kommt nach kurzer Zeit wieder
+func skipDue(now time.Time) time.Time {
+	return now.Add(time.Minute * 5)
+}
+
+// liefert Level, Fälligkeit und Done-Status nach einer erfolgreich beantworteten Challenge
+func (tc *TrainingChallenge) nextLevel(now time.Time) (level int, due time.Time, done bool) {
+	level = tc.Level + 1
+	switch level {
 	case 1:
-		{
-			tc.Timestamp = time.Now().Add(time.Minute * 10)
-		}
+		return level, now.Add(time.Minute * 10), false
 	case 2:
-		{
-			tc.Timestamp = time.Now().Add(time.Hour * 6)
-		}
+		return level, now.Add(time.Hour * 6), false
 	case 3:
-		{
-			tc.Timestamp = time.Now().Add(time.Hour * 24)
-		}
+		return level, now.Add(time.Hour * 24), false
 	case 4:
-		{
-			tc.Timestamp = time.Now()
-			tc.Done = true
-		}
+		return level, now, true
+	default:
+		return level, tc.Timestamp, tc.Done
 	}
 }
 
-func createTrainingChallenge(challenge Challenge) *TrainingChallenge {
-	return &TrainingChallenge{challenge.Id, challenge.Answer, 0, time.Now(), false, 0}
+func createTrainingChallenge(id uuid.UUID, answer []uuid.UUID, timestamp time.Time) *TrainingChallenge {
+	return &TrainingChallenge{id, answer, 0, timestamp, false, 0, false, false}
 }
 
 func getChallengeId(c *TrainingChallenge) uuid.UUID {
@@ -105,23 +186,36 @@ func createdEvent(id uuid.UUID) event {
 	return event{"training.created", CreatedEvent{id}}
 }
 
+func deletedEvent(id uuid.UUID) event {
+	return event{"training.deleted", LifecycleEvent{id}}
+}
+
 type Stats struct {
 	totalChallenges          int
 	passedChallenges         int
 	failedChallenges         int
+	skippedChallenges        int
 	currentChallengeAttempts int
 }
 
-func (stats *Stats) pass() {
+// failed gibt an, ob die Challenge schon einmal falsch beantwortet wurde, auch bevor zu einer
+// anderen Challenge gesprungen wurde
+func (stats *Stats) pass(failed bool) {
 	stats.totalChallenges++
-	if stats.currentChallengeAttempts == 0 {
+	if !failed {
 		stats.passedChallenges++
 	}
 	stats.currentChallengeAttempts = 0
 }
 
-func (stats *Stats) fail() {
-	if stats.currentChallengeAttempts == 0 {
+// skip zählt eine übersprungene Challenge weder als bestanden noch als fehlgeschlagen
+func (stats *Stats) skip() {
+	stats.skippedChallenges++
+	stats.currentChallengeAttempts = 0
+}
+
+func (stats *Stats) fail(failed bool) {
+	if !failed {
 		stats.failedChallenges++
 	}
 	stats.currentChallengeAttempts++
@@ -134,93 +228,323 @@ type Training struct {
 	Updated                time.Time
 	Created                time.Time
 	events                 []event
+	changes                []Change
+	Version                int
 	Stats                  *Stats
 	Challenges             []*TrainingChallenge
-	logger                 utils.Logger
+	Tags                   []string
+	OnExhausted            ExhaustedPolicy
+	Completed              bool
+	// Paused ist der Beginn der laufenden Pause, solange das Training pausiert ist
+	Paused   time.Time
+	Archived bool
+	Owner    string
 }
 
 func CreateTraining(nextChallenge ChallengeProvider) (training *Training, err error) {
-	challenge, err := nextChallenge(make([]uuid.UUID, 0))
+	return CreateTrainingWithSettings(nextChallenge, Settings{OnExhausted: CompleteOnExhausted})
+}
+
+// CreateTrainingWithSettings legt ein Training an, dessen Fragen auf die Tags beschränkt sind.
+// Passt keine Frage zu den Tags, wird mit WidenOnExhausted gleich unter allen Fragen gesucht.
+func CreateTrainingWithSettings(nextChallenge ChallengeProvider, settings Settings) (training *Training, err error) {
+	widen := false
+	challenge, err := nextChallenge(ChallengeQuery{ExcludeIds: make([]uuid.UUID, 0), Tags: settings.Tags, Owner: settings.Owner})
+	if errors.Is(err, ErrPoolExhausted) && settings.OnExhausted == WidenOnExhausted && len(settings.Tags) > 0 {
+		widen = true
+		challenge, err = nextChallenge(ChallengeQuery{ExcludeIds: make([]uuid.UUID, 0), Owner: settings.Owner})
+	}
 	if err != nil {
 		return training, err
 	}
 	id := uuid.New()
-	return (&Training{
-		Id:                     id,
-		CurrentChallenge:       createTrainingChallenge(challenge),
-		currentChallengeFailed: false,
-		Updated:                time.Now(),
-		Created:                time.Now(),
-		Challenges:             make([]*TrainingChallenge, 0),
-		Stats: &Stats{
-			totalChallenges:          1,
-			passedChallenges:         0,
-			failedChallenges:         0,
-			currentChallengeAttempts: 0,
-		},
-	}).init(createdEvent(id)), nil
-}
-
-func (training *Training) Next(answerIds []uuid.UUID, nextChallenge ChallengeProvider) (success bool, err error) {
-
-	success = collections.MutualContainment(training.CurrentChallenge.Answer, answerIds)
+	training = (&Training{Id: id}).init(createdEvent(id))
+	err = training.record(Change{Type: TrainingCreated, ChallengeId: challenge.Id, AnswerIds: challenge.Answer, Tags: settings.Tags, Policy: settings.OnExhausted, Owner: settings.Owner})
+	if err == nil && widen {
+		err = training.record(Change{Type: FilterWidened})
+	}
+	return training, err
+}
+
+// Next beantwortet die aktuelle Challenge. Die Log-Ausgaben enthalten die Felder aus ctx, etwa die
+// Request-Id.
+func (training *Training) Next(ctx context.Context, answerIds []uuid.UUID, nextChallenge ChallengeProvider) (success bool, err error) {
+	if len(answerIds) == 0 {
+		return success, utils.Invalid("answer must not be empty")
+	} else if err = training.answerable(); err != nil {
+		return success, err
+	}
 
+	current := training.CurrentChallenge
+	success = collections.MutualContainment(current.Answer, answerIds)
+
+	if err = training.record(Change{Type: AnswerGiven, ChallengeId: current.Id, AnswerIds: answerIds, Passed: success}); err != nil {
+		return success, err
+	}
 	training.events = append(training.events, event{"training.updated", UpdatedEvent{
 		TrainingId:  training.Id,
-		ChallengeId: training.CurrentChallenge.Id,
+		ChallengeId: current.Id,
 		AnswerIds:   answerIds,
 		Passed:      success,
 	}})
 
-	if success {
-		training.Stats.pass()
+	if !success {
+		return success, nil
+	}
 
-		if training.currentChallengeFailed {
-			training.logger.Info("reset Challenge {id: %s, level: %d}", training.CurrentChallenge.Id, training.CurrentChallenge.Level)
-			training.CurrentChallenge.reset()
-		} else {
-			training.logger.Info("proceed Challenge {id: %s, level: %d}", training.CurrentChallenge.Id, training.CurrentChallenge.Level)
-			training.CurrentChallenge.proceed()
+	logger := training.logger(ctx)
+	now := time.Now()
+	if training.currentChallengeFailed {
+		logger.Info("reset Challenge {id: %s, level: %d}", current.Id, current.Level)
+		err = training.record(Change{Type: ChallengeReset, ChallengeId: current.Id, Due: resetDue(now)})
+	} else {
+		logger.Info("proceed Challenge {id: %s, level: %d}", current.Id, current.Level)
+		level, due, done := current.nextLevel(now)
+		err = training.record(Change{Type: ChallengeAdvanced, ChallengeId: current.Id, Level: level, Due: due, Done: done})
+	}
+	if err != nil {
+		return success, err
+	}
+	return success, training.proceed(logger, nextChallenge)
+}
+
+// Skip stellt die aktuelle Challenge kurz zurück, etwa um die Antwort nachzuschlagen. Level und
+// Fehlerstatus bleiben an der Challenge erhalten: eine falsch beantwortete Challenge wird auch
+// nach dem Skip zurückgesetzt. In den Stats zählt sie als übersprungen.
+func (training *Training) Skip(ctx context.Context, nextChallenge ChallengeProvider) error {
+	if err := training.answerable(); err != nil {
+		return err
+	}
+	logger := training.logger(ctx)
+	current := training.CurrentChallenge
+	logger.Info("skip Challenge {id: %s, level: %d}", current.Id, current.Level)
+	if err := training.record(Change{Type: ChallengeSkipped, ChallengeId: current.Id, Due: skipDue(time.Now())}); err != nil {
+		return err
+	}
+	return training.proceed(logger, nextChallenge)
+}
+
+// proceed stellt die nächste Challenge: eine fällige Wiederholung oder eine neue vom Provider
+func (training *Training) proceed(logger utils.Logger, nextChallenge ChallengeProvider) error {
+	if candidate, found := training.findRetryCandidate(); found {
+		logger.Info("found retry candidate question %s %d", candidate.Id, candidate.Level)
+		return training.record(Change{Type: ChallengeSelected, ChallengeId: candidate.Id})
+	}
+	return training.selectNext(logger, nextChallenge)
+}
+
+// Bookmark merkt eine Challenge zum späteren Nachschlagen vor oder entfernt die Markierung
+func (training *Training) Bookmark(challengeId uuid.UUID, bookmarked bool) error {
+	if training.Archived {
+		return ErrArchived
+	}
+	challenge, found := training.findChallenge(challengeId)
+	if !found {
+		return ErrNoChallenge
+	} else if challenge.Bookmarked == bookmarked {
+		return nil
+	} else if bookmarked {
+		return training.record(Change{Type: BookmarkAdded, ChallengeId: challengeId})
+	}
+	return training.record(Change{Type: BookmarkRemoved, ChallengeId: challengeId})
+}
+
+// Bookmarks liefert die vorgemerkten Challenges
+func (training *Training) Bookmarks() []*TrainingChallenge {
+	return collections.Filter(training.AllChallenges(), Bookmarked())
+}
+
+// selectNext holt eine neue Challenge vom Provider. Ist der Pool erschöpft, entscheidet
+// OnExhausted, wie es weitergeht.
+func (training *Training) selectNext(logger utils.Logger, nextChallenge ChallengeProvider) error {
+	challenge, err := nextChallenge(training.query())
+	if errors.Is(err, ErrPoolExhausted) && training.OnExhausted == WidenOnExhausted && len(training.Tags) > 0 {
+		logger.Info("question pool exhausted, widen tag filter %v", training.Tags)
+		if err = training.record(Change{Type: FilterWidened}); err != nil {
+			return err
 		}
+		challenge, err = nextChallenge(training.query())
+	}
+	if errors.Is(err, ErrPoolExhausted) {
+		return training.exhausted(logger)
+	} else if err != nil {
+		return err
+	}
+	logger.Info("no retry challenge found, got new one from provider %s", challenge.Id)
+	return training.record(Change{Type: ChallengeAdded, ChallengeId: challenge.Id, AnswerIds: challenge.Answer})
+}
 
-		if candidate, found := training.findRetryCandidate(); found {
-			training.logger.Info("found retry candidate question %s %d", candidate.Id, candidate.Level)
-			training.setCurrentChallenge(candidate)
-		} else {
-			challenge, err := nextChallenge(training.getExcludeIds())
-			training.logger.Info("no retry challenge found, got new one from provider %s", challenge.Id)
-			if err != nil {
-				return success, err
-			}
-			trainingChallenge := createTrainingChallenge(challenge)
-			training.Challenges = append(training.Challenges, trainingChallenge)
-			training.setCurrentChallenge(trainingChallenge)
+// exhausted zieht die nächste offene Challenge vor, stellt mit RecycleOnExhausted eine
+// abgeschlossene Challenge erneut oder schließt das Training ab
+func (training *Training) exhausted(logger utils.Logger) error {
+	if open, found := training.firstChallenge(predicates.Not(Done())); found {
+		logger.Info("question pool exhausted, select open challenge %s ahead of time", open.Id)
+		return training.record(Change{Type: ChallengeSelected, ChallengeId: open.Id})
+	} else if done, found := training.firstChallenge(Done()); found && training.OnExhausted == RecycleOnExhausted {
+		logger.Info("question pool exhausted, recycle challenge %s", done.Id)
+		return training.record(Change{Type: ChallengeRecycled, ChallengeId: done.Id})
+	}
+	logger.Info("question pool exhausted, training completed")
+	return training.record(Change{Type: TrainingCompleted})
+}
+
+// DueQueue liefert die offenen Challenges in der Reihenfolge ihrer Fälligkeit, die aktuelle
+// Challenge eingeschlossen
+func (training *Training) DueQueue() []*TrainingChallenge {
+	if training.Completed || training.Archived {
+		return make([]*TrainingChallenge, 0)
+	}
+	queue := collections.Filter(training.AllChallenges(), predicates.Not(Done()))
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].Timestamp.Before(queue[j].Timestamp)
+	})
+	return queue
+}
+
+// SkipToNextDue stellt die als nächste fällige Challenge vorzeitig, statt auf die Fälligkeit zu
+// warten. Die aktuelle Challenge bleibt offen und behält ihren Fehlerstatus.
+func (training *Training) SkipToNextDue(ctx context.Context) error {
+	if err := training.answerable(); err != nil {
+		return err
+	}
+	for _, challenge := range training.DueQueue() {
+		if challenge.Id != training.CurrentChallenge.Id {
+			training.logger(ctx).Info("skip to challenge %s due %s", challenge.Id, challenge.Timestamp)
+			return training.record(Change{Type: ChallengeSelected, ChallengeId: challenge.Id})
 		}
-		training.Updated = time.Now()
-		return success, err
-	} else {
-		training.currentChallengeFailed = true
-		training.Stats.fail()
-		return success, nil
 	}
+	return ErrNothingDue
+}
+
+// answerable prüft, ob das Training Antworten annimmt
+func (training *Training) answerable() error {
+	if training.Archived {
+		return ErrArchived
+	} else if training.Completed {
+		return ErrCompleted
+	} else if training.IsPaused() {
+		return ErrPaused
+	}
+	return nil
+}
+
+func (training *Training) IsPaused() bool {
+	return !training.Paused.IsZero()
+}
+
+// Pause hält das Training an. Bis zum Fortsetzen werden keine Antworten angenommen und die
+// Fälligkeiten der Challenges laufen nicht weiter.
+func (training *Training) Pause() error {
+	if err := training.answerable(); err != nil {
+		return err
+	}
+	return training.lifecycle(TrainingPaused)
+}
+
+// Resume setzt ein pausiertes Training fort, die Fälligkeiten verschieben sich um die Pause
+func (training *Training) Resume() error {
+	if training.Archived {
+		return ErrArchived
+	} else if !training.IsPaused() {
+		return ErrNotPaused
+	}
+	return training.lifecycle(TrainingResumed)
+}
+
+// Reset stellt alle Challenges zurück auf Level 0, ein abgeschlossenes Training wird wieder
+// aufgenommen. Die Stats bleiben erhalten.
+func (training *Training) Reset() error {
+	if training.Archived {
+		return ErrArchived
+	}
+	return training.lifecycle(TrainingReset)
+}
+
+// Archive legt das Training ab, danach kann es nur noch gelesen werden
+func (training *Training) Archive() error {
+	if training.Archived {
+		return ErrArchived
+	}
+	return training.lifecycle(TrainingArchived)
+}
+
+// lifecycle speichert eine Änderung des Lebenszyklus und meldet sie als gleichnamiges Event
+func (training *Training) lifecycle(changeType ChangeType) error {
+	if err := training.record(Change{Type: changeType}); err != nil {
+		return err
+	}
+	training.events = append(training.events, event{string(changeType), LifecycleEvent{training.Id}})
+	return nil
+}
+
+// firstChallenge liefert die am frühesten fällige Challenge, auf die predicate zutrifft
+func (training *Training) firstChallenge(predicate predicates.Predicate[*TrainingChallenge]) (challenge *TrainingChallenge, found bool) {
+	candidates := collections.Filter(training.AllChallenges(), predicate)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Timestamp.Before(candidates[j].Timestamp)
+	})
+	if len(candidates) > 0 {
+		return candidates[0], true
+	}
+	return challenge, false
+}
+
+// AllChallenges enthält auch die erste Challenge migrierter Trainings, solange sie die aktuelle ist
+func (training *Training) AllChallenges() []*TrainingChallenge {
+	if training.CurrentChallenge == nil || collections.AnyMatch(training.Challenges, TrainingChallengeIdEquals(training.CurrentChallenge.Id)) {
+		return training.Challenges
+	}
+	return append([]*TrainingChallenge{training.CurrentChallenge}, training.Challenges...)
+}
+
+func (training *Training) query() ChallengeQuery {
+	return ChallengeQuery{ExcludeIds: training.getExcludeIds(), Tags: training.Tags, Owner: training.Owner}
 }
 
 func (training *Training) getExcludeIds() []uuid.UUID {
-	return collections.Map(training.Challenges, getChallengeId)
+	return collections.Map(training.AllChallenges(), getChallengeId)
 }
 
 func (training *Training) findRetryCandidate() (candidate *TrainingChallenge, found bool) {
 	return filterCandidates(training.Challenges)
 }
 
+func (training *Training) findChallenge(id uuid.UUID) (challenge *TrainingChallenge, found bool) {
+	if training.CurrentChallenge != nil && training.CurrentChallenge.Id == id {
+		return training.CurrentChallenge, true
+	}
+	return collections.First(training.Challenges, TrainingChallengeIdEquals(id))
+}
+
+// track übernimmt die erste Challenge in die Liste. Seit TrainingCreated sie selbst aufnimmt,
+// fehlt sie nur noch in migrierten Trainings und ginge dort mit der nächsten verloren.
+func (training *Training) track(challenge *TrainingChallenge) {
+	if !collections.AnyMatch(training.Challenges, TrainingChallengeIdEquals(challenge.Id)) {
+		training.Challenges = append(training.Challenges, challenge)
+	}
+}
+
+// logger schreibt die Felder aus ctx, etwa die Request-Id, und die Id des Trainings mit
+func (training *Training) logger(ctx context.Context) utils.Logger {
+	return domainLogger.WithContext(utils.WithLogField(ctx, utils.LogFieldTrainingId, training.Id))
+}
+
 func (training *Training) setCurrentChallenge(candidate *TrainingChallenge) {
 	training.CurrentChallenge = candidate
-	training.currentChallengeFailed = false
+	training.currentChallengeFailed = candidate.Failed
 }
 
 func (training *Training) init(events ...event) *Training {
-	training.logger = utils.NewStdLogger(fmt.Sprintf("training-%s", training.Id.String()))
 	training.events = append([]event{}, events...)
+	training.changes = make([]Change, 0)
+	// nach dem Laden eines Snapshots zeigt die aktuelle Challenge wieder auf das Element der Liste
+	if training.CurrentChallenge != nil {
+		if challenge, found := collections.First(training.Challenges, TrainingChallengeIdEquals(training.CurrentChallenge.Id)); found {
+			training.CurrentChallenge = challenge
+		}
+		// ältere Snapshots kennen den Fehlerstatus nur für die aktuelle Challenge
+		training.CurrentChallenge.Failed = training.CurrentChallenge.Failed || training.currentChallengeFailed
+	}
 	return training
 }
 
@@ -232,16 +556,8 @@ func (training *Training) emitEvents() {
 }
 
 func (training *Training) updateChallengeAnswer(challengeId uuid.UUID, answerId []uuid.UUID) {
-	training.logger.Info("updateChallengeAnswer with id challenge Id %s to %s", challengeId, answerId)
-	if training.CurrentChallenge.Id == challengeId {
-		training.CurrentChallenge.Answer = answerId
-	}
-	for _, tq := range training.Challenges {
-		if tq.Id == challengeId {
-			tq.Answer = answerId
-			tq.reset()
-		}
-	}
+	training.logger(context.Background()).Info("updateChallengeAnswer with id challenge Id %s to %s", challengeId, answerId)
+	_ = training.record(Change{Type: AnswerKeyChanged, ChallengeId: challengeId, AnswerIds: answerId, Due: resetDue(time.Now())})
 }
 
 func (training *Training) GetChallengeCount(predicate predicates.Predicate[*TrainingChallenge]) int {
@@ -250,7 +566,7 @@ func (training *Training) GetChallengeCount(predicate predicates.Predicate[*Trai
 
 func ContainsChallenge(challengeId uuid.UUID) predicates.Predicate[*Training] {
 	return func(q *Training) bool {
-		return q.CurrentChallenge.Id == challengeId || collections.AnyMatch(q.Challenges, TrainingChallengeIdEquals(challengeId))
+		return q.CurrentChallenge != nil && q.CurrentChallenge.Id == challengeId || collections.AnyMatch(q.Challenges, TrainingChallengeIdEquals(challengeId))
 	}
 }
 
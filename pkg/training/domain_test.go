@@ -1,8 +1,14 @@
 package training
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/collections"
+	"strings"
 	"testing"
 	"time"
 )
@@ -121,3 +127,317 @@ func TestDomainFindCandidateByTime(t *testing.T) {
 	utils.Assert(t, found, "found")
 	utils.Assert(t, candidate == candidates[2], "wrong found")
 }
+
+func TestNextRejectsEmptyAnswer(t *testing.T) {
+	provider := sequenceProvider(createChallenges(2)...)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+
+	_, err = training.Next(context.Background(), nil, provider)
+	utils.Assert(t, errors.Is(err, utils.ErrInvalid), "expected validation error, got %v", err)
+	utils.Assert(t, training.Version == 1, "empty answer must not be recorded, version %d", training.Version)
+}
+
+// poolProvider liefert die Challenges des Pools in Reihenfolge und beachtet dabei ExcludeIds und Tags
+func poolProvider(challenges []Challenge, tags map[uuid.UUID][]string) ChallengeProvider {
+	return func(query ChallengeQuery) (Challenge, error) {
+		for _, challenge := range challenges {
+			if !collections.Contains(query.ExcludeIds, challenge.Id) && (len(query.Tags) == 0 || collections.AnyMatch(tags[challenge.Id], func(tag string) bool { return collections.Contains(query.Tags, tag) })) {
+				return challenge, nil
+			}
+		}
+		return Challenge{}, ErrPoolExhausted
+	}
+}
+
+func answerUntilDone(t *testing.T, training *Training, challenge Challenge, provider ChallengeProvider) {
+	for i := 0; i < 4; i++ {
+		utils.Assert(t, training.CurrentChallenge.Id == challenge.Id, "expected challenge %s, got %s", challenge.Id, training.CurrentChallenge.Id)
+		_, err := training.Next(context.Background(), challenge.Answer, provider)
+		utils.AssertNoError(t, err, "answer %d failed", i)
+	}
+}
+
+func TestExhaustedPoolCompletesTraining(t *testing.T) {
+	challenges := createChallenges(1)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+
+	answerUntilDone(t, training, challenges[0], provider)
+	utils.Assert(t, training.Completed, "training not completed")
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.Assert(t, errors.Is(err, ErrCompleted), "expected completed error, got %v", err)
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	utils.Assert(t, replayed.Completed, "completed state not replayed")
+}
+
+func TestExhaustedPoolRecyclesDoneChallenge(t *testing.T) {
+	challenges := createChallenges(1)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTrainingWithSettings(provider, Settings{OnExhausted: RecycleOnExhausted})
+	utils.AssertNoError(t, err, "create training failed")
+
+	answerUntilDone(t, training, challenges[0], provider)
+	utils.Assert(t, !training.Completed, "recycling training must not complete")
+	utils.Assert(t, training.CurrentChallenge.Id == challenges[0].Id, "done challenge not recycled")
+	utils.Assert(t, training.CurrentChallenge.Level == 0 && !training.CurrentChallenge.Done, "recycled challenge not reset")
+}
+
+func TestExhaustedTagFilterIsWidened(t *testing.T) {
+	challenges := createChallenges(2)
+	provider := poolProvider(challenges, map[uuid.UUID][]string{challenges[0].Id: {"network"}})
+	training, err := CreateTrainingWithSettings(provider, Settings{Tags: []string{"network"}, OnExhausted: WidenOnExhausted})
+	utils.AssertNoError(t, err, "create training failed")
+
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer failed")
+	utils.Assert(t, len(training.Tags) == 0, "tag filter not widened: %v", training.Tags)
+	utils.Assert(t, training.CurrentChallenge.Id == challenges[1].Id, "expected untagged challenge after widening")
+}
+
+func TestCreateTrainingWithUnknownTags(t *testing.T) {
+	provider := poolProvider(createChallenges(1), nil)
+	_, err := CreateTrainingWithSettings(provider, Settings{Tags: []string{"unknown"}, OnExhausted: CompleteOnExhausted})
+	utils.Assert(t, errors.Is(err, ErrPoolExhausted), "expected exhausted pool, got %v", err)
+
+	training, err := CreateTrainingWithSettings(provider, Settings{Tags: []string{"unknown"}, OnExhausted: WidenOnExhausted})
+	utils.AssertNoError(t, err, "create widened training failed")
+	utils.Assert(t, len(training.Tags) == 0, "tag filter not widened: %v", training.Tags)
+}
+
+func TestFirstChallengeIsTracked(t *testing.T) {
+	challenges := createChallenges(2)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+	utils.Assert(t, len(training.Challenges) == 1 && training.Challenges[0] == training.CurrentChallenge, "first challenge not tracked: %v", training.Challenges)
+
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer first failed")
+	utils.Assert(t, ContainsChallenge(challenges[0].Id)(training), "first challenge lost after the next challenge")
+	utils.Assert(t, training.Challenges[0].Id == challenges[0].Id && training.Challenges[0].Level == 1, "expected first challenge on level 1, got %+v", training.Challenges[0])
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+}
+
+func TestDueQueueAndSkipToNextDue(t *testing.T) {
+	challenges := createChallenges(2)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+	err = training.SkipToNextDue(context.Background())
+	utils.Assert(t, errors.Is(err, ErrNothingDue), "expected nothing due with a single challenge, got %v", err)
+
+	// challenges[1] steigt auf Level 1 und ist in 10 Minuten fällig, danach wird eine neue
+	// Challenge gestellt, die sofort fällig ist
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer first failed")
+	_, err = training.Next(context.Background(), challenges[1].Answer, provider)
+	utils.AssertNoError(t, err, "answer second failed")
+	queue := training.DueQueue()
+	utils.Assert(t, len(queue) == 2 && queue[0].Id == training.CurrentChallenge.Id && queue[1].Id == challenges[1].Id, "wrong due queue %v", queue)
+
+	utils.AssertNoError(t, training.SkipToNextDue(context.Background()), "skip failed")
+	utils.Assert(t, training.CurrentChallenge.Id == challenges[1].Id, "expected skip to challenge due later, got %s", training.CurrentChallenge.Id)
+	utils.Assert(t, len(training.DueQueue()) == 2, "skipped challenge must stay open")
+
+	// die falsch beantwortete Challenge wird nach der Rückkehr zurückgesetzt statt aufzusteigen
+	failed := training.CurrentChallenge
+	_, err = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+	utils.AssertNoError(t, err, "wrong answer failed")
+	utils.AssertNoError(t, training.SkipToNextDue(context.Background()), "skip after failure failed")
+	utils.Assert(t, training.CurrentChallenge.Id != failed.Id && !training.currentChallengeFailed, "failure must not carry over to challenge %s", training.CurrentChallenge.Id)
+	utils.AssertNoError(t, training.SkipToNextDue(context.Background()), "skip back failed")
+	utils.Assert(t, training.CurrentChallenge.Id == failed.Id && training.currentChallengeFailed, "failure of challenge %s lost", failed.Id)
+	_, err = training.Next(context.Background(), failed.Answer, provider)
+	utils.AssertNoError(t, err, "correct answer failed")
+	utils.Assert(t, failed.Level == 0 && !failed.Failed, "expected reset to level 0, got level %d", failed.Level)
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+}
+
+func TestSkipToNextDueFromFreshTraining(t *testing.T) {
+	challenges := createChallenges(2)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer first failed")
+	second := training.CurrentChallenge
+
+	utils.AssertNoError(t, training.SkipToNextDue(context.Background()), "skip failed")
+	utils.Assert(t, training.CurrentChallenge.Id == challenges[0].Id, "expected skip back to the first challenge, got %s", training.CurrentChallenge.Id)
+	utils.Assert(t, ContainsChallenge(second.Id)(training), "left challenge %s lost", second.Id)
+	utils.Assert(t, len(training.Challenges) == 2 && len(training.DueQueue()) == 2, "expected both challenges open, got %v", training.Challenges)
+	utils.AssertNoError(t, training.SkipToNextDue(context.Background()), "skip back failed")
+	utils.Assert(t, training.CurrentChallenge == second, "expected skip back to challenge %s, got %s", second.Id, training.CurrentChallenge.Id)
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+}
+
+func TestPauseFreezesDueTimestamps(t *testing.T) {
+	challenges := createChallenges(3)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer first failed")
+	_, err = training.Next(context.Background(), challenges[1].Answer, provider)
+	utils.AssertNoError(t, err, "answer second failed")
+	advanced, _ := training.findChallenge(challenges[1].Id)
+	due := advanced.Timestamp
+
+	paused := time.Now()
+	utils.AssertNoError(t, training.record(Change{Type: TrainingPaused, Timestamp: paused}), "pause failed")
+	_, err = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+	utils.Assert(t, errors.Is(err, ErrPaused), "expected paused training to reject answers, got %v", err)
+	utils.Assert(t, errors.Is(training.Pause(), ErrPaused), "expected second pause to fail")
+
+	utils.AssertNoError(t, training.record(Change{Type: TrainingResumed, Timestamp: paused.Add(time.Hour)}), "resume failed")
+	utils.Assert(t, !training.IsPaused(), "training still paused")
+	utils.Assert(t, advanced.Timestamp.Equal(due.Add(time.Hour)), "expected due %s shifted by the pause, got %s", due, advanced.Timestamp)
+	utils.Assert(t, errors.Is(training.Resume(), ErrNotPaused), "expected resume of running training to fail")
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+}
+
+func TestResetAndArchive(t *testing.T) {
+	challenges := createChallenges(1)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+	answerUntilDone(t, training, challenges[0], provider)
+	utils.Assert(t, training.Completed, "expected completed training")
+
+	utils.AssertNoError(t, training.Reset(), "reset failed")
+	utils.Assert(t, !training.Completed, "reset training must be running again")
+	for _, challenge := range training.AllChallenges() {
+		utils.Assert(t, challenge.Level == 0 && !challenge.Done, "challenge %s not reset", challenge.Id)
+	}
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer after reset failed")
+
+	utils.AssertNoError(t, training.Archive(), "archive failed")
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.Assert(t, errors.Is(err, ErrArchived), "expected archived training to reject answers, got %v", err)
+	utils.Assert(t, errors.Is(training.Reset(), ErrArchived), "expected reset of archived training to fail")
+	utils.Assert(t, errors.Is(training.Archive(), ErrArchived), "expected second archive to fail")
+	utils.Assert(t, len(training.DueQueue()) == 0, "archived training has nothing due")
+}
+
+func TestSkipDefersCurrentChallenge(t *testing.T) {
+	challenges := createChallenges(3)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+
+	utils.AssertNoError(t, training.Skip(context.Background(), provider), "skip failed")
+	utils.Assert(t, training.CurrentChallenge.Id == challenges[1].Id, "expected new challenge after skip, got %s", training.CurrentChallenge.Id)
+	skipped, found := training.findChallenge(challenges[0].Id)
+	utils.Assert(t, found && skipped.Level == 0 && skipped.Timestamp.After(time.Now()), "skipped challenge must stay open and be deferred")
+	utils.Assert(t, training.Stats.skippedChallenges == 1 && training.Stats.passedChallenges == 0 && training.Stats.failedChallenges == 0, "expected skip counted separately, got %+v", *training.Stats)
+
+	_, err = training.Next(context.Background(), challenges[1].Answer, provider)
+	utils.AssertNoError(t, err, "answer failed")
+	utils.AssertNoError(t, training.Skip(context.Background(), provider), "second skip failed")
+	utils.Assert(t, training.CurrentChallenge.Id == challenges[0].Id, "expected skipped challenge ahead of time once the pool is exhausted, got %s", training.CurrentChallenge.Id)
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+}
+
+func TestSkipKeepsFailure(t *testing.T) {
+	challenges := createChallenges(3)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer first failed")
+
+	_, err = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+	utils.AssertNoError(t, err, "wrong answer failed")
+	utils.AssertNoError(t, training.Skip(context.Background(), provider), "skip failed")
+	utils.Assert(t, training.CurrentChallenge.Id != challenges[1].Id && !training.currentChallengeFailed, "expected fresh challenge after skip")
+	for i := 0; i < 3 && training.CurrentChallenge.Id != challenges[1].Id; i++ {
+		_, err = training.Next(context.Background(), training.CurrentChallenge.Answer, provider)
+		utils.AssertNoError(t, err, "answer %d failed", i)
+	}
+
+	utils.Assert(t, training.CurrentChallenge.Id == challenges[1].Id && training.currentChallengeFailed, "expected skipped challenge with failure, got %s", training.CurrentChallenge.Id)
+	passed := training.Stats.passedChallenges
+	_, err = training.Next(context.Background(), challenges[1].Answer, provider)
+	utils.AssertNoError(t, err, "answer skipped failed")
+	skipped, _ := training.findChallenge(challenges[1].Id)
+	utils.Assert(t, skipped.Level == 0 && !skipped.Failed, "expected reset to level 0 after failure, got level %d", skipped.Level)
+	utils.Assert(t, training.Stats.passedChallenges == passed && training.Stats.failedChallenges == 1, "failed challenge counted as passed: %+v", *training.Stats)
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+}
+
+func TestBookmarks(t *testing.T) {
+	challenges := createChallenges(3)
+	provider := poolProvider(challenges, nil)
+	training, err := CreateTraining(provider)
+	utils.AssertNoError(t, err, "create training failed")
+
+	utils.AssertNoError(t, training.Bookmark(challenges[0].Id, true), "bookmark failed")
+	version := training.Version
+	utils.AssertNoError(t, training.Bookmark(challenges[0].Id, true), "second bookmark failed")
+	utils.Assert(t, training.Version == version, "bookmarking twice must not record a change")
+	_, err = training.Next(context.Background(), challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "answer failed")
+	bookmarks := training.Bookmarks()
+	utils.Assert(t, len(bookmarks) == 1 && bookmarks[0].Id == challenges[0].Id, "bookmark of the first challenge lost: %v", bookmarks)
+	err = training.Bookmark(challenges[2].Id, true)
+	utils.Assert(t, errors.Is(err, utils.ErrInvalid), "expected invalid challenge, got %v", err)
+
+	replayed, err := replay(training.Id, nil, training.uncommittedChanges())
+	utils.AssertNoError(t, err, "replay failed")
+	assertSameState(t, training, replayed)
+
+	utils.AssertNoError(t, training.Bookmark(challenges[0].Id, false), "remove bookmark failed")
+	utils.Assert(t, len(training.Bookmarks()) == 0, "bookmark not removed")
+	utils.AssertNoError(t, training.Archive(), "archive failed")
+	utils.Assert(t, errors.Is(training.Bookmark(challenges[1].Id, true), ErrArchived), "expected archived training to reject bookmarks")
+}
+
+func TestDomainLogsContextFields(t *testing.T) {
+	var out bytes.Buffer
+	handler, _ := utils.NewLogHandler(&out, "json", "info")
+	previous := domainLogger
+	domainLogger = utils.NewLogger("training", handler)
+	defer func() { domainLogger = previous }()
+
+	challenges := createChallenges(2)
+	provider := sequenceProvider(challenges...)
+	training, _ := CreateTraining(provider)
+	// der Request setzt die Training-Id bereits, sie darf nur einmal geschrieben werden
+	ctx := utils.WithLogField(context.Background(), utils.LogFieldRequestId, "r-1")
+	ctx = utils.WithLogField(ctx, utils.LogFieldTrainingId, training.Id.String())
+	_, err := training.Next(ctx, challenges[0].Answer, provider)
+	utils.AssertNoError(t, err, "next failed")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	utils.Assert(t, len(lines) == 2, "expected 2 log lines, got %q", out.String())
+	for _, line := range lines {
+		utils.Assert(t, strings.Count(line, `"trainingId"`) == 1, "training id not written once in %s", line)
+		var fields map[string]any
+		utils.AssertNoError(t, json.Unmarshal([]byte(line), &fields), "invalid json %s", line)
+		utils.Assert(t, fields[utils.LogFieldRequestId] == "r-1", "request id missing in %s", line)
+		utils.Assert(t, fields[utils.LogFieldTrainingId] == training.Id.String(), "training id missing in %s", line)
+	}
+}
@@ -12,3 +12,9 @@ type UpdatedEvent struct {
 	AnswerIds   []uuid.UUID `json:"answerId"`
 	Passed      bool        `json:"passed"`
 }
+
+// LifecycleEvent wird zu training.paused, training.resumed, training.reset, training.archived
+// und training.deleted verschickt
+type LifecycleEvent struct {
+	TrainingId uuid.UUID `json:"trainingId"`
+}
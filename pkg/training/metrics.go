@@ -0,0 +1,35 @@
+package training
+
+import (
+	"context"
+	"github.com/mwildt/ceh-utils/pkg/metrics"
+	"time"
+)
+
+// ein Training gilt als aktiv, wenn es in diesem Zeitraum beantwortet wurde
+const activeWindow = 24 * time.Hour
+
+var answers = metrics.NewCounter("ceh_training_answers_total", "Number of answers given in trainings by result.", "result")
+
+func countAnswer(success bool) {
+	if success {
+		answers.Inc("passed")
+	} else {
+		answers.Inc("failed")
+	}
+}
+
+// RegisterMetrics meldet die Anzahl der Trainings, die beim Abruf der Metriken ermittelt wird
+func RegisterMetrics(repo Repository) {
+	metrics.NewGaugeFunc("ceh_trainings", "Number of stored trainings.", func() float64 {
+		trainings, _ := repo.FindAllBy(context.Background(), func(*Training) bool { return true })
+		return float64(len(trainings))
+	})
+	metrics.NewGaugeFunc("ceh_trainings_active", "Number of trainings answered within the last 24 hours.", func() float64 {
+		since := time.Now().Add(-activeWindow)
+		trainings, _ := repo.FindAllBy(context.Background(), func(training *Training) bool {
+			return training.Updated.After(since)
+		})
+		return float64(len(trainings))
+	})
+}
@@ -0,0 +1,44 @@
+package training
+
+import (
+	"encoding/json"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+)
+
+// Migrationen der Datensätze im Trainings-Log. Neue Versionen werden hier ergänzt, die
+// Migration bekommt den Datensatz als JSON-Objekt der Vorgängerversion.
+var logRecordSchema = utils.NewMigrationRegistry("trainings", 1,
+	utils.Migration{
+		From:        0,
+		Description: "stamp schema version, convert unversioned trainings into snapshots",
+		Apply:       migrateLogRecordV0,
+	},
+)
+
+// Version 0 umfasst die Log-Datensätze vor Einführung der Schema-Version und das alte Format
+// ohne trainingId, in dem das Training direkt serialisiert wurde
+func migrateLogRecordV0(record utils.JsonObject) (utils.JsonObject, error) {
+	if _, exists := record["trainingId"]; exists {
+		return record, nil
+	}
+	legacy := legacyTraining{}
+	if err := remarshal(record, &legacy); err != nil {
+		return record, err
+	}
+	migrated := utils.JsonObject{}
+	err := remarshal(logRecord{TrainingId: legacy.Id, Snapshot: migrateLegacyTraining(legacy)}, &migrated)
+	return migrated, err
+}
+
+func remarshal(source any, target any) error {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Schema liefert die Migrationen der Datensätze im Trainings-Log
+func Schema() *utils.MigrationRegistry {
+	return logRecordSchema
+}
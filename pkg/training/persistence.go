@@ -0,0 +1,205 @@
+package training
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Version des Persistenz-Modells. Version 0 bezeichnet das alte Format, in dem das Training
+// direkt serialisiert wurde und dabei alle privaten Felder (Stats, Fehlerstatus der aktuellen
+// Challenge) verloren gingen.
+const trainingRecordVersion = 1
+
+// trainingRecord ist der persistierte Zustand eines Trainings zu einer Version. Das Modell ist
+// bewusst unabhängig vom Domain-Struct, damit dessen Felder frei geändert werden können.
+type trainingRecord struct {
+	SchemaVersion          int               `json:"schemaVersion"`
+	Id                     uuid.UUID         `json:"id"`
+	Version                int               `json:"version"`
+	Created                time.Time         `json:"created"`
+	Updated                time.Time         `json:"updated"`
+	CurrentChallenge       challengeRecord   `json:"currentChallenge"`
+	CurrentChallengeFailed bool              `json:"currentChallengeFailed"`
+	Challenges             []challengeRecord `json:"challenges"`
+	Stats                  statsRecord       `json:"stats"`
+	Tags                   []string          `json:"tags,omitempty"`
+	OnExhausted            ExhaustedPolicy   `json:"onExhausted,omitempty"`
+	Completed              bool              `json:"completed,omitempty"`
+	Paused                 *time.Time        `json:"paused,omitempty"`
+	Archived               bool              `json:"archived,omitempty"`
+	Owner                  string            `json:"owner,omitempty"`
+}
+
+type challengeRecord struct {
+	Id         uuid.UUID   `json:"id"`
+	Answer     []uuid.UUID `json:"answer"`
+	Level      int         `json:"level"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Done       bool        `json:"done"`
+	Count      int         `json:"count"`
+	Bookmarked bool        `json:"bookmarked,omitempty"`
+	Failed     bool        `json:"failed,omitempty"`
+}
+
+type statsRecord struct {
+	Total           int `json:"total"`
+	Passed          int `json:"passed"`
+	Failed          int `json:"failed"`
+	Skipped         int `json:"skipped,omitempty"`
+	CurrentAttempts int `json:"currentAttempts"`
+}
+
+// legacyTraining entspricht dem alten Format, das der Encoder aus den öffentlichen Feldern
+// des Trainings erzeugt hat
+type legacyTraining struct {
+	Id               uuid.UUID
+	CurrentChallenge *legacyChallenge
+	Updated          time.Time
+	Created          time.Time
+	Challenges       []*legacyChallenge
+}
+
+type legacyChallenge struct {
+	Id        uuid.UUID
+	Answer    []uuid.UUID
+	Level     int
+	Timestamp time.Time
+	Done      bool
+	Count     int
+}
+
+func (legacy legacyChallenge) toRecord() challengeRecord {
+	return challengeRecord{
+		Id:        legacy.Id,
+		Answer:    legacy.Answer,
+		Level:     legacy.Level,
+		Timestamp: legacy.Timestamp,
+		Done:      legacy.Done,
+		Count:     legacy.Count,
+	}
+}
+
+func toChallengeRecord(challenge *TrainingChallenge) challengeRecord {
+	return challengeRecord{
+		Id:         challenge.Id,
+		Answer:     challenge.Answer,
+		Level:      challenge.Level,
+		Timestamp:  challenge.Timestamp,
+		Done:       challenge.Done,
+		Count:      challenge.Count,
+		Bookmarked: challenge.Bookmarked,
+		Failed:     challenge.Failed,
+	}
+}
+
+func (record challengeRecord) toDomain() *TrainingChallenge {
+	return &TrainingChallenge{
+		Id:         record.Id,
+		Answer:     record.Answer,
+		Level:      record.Level,
+		Timestamp:  record.Timestamp,
+		Done:       record.Done,
+		Count:      record.Count,
+		Bookmarked: record.Bookmarked,
+		Failed:     record.Failed,
+	}
+}
+
+func toTrainingRecord(training *Training) *trainingRecord {
+	challenges := make([]challengeRecord, 0, len(training.Challenges))
+	for _, challenge := range training.Challenges {
+		challenges = append(challenges, toChallengeRecord(challenge))
+	}
+	stats := statsRecord{}
+	if training.Stats != nil {
+		stats = statsRecord{
+			Total:           training.Stats.totalChallenges,
+			Passed:          training.Stats.passedChallenges,
+			Failed:          training.Stats.failedChallenges,
+			Skipped:         training.Stats.skippedChallenges,
+			CurrentAttempts: training.Stats.currentChallengeAttempts,
+		}
+	}
+	record := &trainingRecord{
+		SchemaVersion:          trainingRecordVersion,
+		Id:                     training.Id,
+		Version:                training.Version,
+		Created:                training.Created,
+		Updated:                training.Updated,
+		CurrentChallenge:       toChallengeRecord(training.CurrentChallenge),
+		CurrentChallengeFailed: training.currentChallengeFailed,
+		Challenges:             challenges,
+		Stats:                  stats,
+		Tags:                   training.Tags,
+		OnExhausted:            training.OnExhausted,
+		Completed:              training.Completed,
+		Archived:               training.Archived,
+		Owner:                  training.Owner,
+	}
+	if training.IsPaused() {
+		paused := training.Paused
+		record.Paused = &paused
+	}
+	return record
+}
+
+// toDomain erzeugt ein neues, unabhängiges Training aus dem gespeicherten Zustand
+func (record *trainingRecord) toDomain() *Training {
+	challenges := make([]*TrainingChallenge, 0, len(record.Challenges))
+	for _, challenge := range record.Challenges {
+		challenges = append(challenges, challenge.toDomain())
+	}
+	training := &Training{
+		Id:                     record.Id,
+		Version:                record.Version,
+		Created:                record.Created,
+		Updated:                record.Updated,
+		CurrentChallenge:       record.CurrentChallenge.toDomain(),
+		currentChallengeFailed: record.CurrentChallengeFailed,
+		Challenges:             challenges,
+		Stats: &Stats{
+			totalChallenges:          record.Stats.Total,
+			passedChallenges:         record.Stats.Passed,
+			failedChallenges:         record.Stats.Failed,
+			skippedChallenges:        record.Stats.Skipped,
+			currentChallengeAttempts: record.Stats.CurrentAttempts,
+		},
+		Tags:        record.Tags,
+		OnExhausted: record.OnExhausted,
+		Completed:   record.Completed,
+		Archived:    record.Archived,
+		Owner:       record.Owner,
+	}
+	if record.Paused != nil {
+		training.Paused = *record.Paused
+	}
+	return training.init()
+}
+
+// migrateLegacyTraining überführt ein Training aus dem alten Format. Die dort verlorenen Stats
+// werden so weit wie möglich rekonstruiert: jede bestandene Challenge hat ihren Zähler erhöht.
+// Die erste Challenge eines alten Trainings ist nicht Teil der Liste, sie wurde genau dann einmal
+// bestanden, wenn die Liste nicht leer ist. Bestandene und fehlgeschlagene Challenges lassen
+// sich nicht rekonstruieren, da das alte Format falsche Antworten nicht festhält. Beide Zähler
+// beginnen daher bei 0, nur Total enthält die Challenges vor der Migration.
+func migrateLegacyTraining(legacy legacyTraining) *trainingRecord {
+	record := &trainingRecord{
+		SchemaVersion: trainingRecordVersion,
+		Id:            legacy.Id,
+		Created:       legacy.Created,
+		Updated:       legacy.Updated,
+		Challenges:    make([]challengeRecord, 0, len(legacy.Challenges)),
+		Stats:         statsRecord{Total: 1},
+	}
+	if legacy.CurrentChallenge != nil {
+		record.CurrentChallenge = legacy.CurrentChallenge.toRecord()
+	}
+	for _, challenge := range legacy.Challenges {
+		record.Challenges = append(record.Challenges, challenge.toRecord())
+		record.Stats.Total = record.Stats.Total + challenge.Count
+	}
+	if len(legacy.Challenges) > 0 {
+		record.Stats.Total = record.Stats.Total + 1
+	}
+	return record
+}
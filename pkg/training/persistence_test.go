@@ -0,0 +1,165 @@
+package training
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func encodeDecode(t *testing.T, training *Training) *Training {
+	data, err := utils.VersionedJsonEncoder[logRecord](logRecordSchema)(logRecord{TrainingId: training.Id, Snapshot: toTrainingRecord(training)})
+	utils.AssertNoError(t, err, "encode failed")
+	record, from, err := decodeLogRecord(data)
+	utils.AssertNoError(t, err, "decode failed")
+	utils.Assert(t, from == logRecordSchema.Current(), "record not stamped with the current schema version")
+	utils.Assert(t, record.Snapshot.SchemaVersion == trainingRecordVersion, "wrong schema version %d", record.Snapshot.SchemaVersion)
+	return record.Snapshot.toDomain()
+}
+
+func TestTrainingRecordRoundTripNewTraining(t *testing.T) {
+	training, err := CreateTraining(sequenceProvider(createChallenges(1)...))
+	utils.AssertNoError(t, err, "create failed")
+	assertSameState(t, training, encodeDecode(t, training))
+}
+
+func TestTrainingRecordRoundTripKeepsPrivateState(t *testing.T) {
+	challenges := createChallenges(4)
+	provider := sequenceProvider(challenges...)
+	training, _ := CreateTraining(provider)
+	_, _ = training.Next(context.Background(), challenges[0].Answer, provider)
+	_, _ = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+	_, _ = training.Next(context.Background(), []uuid.UUID{uuid.New()}, provider)
+
+	restored := encodeDecode(t, training)
+	assertSameState(t, training, restored)
+	utils.Assert(t, restored.currentChallengeFailed, "failed flag lost")
+	utils.Assert(t, restored.Stats.failedChallenges == 1, "failed challenges lost")
+	utils.Assert(t, restored.Stats.currentChallengeAttempts == 2, "current attempts lost")
+	utils.Assert(t, restored.Stats.passedChallenges == 1, "passed challenges lost")
+}
+
+func TestTrainingRecordRoundTripKeepsSettings(t *testing.T) {
+	training, err := CreateTrainingWithSettings(sequenceProvider(createChallenges(1)...), Settings{Tags: []string{"network"}, OnExhausted: RecycleOnExhausted})
+	utils.AssertNoError(t, err, "create failed")
+	training.Completed = true
+	training.Archived = true
+	training.Paused = time.Now()
+
+	restored := encodeDecode(t, training)
+	utils.Assert(t, len(restored.Tags) == 1 && restored.Tags[0] == "network", "tags lost: %v", restored.Tags)
+	utils.Assert(t, restored.OnExhausted == RecycleOnExhausted, "policy lost: %s", restored.OnExhausted)
+	utils.Assert(t, restored.Completed, "completed state lost")
+	utils.Assert(t, restored.Archived, "archived state lost")
+	utils.Assert(t, restored.Paused.Equal(training.Paused), "pause lost: %s", restored.Paused)
+}
+
+func TestTrainingRecordRoundTripKeepsChallengeIdentity(t *testing.T) {
+	challenges := createChallenges(2)
+	provider := sequenceProvider(challenges...)
+	training, _ := CreateTraining(provider)
+	_, _ = training.Next(context.Background(), challenges[0].Answer, provider)
+
+	restored := encodeDecode(t, training)
+	utils.Assert(t, restored.CurrentChallenge == restored.Challenges[1], "current challenge is not linked to the challenge list")
+}
+
+func TestLegacyFileIsMigrated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	utils.AssertNoError(t, utils.CopyFile("testdata/legacy-trainings.data", path), "copy fixture failed")
+
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "load legacy file failed")
+	utils.Assert(t, utils.FileExist(path+".legacy"), "legacy backup missing")
+
+	trainings, _ := repo.FindAllBy(context.Background(), func(*Training) bool { return true })
+	utils.Assert(t, len(trainings) == 2, "expected 2 trainings, got %d", len(trainings))
+
+	migrated, found := repo.FindFirst(context.Background(), IdEquals(uuid.MustParse("5b0c2c3e-6a44-4c1e-9a0f-1d6f4b8f2a01")))
+	utils.Assert(t, found, "migrated training not found")
+	utils.Assert(t, len(migrated.Challenges) == 2, "expected latest legacy state with 2 challenges")
+	utils.Assert(t, migrated.CurrentChallenge == migrated.Challenges[1], "current challenge not linked")
+	utils.Assert(t, migrated.Stats.totalChallenges == 3, "expected reconstructed total of 3, got %d", migrated.Stats.totalChallenges)
+	// das alte Format kennt keine falschen Antworten, bestanden und fehlgeschlagen gehen verloren
+	utils.Assert(t, migrated.Stats.passedChallenges == 0, "expected no passed challenges, got %d", migrated.Stats.passedChallenges)
+	utils.Assert(t, migrated.Stats.failedChallenges == 0, "expected no failed challenges, got %d", migrated.Stats.failedChallenges)
+
+	count, err := utils.LoadRecords(path, func(data []byte) error {
+		_, from, err := decodeLogRecord(data)
+		utils.Assert(t, err == nil && from == logRecordSchema.Current(), "file still contains legacy records")
+		return err
+	})
+	utils.AssertNoError(t, err, "read migrated file failed")
+	utils.Assert(t, count == 2, "expected 2 records after migration, got %d", count)
+
+	reloaded, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "reload failed")
+	again, _ := reloaded.FindFirst(context.Background(), IdEquals(migrated.Id))
+	assertSameState(t, migrated, again)
+}
+
+func TestMigratedTrainingKeepsStateAfterAnswers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	utils.AssertNoError(t, utils.CopyFile("testdata/legacy-trainings.data", path), "copy fixture failed")
+	repo, _ := CreateFileRepository(path)
+
+	training, _ := repo.FindFirst(context.Background(), IdEquals(uuid.MustParse("5b0c2c3e-6a44-4c1e-9a0f-1d6f4b8f2a02")))
+	_, err := training.Next(context.Background(), []uuid.UUID{uuid.New()}, sequenceProvider(createChallenges(1)...))
+	utils.AssertNoError(t, err, "next failed")
+	_, err = repo.Save(context.Background(), training)
+	utils.AssertNoError(t, err, "save failed")
+
+	reloaded, _ := CreateFileRepository(path)
+	again, _ := reloaded.FindFirst(context.Background(), IdEquals(training.Id))
+	assertSameState(t, training, again)
+	utils.Assert(t, again.currentChallengeFailed, "failed flag lost after reload")
+}
+
+func TestUnversionedLogIsMigrated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	utils.AssertNoError(t, utils.CopyFile("testdata/trainings-v0.data", path), "copy fixture failed")
+
+	reports, err := utils.MigrateLogFile(path, Schema(), true)
+	utils.AssertNoError(t, err, "dry run failed")
+	utils.Assert(t, len(reports) == 16, "expected 16 records to migrate, got %d", len(reports))
+
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "load fixture failed")
+	trainings, _ := repo.FindAllBy(context.Background(), func(*Training) bool { return true })
+	utils.Assert(t, len(trainings) == 1, "expected 1 training, got %d", len(trainings))
+	training := trainings[0]
+	timeline, _ := repo.Timeline(context.Background(), training.Id)
+	utils.Assert(t, len(timeline) == training.Version, "expected %d changes, got %d", training.Version, len(timeline))
+	utils.Assert(t, training.Stats.failedChallenges == 3, "expected 3 failed challenges, got %d", training.Stats.failedChallenges)
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	reports, err = utils.MigrateLogFile(path, Schema(), true)
+	utils.AssertNoError(t, err, "check after load failed")
+	utils.Assert(t, len(reports) == 0, "expected stamped records after load, %d left", len(reports))
+}
+
+func TestJsonLinesCodecIsUsedForNewFiles(t *testing.T) {
+	t.Setenv("STORAGE_CODEC", utils.CodecJsonLines)
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "create repository failed")
+	challenges := createChallenges(2)
+	provider := sequenceProvider(challenges...)
+	training, _ := CreateTraining(provider)
+	_, _ = training.Next(context.Background(), challenges[0].Answer, provider)
+	_, err = repo.Save(context.Background(), training)
+	utils.AssertNoError(t, err, "save failed")
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	codec, detected, err := utils.DetectCodec(path)
+	utils.Assert(t, err == nil && detected && codec == utils.JsonLinesCodec, "expected a JSON Lines file")
+
+	t.Setenv("STORAGE_CODEC", utils.CodecBinary)
+	reloaded, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "reload failed")
+	again, found := reloaded.FindFirst(context.Background(), IdEquals(training.Id))
+	utils.Assert(t, found, "training not found after reload")
+	assertSameState(t, training, again)
+}
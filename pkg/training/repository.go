@@ -2,18 +2,27 @@ package training
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/storage"
 	"github.com/mwildt/ceh-utils/pkg/utils"
 	"github.com/ohrenpiraten/go-collections/predicates"
-	"os"
 	"sync"
+	"time"
 )
 
 type Repository interface {
 	Save(context.Context, *Training) (*Training, error)
 	FindAllBy(ctx context.Context, predicate predicates.Predicate[*Training]) ([]*Training, error)
 	FindFirst(ctx context.Context, predicate predicates.Predicate[*Training]) (*Training, bool)
+	// Timeline liefert alle gespeicherten Changes eines Trainings in der Reihenfolge ihres Auftretens
+	Timeline(ctx context.Context, id uuid.UUID) ([]Change, bool)
+	// FindAt liefert den Zustand eines Trainings zum angegebenen Zeitpunkt
+	FindAt(ctx context.Context, id uuid.UUID, at time.Time) (*Training, bool)
+	// Delete entfernt ein Training mit seiner Historie endgültig
+	Delete(ctx context.Context, training *Training) error
+	Close() error
 }
 
 func IdEquals(value uuid.UUID) predicates.Predicate[*Training] {
@@ -22,41 +31,122 @@ func IdEquals(value uuid.UUID) predicates.Predicate[*Training] {
 	}
 }
 
+func IsArchived() predicates.Predicate[*Training] {
+	return func(q *Training) bool {
+		return q.Archived
+	}
+}
+
+func OwnedBy(owner string) predicates.Predicate[*Training] {
+	return func(q *Training) bool {
+		return owner == q.Owner
+	}
+}
+
+// logRecord ist ein Eintrag im Trainings-Log. Er enthält entweder einen Change, einen Snapshot
+// oder als Tombstone die Löschung des Trainings.
+// Einträge ohne TrainingId stammen aus dem alten Format, in dem jedes Speichern das
+// vollständige Training geschrieben hat.
+type logRecord struct {
+	TrainingId uuid.UUID       `json:"trainingId"`
+	Change     *Change         `json:"change,omitempty"`
+	Snapshot   *trainingRecord `json:"snapshot,omitempty"`
+	Deleted    bool            `json:"deleted,omitempty"`
+}
+
+// stream hält die gespeicherte Historie eines Trainings: den Ausgangszustand (nur bei Trainings
+// aus dem alten Format), alle Changes und die Version des zuletzt geschriebenen Snapshots
+type stream struct {
+	base            *trainingRecord
+	changes         []Change
+	snapshotVersion int
+}
+
+func (s *stream) baseVersion() int {
+	if s.base == nil {
+		return 0
+	}
+	return s.base.Version
+}
+
+// Anzahl der Einträge, die nach einer Kompaktierung für diesen Stream übrig bleiben
+func (s *stream) liveRecords() int {
+	count := len(s.changes)
+	if s.base != nil {
+		count++
+	}
+	if s.snapshotVersion > s.baseVersion() {
+		count++
+	}
+	return count
+}
+
 type fileRepository struct {
 	values            map[uuid.UUID]*Training
+	streams           map[uuid.UUID]*stream
 	path              string
 	logger            utils.Logger
-	file              *os.File
-	decoder           utils.Decoder[Training]
-	encoder           utils.Encoder[Training]
+	file              utils.RecordLog
+	codec             utils.Codec
+	decoder           func(data []byte) (logRecord, int, error)
+	encoder           utils.Encoder[logRecord]
 	mutex             *sync.Mutex
-	syncFactor        int
+	compactor         *utils.Compactor
+	snapshotInterval  int
 	writtenOperations int
+	migratedRecords   int
 }
 
 func CreateFileRepository(path string) (Repository, error) {
 	repo := &fileRepository{
-		values:     make(map[uuid.UUID]*Training),
-		path:       path,
-		logger:     utils.NewStdLogger("trainings.repository"),
-		encoder:    utils.B64JsonEncoder[Training],
-		decoder:    utils.B64JsonDecoder[Training],
-		mutex:      &sync.Mutex{},
-		syncFactor: 100,
+		values:           make(map[uuid.UUID]*Training),
+		streams:          make(map[uuid.UUID]*stream),
+		path:             path,
+		logger:           utils.NewStdLogger("trainings.repository"),
+		encoder:          utils.VersionedJsonEncoder[logRecord](logRecordSchema),
+		decoder:          decodeLogRecord,
+		mutex:            &sync.Mutex{},
+		snapshotInterval: 20,
 	}
 
+	if err := repo.selectCodec(); err != nil {
+		return repo, err
+	}
 	if err := utils.CreateFileIfNotExists(repo.filepath()); err != nil {
 		return repo, err
 	}
+	if err := repo.recover(); err != nil {
+		return repo, err
+	}
 	if err := repo.load(); err != nil {
 		return repo, err
 	}
+	if err := repo.backupLegacy(); err != nil {
+		return repo, err
+	}
 	if err := repo.open(); err != nil {
 		return repo, err
 	}
+	if err := repo.migrate(); err != nil {
+		return repo, err
+	}
+	repo.compactor = utils.NewCompactor("trainings", repo, utils.ConfiguredCompactionThresholds())
 	return repo, nil
 }
 
+func decodeLogRecord(data []byte) (record logRecord, from int, err error) {
+	return utils.VersionedJsonDecoder[logRecord](logRecordSchema)(data)
+}
+
+// bestehende Dateien behalten ihr Format, neue Dateien verwenden STORAGE_CODEC
+func (repo *fileRepository) selectCodec() error {
+	configured, err := utils.ConfiguredCodec()
+	if err == nil {
+		repo.codec, err = utils.CodecFor(repo.filepath(), configured)
+	}
+	return err
+}
+
 func (repo *fileRepository) filepath() string {
 	return repo.path
 }
@@ -66,87 +156,243 @@ func (repo *fileRepository) open() (err error) {
 		return fmt.Errorf("could not open log segment. File %s not found", repo.filepath())
 	}
 
-	repo.logger.Info("open file %s for writing", repo.filepath())
-	repo.file, err = os.OpenFile(repo.filepath(), os.O_APPEND|os.O_WRONLY, 0644)
+	repo.logger.Info("open file %s for writing (%s)", repo.filepath(), repo.codec.Name())
+	repo.file, err = repo.codec.Open(repo.filepath(), utils.ConfiguredSyncPolicy())
+	return err
+}
+
+func (repo *fileRepository) recover() error {
+	report, err := repo.codec.Recover(repo.filepath())
+	if err == nil && report.Modified() {
+		repo.logger.Warn("recovered %s: %d records kept, %d corrupt records quarantined, %d bytes of an incomplete record truncated",
+			repo.filepath(), report.Records, report.Quarantined, report.TruncatedBytes)
+	}
 	return err
 }
 
 func (repo *fileRepository) load() (err error) {
 	repo.logger.Info("start load items from file-system (%s)", repo.filepath())
 
-	count, err := utils.LoadFromFile(repo.filepath(), func(buffer []byte) error {
+	count, err := repo.codec.Load(repo.filepath(), func(buffer []byte) error {
 		repo.writtenOperations = repo.writtenOperations + 1
-		value, err := repo.decoder(buffer)
+		record, from, err := repo.decoder(buffer)
 		if err != nil {
 			return err
 		}
-		value.init()
-		repo.values[value.Id] = &value
-		return nil
+		if from != logRecordSchema.Current() {
+			repo.migratedRecords++
+		}
+		return repo.loadRecord(record)
 	})
 	if err == nil {
-		repo.logger.Info("%d items loaded from file system, %d in store", count, len(repo.values))
+		repo.logger.Info("%d records loaded from file system, %d trainings in store", count, len(repo.values))
 	}
 	return err
 }
 
-func (repo *fileRepository) checkForSync() (err error) {
-	if len(repo.values)+repo.syncFactor <= repo.writtenOperations { // nach 100 operationen wird die Datei neu geschrieben...
-		return repo.sync()
+// sichert eine Datei mit Einträgen im alten Format vor der Migration
+// vor dem Umschreiben migrierter Datensätze bleibt die Originaldatei erhalten
+func (repo *fileRepository) backupLegacy() error {
+	if repo.migratedRecords == 0 {
+		return nil
+	}
+	backupPath := repo.filepath() + ".legacy"
+	repo.logger.Warn("migrated %d records to schema version %d, original file is kept as %s", repo.migratedRecords, logRecordSchema.Current(), backupPath)
+	return utils.CopyFile(repo.filepath(), backupPath)
+}
+
+// schreibt eine Datei mit Einträgen im alten Format vollständig im aktuellen Format neu
+func (repo *fileRepository) migrate() error {
+	if repo.migratedRecords == 0 {
+		return nil
 	}
+	if err := repo.Compact(); err != nil {
+		return err
+	}
+	repo.migratedRecords = 0
 	return nil
 }
 
-func (repo *fileRepository) sync() (err error) {
-	repo.logger.Info("start sync operation")
+func (repo *fileRepository) stream(id uuid.UUID) *stream {
+	s, exists := repo.streams[id]
+	if !exists {
+		s = &stream{changes: make([]Change, 0)}
+		repo.streams[id] = s
+	}
+	return s
+}
 
-	repo.mutex.Lock()
-	defer repo.mutex.Unlock()
+func (repo *fileRepository) loadRecord(record logRecord) error {
+	if record.Deleted {
+		delete(repo.values, record.TrainingId)
+		delete(repo.streams, record.TrainingId)
+		return nil
+	}
+	s := repo.stream(record.TrainingId)
 
-	intermediateFilePath := repo.filepath() + ".ifd"
-	intermediateFile, err := os.OpenFile(intermediateFilePath, os.O_CREATE|os.O_WRONLY, 0644) // intermediate flush data
-	for _, training := range repo.values {
-		if err = utils.Append(intermediateFile, *training, repo.encoder); err != nil {
-			return err
+	if record.Snapshot != nil {
+		if record.Snapshot.SchemaVersion != trainingRecordVersion {
+			return fmt.Errorf("unsupported schema version %d for training %s", record.Snapshot.SchemaVersion, record.TrainingId)
 		}
+		// Snapshots vor dem ersten Change bilden den Ausgangszustand der Historie
+		if len(s.changes) == 0 {
+			s.base = record.Snapshot
+		}
+		s.snapshotVersion = record.Snapshot.Version
+		repo.values[record.TrainingId] = record.Snapshot.toDomain()
+		return nil
 	}
-	repo.logger.Info("%d objects written to %s", len(repo.values), intermediateFile.Name())
-	// nach dem schreiben die Files tauschen...
-	if err = intermediateFile.Close(); err != nil {
-		return err
-	} else if err = repo.file.Close(); err != nil {
-		return err
-	} else if err = os.Remove(repo.filepath()); err != nil {
-		return err
-	} else if err = os.Rename(intermediateFilePath, repo.filepath()); err != nil {
-		return err
-	} else {
-		repo.writtenOperations = len(repo.values)
-		return repo.open()
+
+	if record.Change == nil {
+		return fmt.Errorf("invalid record for training %s", record.TrainingId)
+	}
+	s.changes = append(s.changes, *record.Change)
+	training, exists := repo.values[record.TrainingId]
+	if !exists {
+		training = (&Training{Id: record.TrainingId}).init()
+		repo.values[record.TrainingId] = training
+	}
+	if record.Change.Version > training.Version {
+		return training.apply(*record.Change)
 	}
+	return nil
 }
 
-func (repo *fileRepository) Save(ctx context.Context, training *Training) (_ *Training, err error) {
+func (repo *fileRepository) liveRecords() (count int) {
+	for _, s := range repo.streams {
+		count = count + s.liveRecords()
+	}
+	return count
+}
+
+func (repo *fileRepository) CompactionStats() (total int, live int) {
 	repo.mutex.Lock()
-	defer func() {
-		repo.mutex.Unlock()
-		go func() {
-			if err = repo.checkForSync(); err != nil {
-				repo.logger.Error("sync error: %s", err.Error())
+	defer repo.mutex.Unlock()
+	return repo.writtenOperations, repo.liveRecords()
+}
+
+// Compact schreibt je Training den Ausgangszustand, alle Changes und einen aktuellen Snapshot.
+// Zwischenzeitliche Snapshots werden dabei verworfen, gelöschte Trainings und ihre Tombstones
+// entfallen.
+func (repo *fileRepository) Compact() (err error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	written := 0
+	versions := make(map[uuid.UUID]int)
+	err = utils.CompactLogFile(repo.filepath(), repo.codec, func(log utils.RecordLog) error {
+		for id, s := range repo.streams {
+			records := make([]logRecord, 0, s.liveRecords())
+			if s.base != nil {
+				records = append(records, logRecord{TrainingId: id, Snapshot: s.base})
+			}
+			for i := range s.changes {
+				records = append(records, logRecord{TrainingId: id, Change: &s.changes[i]})
+			}
+			// der Snapshot entsteht aus den geschriebenen Changes: die Trainings in repo.values ändern
+			// die Handler ohne Lock, und nach einem fehlgeschlagenen Save enthalten sie ungeschriebene Changes
+			if s.snapshotVersion > s.baseVersion() {
+				training, err := replay(id, s.base, s.changes)
+				if err != nil {
+					return err
+				}
+				records = append(records, logRecord{TrainingId: id, Snapshot: toTrainingRecord(training)})
+				versions[id] = training.Version
+			}
+			for _, record := range records {
+				if err := utils.Append(log, record, repo.encoder); err != nil {
+					return err
+				}
 			}
-		}()
-	}()
-	err = utils.Append(repo.file, *training, repo.encoder)
+			written = written + len(records)
+		}
+		return nil
+	})
 	if err != nil {
+		return err
+	}
+	repo.logger.Info("%d records written to %s", written, repo.filepath())
+
+	// die alte Datei ist durch das rename bereits ersetzt, das Handle zeigt noch auf sie
+	for id, version := range versions {
+		repo.streams[id].snapshotVersion = version
+	}
+	repo.writtenOperations = written
+	if repo.file != nil {
+		if err = repo.file.Close(); err != nil {
+			return err
+		}
+	}
+	return repo.open()
+}
+
+func (repo *fileRepository) Save(ctx context.Context, training *Training) (_ *Training, err error) {
+	if err = repo.append(training); err != nil {
 		return training, err
 	}
+	// die Events werden erst nach dem Freigeben des Locks verteilt, damit Subscriber wieder auf das Repository zugreifen können
+	training.emitEvents()
+	if repo.compactor != nil {
+		repo.compactor.Notify()
+	}
+	return training, err
+}
+
+func (repo *fileRepository) append(training *Training) (err error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	s := repo.stream(training.Id)
+	for _, change := range training.uncommittedChanges() {
+		if err = utils.Append(repo.file, logRecord{TrainingId: training.Id, Change: &change}, repo.encoder); err != nil {
+			return err
+		}
+		s.changes = append(s.changes, change)
+		repo.writtenOperations = repo.writtenOperations + 1
+	}
+	training.commitChanges()
+
+	// in regelmäßigen Abständen wird ein Snapshot geschrieben, damit beim Laden nicht alle Changes angewendet werden müssen
+	if training.Version-s.snapshotVersion >= repo.snapshotInterval {
+		if err = utils.Append(repo.file, logRecord{TrainingId: training.Id, Snapshot: toTrainingRecord(training)}, repo.encoder); err != nil {
+			return err
+		}
+		s.snapshotVersion = training.Version
+		repo.writtenOperations = repo.writtenOperations + 1
+	}
+
 	repo.values[training.Id] = training
+	return nil
+}
+
+// Delete schreibt einen Tombstone, die Einträge des Trainings entfallen bei der nächsten Kompaktierung
+func (repo *fileRepository) Delete(ctx context.Context, training *Training) error {
+	if err := repo.remove(training.Id); err != nil {
+		return err
+	}
+	training.events = append(training.events, deletedEvent(training.Id))
 	training.emitEvents()
+	if repo.compactor != nil {
+		repo.compactor.Notify()
+	}
+	return nil
+}
+
+func (repo *fileRepository) remove(id uuid.UUID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	if err := utils.Append(repo.file, logRecord{TrainingId: id, Deleted: true}, repo.encoder); err != nil {
+		return err
+	}
 	repo.writtenOperations = repo.writtenOperations + 1
-	return training, err
+	delete(repo.values, id)
+	delete(repo.streams, id)
+	return nil
 }
 
 func (repo *fileRepository) FindAllBy(ctx context.Context, predicate predicates.Predicate[*Training]) (list []*Training, err error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
 	for _, training := range repo.values {
 		if predicate(training) {
 			list = append(list, training)
@@ -156,6 +402,8 @@ func (repo *fileRepository) FindAllBy(ctx context.Context, predicate predicates.
 }
 
 func (repo *fileRepository) FindFirst(ctx context.Context, predicate predicates.Predicate[*Training]) (*Training, bool) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
 	for _, training := range repo.values {
 		if predicate(training) {
 			return training, true
@@ -163,3 +411,61 @@ func (repo *fileRepository) FindFirst(ctx context.Context, predicate predicates.
 	}
 	return nil, false
 }
+
+func (repo *fileRepository) Timeline(ctx context.Context, id uuid.UUID) ([]Change, bool) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	if s, exists := repo.streams[id]; !exists {
+		return nil, false
+	} else {
+		return append([]Change{}, s.changes...), true
+	}
+}
+
+func (repo *fileRepository) FindAt(ctx context.Context, id uuid.UUID, at time.Time) (*Training, bool) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	s, exists := repo.streams[id]
+	if !exists {
+		return nil, false
+	}
+
+	if s.base != nil {
+		if s.base.Created.After(at) {
+			return nil, false
+		}
+	} else if len(s.changes) == 0 || s.changes[0].Timestamp.After(at) {
+		return nil, false
+	}
+
+	changes := make([]Change, 0, len(s.changes))
+	for _, change := range s.changes {
+		if change.Timestamp.After(at) {
+			break
+		}
+		changes = append(changes, change)
+	}
+	training, err := replay(id, s.base, changes)
+	if err != nil {
+		repo.logger.Error("unable to replay training %s: %s", id, err.Error())
+		return nil, false
+	}
+	return training, true
+}
+
+// Close beendet den Compactor, schreibt die Log-Datei auf die Platte und schließt sie
+func (repo *fileRepository) Close() error {
+	if repo.compactor != nil {
+		repo.compactor.Stop()
+	}
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return errors.Join(repo.file.Sync(), repo.file.Close())
+}
+
+// Snapshot übernimmt die Log-Datei, solange keine Änderungen geschrieben werden
+func (repo *fileRepository) Snapshot(write storage.SnapshotWriter) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	return storage.SnapshotFile(repo.path, storage.TrainingsFile, write)
+}
@@ -1,13 +1,18 @@
 package training
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/utils"
 	"github.com/mwildt/go-http/httputils"
 	"github.com/mwildt/go-http/routing"
 	"github.com/ohrenpiraten/go-collections/collections"
 	"github.com/ohrenpiraten/go-collections/predicates"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -28,14 +33,42 @@ func (controller *Controller) Routing(router routing.Routing) {
 	router.HandleFunc(routing.Get("/api/trainings/"), controller.GetAll)
 	router.HandleFunc(routing.Patch("/api/trainings/{trainingId}"), controller.PatchById)
 	router.HandleFunc(routing.Get("/api/trainings/{trainingId}"), controller.GetById)
-	router.HandleFunc(routing.Get("/api/trainings/{trainingId}/challenges"), controller.GetChallengesById)
+	router.HandleFunc(routing.Delete("/api/trainings/{trainingId}"), controller.DeleteById)
+	router.HandleFunc(routing.Get("/api/trainings/{trainingId}/{resource}"), utils.SubResources("resource", map[string]http.HandlerFunc{
+		"challenges": controller.GetChallengesById,
+		"timeline":   controller.GetTimelineById,
+		"due":        controller.GetDueById,
+		"bookmarks":  controller.GetBookmarksById,
+	}))
+	router.HandleFunc(routing.Post("/api/trainings/{trainingId}/{action}"), utils.SubResources("action", map[string]http.HandlerFunc{
+		"skip-to-due": controller.action((*Training).SkipToNextDue),
+		"pause":       controller.action(ignoreContext((*Training).Pause)),
+		"resume":      controller.action(ignoreContext((*Training).Resume)),
+		"reset":       controller.action(ignoreContext((*Training).Reset)),
+		"archive":     controller.action(ignoreContext((*Training).Archive)),
+		"skip":        controller.action(controller.skip),
+		"bookmark":    controller.bookmark(true),
+		"unbookmark":  controller.bookmark(false),
+	}))
 }
 
+// Post legt ein Training an. Der Body ist optional: tags beschränkt die Fragen, onExhausted legt
+// fest, wie es weitergeht, wenn keine neue Frage mehr verfügbar ist. Der Header x-user bestimmt
+// den Besitzer des Trainings.
 func (controller *Controller) Post(writer http.ResponseWriter, request *http.Request) {
-	if training, err := CreateTraining(controller.challengeProvider); err != nil {
-		httputils.InternalServerError(writer, request)
+	var requestDTO struct {
+		Tags        []string `json:"tags"`
+		OnExhausted string   `json:"onExhausted"`
+	}
+
+	if err := json.NewDecoder(request.Body).Decode(&requestDTO); err != nil && !errors.Is(err, io.EOF) {
+		utils.BadRequest(writer, request, "invalid request body")
+	} else if policy, err := ParseExhaustedPolicy(requestDTO.OnExhausted); err != nil {
+		utils.SendError(writer, request, err)
+	} else if training, err := CreateTrainingWithSettings(controller.challengeProvider, Settings{Tags: requestDTO.Tags, OnExhausted: policy, Owner: request.Header.Get("x-user")}); err != nil {
+		utils.SendError(writer, request, err)
 	} else if training, err := controller.repo.Save(request.Context(), training); err != nil {
-		httputils.InternalServerError(writer, request)
+		utils.SendError(writer, request, err)
 	} else {
 		httputils.CreatedJson(writer, request, createResponseDTO{
 			Id: training.Id,
@@ -43,12 +76,22 @@ func (controller *Controller) Post(writer http.ResponseWriter, request *http.Req
 	}
 }
 
+// GetAll liefert alle Trainings, archivierte nur mit ?archived=true
 func (controller *Controller) GetAll(writer http.ResponseWriter, request *http.Request) {
-	trainings, err := controller.repo.FindAllBy(request.Context(), predicates.True[*Training]())
+	predicate := predicates.Not(IsArchived())
+	if archived := request.URL.Query().Get("archived"); archived != "" {
+		if include, err := strconv.ParseBool(archived); err != nil {
+			utils.BadRequest(writer, request, "invalid archived flag, expected true or false")
+			return
+		} else if include {
+			predicate = predicates.True[*Training]()
+		}
+	}
+	trainings, err := controller.repo.FindAllBy(request.Context(), predicate)
 	if err != nil {
-		httputils.InternalServerError(writer, request)
+		utils.InternalServerError(writer, request, err)
 	} else {
-		httputils.CreatedJson(writer, request, collections.Map(trainings, mapGetTrainingDTO))
+		httputils.OkJson(writer, request, collections.Map(trainings, mapGetTrainingDTO))
 	}
 }
 
@@ -59,6 +102,10 @@ func mapGetTrainingDTO(t *Training) getTrainigDTO {
 		CurrentChallengeFailed: t.currentChallengeFailed,
 		CurrentLevel:           t.CurrentChallenge.Level,
 		CurrentCount:           t.CurrentChallenge.Count,
+		State:                  stateOf(t),
+		Tags:                   append([]string{}, t.Tags...),
+		OnExhausted:            t.OnExhausted,
+		Owner:                  t.Owner,
 		Updated:                t.Updated.Format(time.RFC3339),
 		Created:                t.Created.Format(time.RFC3339),
 		ChallengeStats: challengeStatsDTO{
@@ -71,11 +118,23 @@ func mapGetTrainingDTO(t *Training) getTrainigDTO {
 			t.Stats.totalChallenges,
 			t.Stats.passedChallenges,
 			t.Stats.failedChallenges,
+			t.Stats.skippedChallenges,
 			t.Stats.currentChallengeAttempts,
 		},
 	}
 }
 
+func stateOf(t *Training) string {
+	if t.Archived {
+		return "archived"
+	} else if t.Completed {
+		return "completed"
+	} else if t.IsPaused() {
+		return "paused"
+	}
+	return "active"
+}
+
 func (controller *Controller) PatchById(w http.ResponseWriter, r *http.Request) {
 	type responseDTO struct {
 		getTrainigDTO
@@ -87,66 +146,258 @@ func (controller *Controller) PatchById(w http.ResponseWriter, r *http.Request)
 	}
 
 	if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "missing training id")
 	} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "invalid training id")
 	} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
-		httputils.NotFound(w, r)
+		utils.NotFound(w, r, "training not found")
 	} else if err := json.NewDecoder(r.Body).Decode(&requestDTO); err != nil {
-		httputils.BadRequest(w, r)
-	} else if success, err := training.Next(requestDTO.Answer, controller.challengeProvider); err != nil {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "invalid request body")
+	} else if success, err := training.Next(r.Context(), requestDTO.Answer, controller.challengeProvider); err != nil {
+		utils.SendError(w, r, err)
 	} else if training, err = controller.repo.Save(r.Context(), training); err != nil {
-		httputils.InternalServerError(w, r)
+		utils.SendError(w, r, err)
 	} else {
+		countAnswer(success)
 		httputils.OkJson(w, r, responseDTO{mapGetTrainingDTO(training), success})
 	}
 }
 
+// GetById liefert den aktuellen Zustand eines Trainings oder, mit dem Parameter at (RFC3339),
+// den Zustand zu einem früheren Zeitpunkt
 func (controller *Controller) GetById(w http.ResponseWriter, r *http.Request) {
 	if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "missing training id")
 	} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "invalid training id")
+	} else if at := r.URL.Query().Get("at"); at != "" {
+		if timestamp, err := time.Parse(time.RFC3339, at); err != nil {
+			utils.BadRequest(w, r, "invalid timestamp, expected RFC3339")
+		} else if training, exists := controller.repo.FindAt(r.Context(), trainingUuid, timestamp); !exists {
+			utils.NotFound(w, r, "training not found")
+		} else {
+			httputils.OkJson(w, r, mapGetTrainingDTO(training))
+		}
 	} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
-		httputils.NotFound(w, r)
+		utils.NotFound(w, r, "training not found")
 	} else {
 		httputils.OkJson(w, r, mapGetTrainingDTO(training))
 	}
 }
 
+func (controller *Controller) GetTimelineById(w http.ResponseWriter, r *http.Request) {
+	type changeDto struct {
+		Type        ChangeType  `json:"type"`
+		Version     int         `json:"version"`
+		Timestamp   string      `json:"timestamp"`
+		ChallengeId uuid.UUID   `json:"challengeId"`
+		AnswerIds   []uuid.UUID `json:"answerIds,omitempty"`
+		Passed      bool        `json:"passed"`
+		Level       int         `json:"level"`
+		Due         string      `json:"due,omitempty"`
+		Done        bool        `json:"done"`
+		Tags        []string    `json:"tags,omitempty"`
+		Policy      string      `json:"policy,omitempty"`
+		Owner       string      `json:"owner,omitempty"`
+	}
+
+	mapChangeDTO := func(c Change) changeDto {
+		dto := changeDto{
+			Type:        c.Type,
+			Version:     c.Version,
+			Timestamp:   c.Timestamp.Format(time.RFC3339),
+			ChallengeId: c.ChallengeId,
+			AnswerIds:   c.AnswerIds,
+			Passed:      c.Passed,
+			Level:       c.Level,
+			Done:        c.Done,
+			Tags:        c.Tags,
+			Policy:      string(c.Policy),
+			Owner:       c.Owner,
+		}
+		if !c.Due.IsZero() {
+			dto.Due = c.Due.Format(time.RFC3339)
+		}
+		return dto
+	}
+
+	type responseDTO struct {
+		Id      uuid.UUID   `json:"id"`
+		Changes []changeDto `json:"changes"`
+	}
+
+	if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
+		utils.BadRequest(w, r, "missing training id")
+	} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
+		utils.BadRequest(w, r, "invalid training id")
+	} else if changes, exists := controller.repo.Timeline(r.Context(), trainingUuid); !exists {
+		utils.NotFound(w, r, "training not found")
+	} else {
+		httputils.OkJson(w, r, responseDTO{
+			Id:      trainingUuid,
+			Changes: collections.Map(changes, mapChangeDTO),
+		})
+	}
+}
+
 func (controller *Controller) GetChallengesById(w http.ResponseWriter, r *http.Request) {
+	if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
+		utils.BadRequest(w, r, "missing training id")
+	} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
+		utils.BadRequest(w, r, "invalid training id")
+	} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
+		utils.NotFound(w, r, "training not found")
+	} else {
+		httputils.OkJson(w, r, challengesDTO{
+			Id:         training.Id,
+			Challenges: collections.Map(training.Challenges, mapChallengeDTO),
+		})
+	}
+}
 
-	type challengeDto struct {
-		Id    uuid.UUID `json:"id"`
-		Level int       `json:"level"`
-		Count int       `json:"count"`
-		Done  bool      `json:"done"`
+// GetBookmarksById liefert die vorgemerkten Challenges eines Trainings
+func (controller *Controller) GetBookmarksById(w http.ResponseWriter, r *http.Request) {
+	if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
+		utils.BadRequest(w, r, "missing training id")
+	} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
+		utils.BadRequest(w, r, "invalid training id")
+	} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
+		utils.NotFound(w, r, "training not found")
+	} else {
+		httputils.OkJson(w, r, challengesDTO{
+			Id:         training.Id,
+			Challenges: collections.Map(training.Bookmarks(), mapChallengeDTO),
+		})
 	}
+}
 
-	mapChallengeDTO := func(c *TrainingChallenge) challengeDto {
-		return challengeDto{Id: c.Id, Level: c.Level, Count: c.Count, Done: c.Done}
+// GetDueById liefert die offenen Challenges eines Trainings, die am frühesten fällige zuerst
+func (controller *Controller) GetDueById(w http.ResponseWriter, r *http.Request) {
+	type dueDTO struct {
+		Id      uuid.UUID `json:"id"`
+		Level   int       `json:"level"`
+		Count   int       `json:"count"`
+		Due     string    `json:"due"`
+		Overdue bool      `json:"overdue"`
+		Current bool      `json:"current"`
 	}
 
 	type responseDTO struct {
-		Id         uuid.UUID      `json:"id"`
-		Challenges []challengeDto `json:"challenges"`
+		Id         uuid.UUID `json:"id"`
+		Challenges []dueDTO  `json:"challenges"`
 	}
 
 	if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "missing training id")
 	} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
-		httputils.BadRequest(w, r)
+		utils.BadRequest(w, r, "invalid training id")
 	} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
-		httputils.NotFound(w, r)
+		utils.NotFound(w, r, "training not found")
 	} else {
+		// in einer Pause ruht die Zeit, überfällig ist, was zu Beginn der Pause fällig war
+		now := time.Now()
+		if training.IsPaused() {
+			now = training.Paused
+		}
 		httputils.OkJson(w, r, responseDTO{
-			Id:         training.Id,
-			Challenges: collections.Map(training.Challenges, mapChallengeDTO),
+			Id: training.Id,
+			Challenges: collections.Map(training.DueQueue(), func(c *TrainingChallenge) dueDTO {
+				return dueDTO{
+					Id:      c.Id,
+					Level:   c.Level,
+					Count:   c.Count,
+					Due:     c.Timestamp.Format(time.RFC3339),
+					Overdue: !c.Timestamp.After(now),
+					Current: c.Id == training.CurrentChallenge.Id,
+				}
+			}),
 		})
 	}
 }
 
+// action führt eine Operation auf dem Training aus, speichert es und liefert den neuen Zustand.
+// Ist die Operation im aktuellen Zustand nicht möglich, antwortet sie mit 409.
+func (controller *Controller) action(operation func(*Training, context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
+			utils.BadRequest(w, r, "missing training id")
+		} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
+			utils.BadRequest(w, r, "invalid training id")
+		} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
+			utils.NotFound(w, r, "training not found")
+		} else if err := operation(training, r.Context()); err != nil {
+			utils.SendError(w, r, err)
+		} else if training, err = controller.repo.Save(r.Context(), training); err != nil {
+			utils.SendError(w, r, err)
+		} else {
+			httputils.OkJson(w, r, mapGetTrainingDTO(training))
+		}
+	}
+}
+
+func (controller *Controller) skip(training *Training, ctx context.Context) error {
+	return training.Skip(ctx, controller.challengeProvider)
+}
+
+// ignoreContext passt Operationen ohne Log-Ausgaben an action an
+func ignoreContext(operation func(*Training) error) func(*Training, context.Context) error {
+	return func(training *Training, _ context.Context) error {
+		return operation(training)
+	}
+}
+
+// bookmark setzt oder entfernt das Lesezeichen der Challenge aus dem optionalen Body
+// {"challengeId": ...}, ohne Angabe das der aktuellen Challenge. Geliefert werden alle
+// vorgemerkten Challenges.
+func (controller *Controller) bookmark(bookmarked bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestDTO struct {
+			ChallengeId uuid.UUID `json:"challengeId"`
+		}
+
+		if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
+			utils.BadRequest(w, r, "missing training id")
+		} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
+			utils.BadRequest(w, r, "invalid training id")
+		} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
+			utils.NotFound(w, r, "training not found")
+		} else if err := json.NewDecoder(r.Body).Decode(&requestDTO); err != nil && !errors.Is(err, io.EOF) {
+			utils.BadRequest(w, r, "invalid request body")
+		} else if err := training.Bookmark(challengeOrCurrent(requestDTO.ChallengeId, training), bookmarked); err != nil {
+			utils.SendError(w, r, err)
+		} else if training, err = controller.repo.Save(r.Context(), training); err != nil {
+			utils.SendError(w, r, err)
+		} else {
+			httputils.OkJson(w, r, challengesDTO{
+				Id:         training.Id,
+				Challenges: collections.Map(training.Bookmarks(), mapChallengeDTO),
+			})
+		}
+	}
+}
+
+func challengeOrCurrent(challengeId uuid.UUID, training *Training) uuid.UUID {
+	if challengeId == uuid.Nil {
+		return training.CurrentChallenge.Id
+	}
+	return challengeId
+}
+
+// DeleteById löscht ein Training endgültig mit seiner Timeline
+func (controller *Controller) DeleteById(w http.ResponseWriter, r *http.Request) {
+	if trainingId, exists := routing.GetParameter(r.Context(), "trainingId"); !exists {
+		utils.BadRequest(w, r, "missing training id")
+	} else if trainingUuid, err := uuid.Parse(trainingId); err != nil {
+		utils.BadRequest(w, r, "invalid training id")
+	} else if training, exists := controller.repo.FindFirst(r.Context(), IdEquals(trainingUuid)); !exists {
+		utils.NotFound(w, r, "training not found")
+	} else if err := controller.repo.Delete(r.Context(), training); err != nil {
+		utils.InternalServerError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 type createResponseDTO struct {
 	Id uuid.UUID `json:"id"`
 }
@@ -155,9 +406,27 @@ type statsDTO struct {
 	TotalChallenges          int `json:"total"`
 	PassedChallenged         int `json:"passed"`
 	FailedChallenges         int `json:"failed"`
+	SkippedChallenges        int `json:"skipped"`
 	CurrentChallengeAttempts int `json:"currentAttempts"`
 }
 
+type challengeDTO struct {
+	Id         uuid.UUID `json:"id"`
+	Level      int       `json:"level"`
+	Count      int       `json:"count"`
+	Done       bool      `json:"done"`
+	Bookmarked bool      `json:"bookmarked"`
+}
+
+func mapChallengeDTO(c *TrainingChallenge) challengeDTO {
+	return challengeDTO{Id: c.Id, Level: c.Level, Count: c.Count, Done: c.Done, Bookmarked: c.Bookmarked}
+}
+
+type challengesDTO struct {
+	Id         uuid.UUID      `json:"id"`
+	Challenges []challengeDTO `json:"challenges"`
+}
+
 type challengeStatsDTO struct {
 	Total      int `json:"total"`
 	Initial    int `json:"initial"`
@@ -171,6 +440,10 @@ type getTrainigDTO struct {
 	CurrentChallengeFailed bool              `json:"currentChallengeFailed"`
 	CurrentLevel           int               `json:"currentLevel"`
 	CurrentCount           int               `json:"currentCount"`
+	State                  string            `json:"state"`
+	Tags                   []string          `json:"tags"`
+	OnExhausted            ExhaustedPolicy   `json:"onExhausted"`
+	Owner                  string            `json:"owner,omitempty"`
 	Updated                string            `json:"updated"`
 	Created                string            `json:"created"`
 	Stats                  statsDTO          `json:"stats"`
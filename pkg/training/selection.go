@@ -0,0 +1,202 @@
+package training
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/collections"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SelectionStrategy wählt aus den passenden Fragen die nächste Challenge. candidates ist nie
+// leer und nach Id sortiert, damit eine Auswahl mit festem Seed reproduzierbar ist.
+type SelectionStrategy interface {
+	Select(query ChallengeQuery, candidates []*questions.Question) *questions.Question
+}
+
+// RandomSource ist ein Zufallsgenerator, den mehrere Requests gleichzeitig verwenden können
+type RandomSource struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// NewRandomSource liefert einen Zufallsgenerator, der mit demselben Seed dieselbe Folge liefert
+func NewRandomSource(seed int64) *RandomSource {
+	return &RandomSource{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (source *RandomSource) Intn(n int) int {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	return source.rand.Intn(n)
+}
+
+func (source *RandomSource) Float64() float64 {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	return source.rand.Float64()
+}
+
+type uniformSelection struct {
+	random *RandomSource
+}
+
+// UniformSelection wählt jede passende Frage mit derselben Wahrscheinlichkeit
+func UniformSelection(random *RandomSource) SelectionStrategy {
+	return uniformSelection{random}
+}
+
+func (selection uniformSelection) Select(_ ChallengeQuery, candidates []*questions.Question) *questions.Question {
+	return candidates[selection.random.Intn(len(candidates))]
+}
+
+// Difficulty liefert den Anteil falscher Antworten auf eine Frage über alle Trainings
+type Difficulty interface {
+	FailureRate(questionId uuid.UUID) float64
+}
+
+type difficultyWeightedSelection struct {
+	difficulty Difficulty
+	random     *RandomSource
+}
+
+// DifficultyWeightedSelection bevorzugt Fragen, die häufig falsch beantwortet werden. Eine Frage,
+// die immer falsch beantwortet wird, kommt fünfmal so oft dran wie eine, die nie falsch ist.
+func DifficultyWeightedSelection(difficulty Difficulty, random *RandomSource) SelectionStrategy {
+	return difficultyWeightedSelection{difficulty, random}
+}
+
+func (selection difficultyWeightedSelection) Select(_ ChallengeQuery, candidates []*questions.Question) *questions.Question {
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for index, candidate := range candidates {
+		weights[index] = 1 + 4*selection.difficulty.FailureRate(candidate.Id)
+		total += weights[index]
+	}
+	threshold := selection.random.Float64() * total
+	for index, weight := range weights {
+		if threshold < weight {
+			return candidates[index]
+		}
+		threshold -= weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+type tagBalancedSelection struct {
+	repo   questions.Repository
+	random *RandomSource
+}
+
+// TagBalancedSelection wählt eine Frage aus dem Tag, der im Training bisher am seltensten
+// vorkam. So verteilen sich die Fragen gleichmäßig auf die Prüfungsbereiche.
+func TagBalancedSelection(repo questions.Repository, random *RandomSource) SelectionStrategy {
+	return tagBalancedSelection{repo, random}
+}
+
+func (selection tagBalancedSelection) Select(query ChallengeQuery, candidates []*questions.Question) *questions.Question {
+	used := make(map[string]int)
+	previous, _ := selection.repo.FindAll(predicates.Not(questions.IdNotIn(query.ExcludeIds)))
+	for _, question := range previous {
+		for _, tag := range tagsOf(question) {
+			used[tag]++
+		}
+	}
+
+	var tags []string
+	for _, candidate := range candidates {
+		for _, tag := range tagsOf(candidate) {
+			if !utils.Contains(tags, tag) && (len(query.Tags) == 0 || utils.Contains(query.Tags, tag)) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return UniformSelection(selection.random).Select(query, candidates)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return used[tags[i]] < used[tags[j]] || used[tags[i]] == used[tags[j]] && tags[i] < tags[j]
+	})
+	rarest := collections.Filter(tags, func(tag string) bool { return used[tag] == used[tags[0]] })
+	tag := rarest[selection.random.Intn(len(rarest))]
+	return UniformSelection(selection.random).Select(query, collections.Filter(candidates, func(q *questions.Question) bool {
+		return utils.Contains(tagsOf(q), tag)
+	}))
+}
+
+// tagsOf liefert die Tags einer Frage, Fragen ohne Tag bilden eine eigene Gruppe
+func tagsOf(question *questions.Question) []string {
+	if len(question.Tags) == 0 {
+		return []string{""}
+	}
+	return question.Tags
+}
+
+type unseenFirstSelection struct {
+	trainings Repository
+	next      SelectionStrategy
+}
+
+// UnseenFirstSelection beschränkt die Auswahl auf Fragen, die in keinem Training des Benutzers
+// vorkamen, und überlässt die Wahl next. Ohne Benutzer oder ohne ungesehene Frage wählt next
+// unter allen Kandidaten.
+func UnseenFirstSelection(trainings Repository, next SelectionStrategy) SelectionStrategy {
+	return unseenFirstSelection{trainings, next}
+}
+
+func (selection unseenFirstSelection) Select(query ChallengeQuery, candidates []*questions.Question) *questions.Question {
+	if query.Owner == "" {
+		return selection.next.Select(query, candidates)
+	}
+	seen := make(map[uuid.UUID]bool)
+	owned, _ := selection.trainings.FindAllBy(context.Background(), OwnedBy(query.Owner))
+	for _, training := range owned {
+		for _, challenge := range training.AllChallenges() {
+			seen[challenge.Id] = true
+		}
+	}
+	if unseen := collections.Filter(candidates, func(q *questions.Question) bool { return !seen[q.Id] }); len(unseen) > 0 {
+		return selection.next.Select(query, unseen)
+	}
+	return selection.next.Select(query, candidates)
+}
+
+// ConfiguredSelection erstellt die Strategie aus SELECTION_STRATEGY (uniform, difficulty oder
+// tag-balanced) und SELECTION_PREFER_UNSEEN. Mit SELECTION_SEED ist die Auswahl reproduzierbar.
+func ConfiguredSelection(questionRepo questions.Repository, trainingRepo Repository, difficulty Difficulty) (SelectionStrategy, error) {
+	seed := time.Now().UnixNano()
+	if value := utils.GetEnvOrDefault("SELECTION_SEED", ""); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SELECTION_SEED %s: %w", value, err)
+		}
+		seed = parsed
+	}
+	random := NewRandomSource(seed)
+
+	var strategy SelectionStrategy
+	switch name := utils.GetEnvOrDefault("SELECTION_STRATEGY", "uniform"); name {
+	case "uniform":
+		strategy = UniformSelection(random)
+	case "difficulty":
+		strategy = DifficultyWeightedSelection(difficulty, random)
+	case "tag-balanced":
+		strategy = TagBalancedSelection(questionRepo, random)
+	default:
+		return nil, fmt.Errorf("unknown SELECTION_STRATEGY %s", name)
+	}
+
+	if preferUnseen, err := strconv.ParseBool(utils.GetEnvOrDefault("SELECTION_PREFER_UNSEEN", "false")); err != nil {
+		return nil, fmt.Errorf("invalid SELECTION_PREFER_UNSEEN: %w", err)
+	} else if preferUnseen {
+		strategy = UnseenFirstSelection(trainingRepo, strategy)
+	}
+	return strategy, nil
+}
@@ -0,0 +1,97 @@
+package training
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+func createQuestionRepo(t *testing.T, tags ...[]string) (questions.Repository, []*questions.Question) {
+	repo, err := questions.CreateRepo(filepath.Join(t.TempDir(), "question.data"))
+	utils.AssertNoError(t, err, "create question repository failed")
+	t.Cleanup(func() { _ = repo.Close() })
+	var saved []*questions.Question
+	for index, questionTags := range tags {
+		options := []questions.Option{{Id: uuid.New(), Option: "a"}, {Id: uuid.New(), Option: "b"}}
+		question, err := repo.Save(questions.CreateQuestion(uuid.NewString(), options, []uuid.UUID{options[0].Id}, nil, questionTags))
+		utils.AssertNoError(t, err, "save question %d failed", index)
+		saved = append(saved, question)
+	}
+	return repo, saved
+}
+
+func selectIds(t *testing.T, provider ChallengeProvider, count int) (ids []uuid.UUID) {
+	for i := 0; i < count; i++ {
+		challenge, err := provider(ChallengeQuery{})
+		utils.AssertNoError(t, err, "select failed")
+		ids = append(ids, challenge.Id)
+	}
+	return ids
+}
+
+func TestSeededSelectionIsReproducible(t *testing.T) {
+	repo, _ := createQuestionRepo(t, nil, nil, nil, nil, nil)
+	first := selectIds(t, SelectingQuestionProvider(repo, UniformSelection(NewRandomSource(42))), 20)
+	second := selectIds(t, SelectingQuestionProvider(repo, UniformSelection(NewRandomSource(42))), 20)
+	for index := range first {
+		utils.Assert(t, first[index] == second[index], "selection %d differs with the same seed", index)
+	}
+}
+
+func TestSelectingProviderReportsExhaustedPool(t *testing.T) {
+	repo, saved := createQuestionRepo(t, nil)
+	provider := SelectingQuestionProvider(repo, UniformSelection(NewRandomSource(1)))
+	_, err := provider(ChallengeQuery{ExcludeIds: []uuid.UUID{saved[0].Id}})
+	utils.Assert(t, err == ErrPoolExhausted, "expected exhausted pool, got %v", err)
+}
+
+type fixedDifficulty map[uuid.UUID]float64
+
+func (difficulty fixedDifficulty) FailureRate(questionId uuid.UUID) float64 {
+	return difficulty[questionId]
+}
+
+func TestDifficultyWeightedSelectionPrefersHardQuestions(t *testing.T) {
+	repo, saved := createQuestionRepo(t, nil, nil, nil)
+	provider := SelectingQuestionProvider(repo, DifficultyWeightedSelection(fixedDifficulty{saved[0].Id: 1}, NewRandomSource(7)))
+	counts := make(map[uuid.UUID]int)
+	for _, id := range selectIds(t, provider, 700) {
+		counts[id]++
+	}
+	// erwartet werden 500 zu 100 zu 100
+	utils.Assert(t, counts[saved[0].Id] > 400, "hard question selected only %d times", counts[saved[0].Id])
+	utils.Assert(t, counts[saved[1].Id] > 0 && counts[saved[2].Id] > 0, "easy questions never selected")
+}
+
+func TestTagBalancedSelectionPicksRarestTag(t *testing.T) {
+	repo, saved := createQuestionRepo(t, []string{"network"}, []string{"network"}, []string{"crypto"}, []string{"crypto"})
+	provider := SelectingQuestionProvider(repo, TagBalancedSelection(repo, NewRandomSource(3)))
+	for i := 0; i < 10; i++ {
+		challenge, err := provider(ChallengeQuery{ExcludeIds: []uuid.UUID{saved[0].Id}})
+		utils.AssertNoError(t, err, "select failed")
+		utils.Assert(t, challenge.Id == saved[2].Id || challenge.Id == saved[3].Id, "expected crypto question, got %s", challenge.Id)
+	}
+}
+
+func TestUnseenFirstSelectionSkipsQuestionsOfOwner(t *testing.T) {
+	questionRepo, saved := createQuestionRepo(t, nil, nil)
+	trainingRepo, err := CreateFileRepository(filepath.Join(t.TempDir(), "trainings.data"))
+	utils.AssertNoError(t, err, "create training repository failed")
+	defer trainingRepo.Close()
+
+	seen := sequenceProvider(Challenge{Id: saved[0].Id, Answer: saved[0].AnswerIds})
+	training, err := CreateTrainingWithSettings(seen, Settings{OnExhausted: CompleteOnExhausted, Owner: "alice"})
+	utils.AssertNoError(t, err, "create training failed")
+	_, err = trainingRepo.Save(context.Background(), training)
+	utils.AssertNoError(t, err, "save training failed")
+
+	provider := SelectingQuestionProvider(questionRepo, UnseenFirstSelection(trainingRepo, UniformSelection(NewRandomSource(5))))
+	for i := 0; i < 10; i++ {
+		challenge, err := provider(ChallengeQuery{Owner: "alice"})
+		utils.AssertNoError(t, err, "select failed")
+		utils.Assert(t, challenge.Id == saved[1].Id, "expected unseen question, got %s", challenge.Id)
+	}
+}
@@ -2,18 +2,55 @@ package training
 
 import (
 	"context"
+	"errors"
 	"github.com/mwildt/ceh-utils/pkg/events"
 	"github.com/mwildt/ceh-utils/pkg/questions"
 	"github.com/mwildt/ceh-utils/pkg/utils"
+	"github.com/ohrenpiraten/go-collections/predicates"
+	"sort"
+	"time"
 )
 
+// QuestionProvider wählt zufällig eine Frage, die noch nicht Teil des Trainings ist und einen
+// der Tags trägt
+func QuestionProvider(repo questions.Repository) ChallengeProvider {
+	return SelectingQuestionProvider(repo, UniformSelection(NewRandomSource(time.Now().UnixNano())))
+}
+
+// SelectingQuestionProvider überlässt die Wahl unter den passenden Fragen der Strategie
+func SelectingQuestionProvider(repo questions.Repository, strategy SelectionStrategy) ChallengeProvider {
+	return func(query ChallengeQuery) (Challenge, error) {
+		candidates, err := repo.FindAll(predicates.And(questions.IdNotIn(query.ExcludeIds), questions.HasAnyTag(query.Tags)))
+		if err != nil {
+			return Challenge{}, err
+		} else if len(candidates) == 0 {
+			return Challenge{}, ErrPoolExhausted
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Id.String() < candidates[j].Id.String()
+		})
+		q := strategy.Select(query, candidates)
+		return Challenge{Id: q.Id, Answer: q.AnswerIds}, nil
+	}
+}
+
 func Subscribe(repository Repository) (err error) {
 
 	logger := utils.NewStdLogger("trainings.service")
 	eventType := "question.updated"
 
-	err = events.Subscribe(eventType, func(event questions.UpdatedEvent) error {
-		logger.Info("handle event %s for id %s", eventType, event.QuestionId)
+	if err = events.Subscribe(eventType, updateChallengeAnswers(repository, logger)); err != nil {
+		return err
+	}
+	logger.Info("successfully registered to %s", eventType)
+	return nil
+}
+
+// updateChallengeAnswers übernimmt die geänderte Antwort in alle Trainings mit der Frage, auch wenn
+// das Speichern einzelner Trainings fehlschlägt
+func updateChallengeAnswers(repository Repository, logger utils.Logger) func(event questions.UpdatedEvent) error {
+	return func(event questions.UpdatedEvent) error {
+		logger.Info("handle event question.updated for id %s", event.QuestionId)
 
 		trainings, err := repository.FindAllBy(context.Background(), ContainsChallenge(event.QuestionId))
 		if err != nil {
@@ -22,15 +59,9 @@ func Subscribe(repository Repository) (err error) {
 		}
 		for _, training := range trainings {
 			training.updateChallengeAnswer(event.QuestionId, event.AnswerIds)
-			_, err = repository.Save(context.Background(), training)
-			return err
+			_, saveErr := repository.Save(context.Background(), training)
+			err = errors.Join(err, saveErr)
 		}
 		return err
-	})
-
-	if err != nil {
-		return err
 	}
-	logger.Info("successfully registered to %s", eventType)
-	return nil
 }
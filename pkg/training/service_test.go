@@ -0,0 +1,41 @@
+package training
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mwildt/ceh-utils/pkg/questions"
+	"github.com/mwildt/ceh-utils/pkg/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateChallengeAnswersUpdatesAllTrainings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trainings.data")
+	repo, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "unable to create repository")
+
+	challenges := createChallenges(2)
+	provider := poolProvider(challenges, nil)
+	for i := 0; i < 2; i++ {
+		training, err := CreateTraining(provider)
+		utils.AssertNoError(t, err, "create training %d failed", i)
+		_, err = repo.Save(context.Background(), training)
+		utils.AssertNoError(t, err, "save training %d failed", i)
+	}
+
+	answer := []uuid.UUID{uuid.New()}
+	handle := updateChallengeAnswers(repo, utils.NewStdLogger("test"))
+	utils.AssertNoError(t, handle(questions.UpdatedEvent{QuestionId: challenges[0].Id, AnswerIds: answer}), "update failed")
+
+	utils.AssertNoError(t, repo.Close(), "close failed")
+
+	reopened, err := CreateFileRepository(path)
+	utils.AssertNoError(t, err, "unable to reopen repository")
+	defer reopened.Close()
+	trainings, err := reopened.FindAllBy(context.Background(), ContainsChallenge(challenges[0].Id))
+	utils.AssertNoError(t, err, "find trainings failed")
+	utils.Assert(t, len(trainings) == 2, "expected 2 trainings, got %d", len(trainings))
+	for i, training := range trainings {
+		utils.Assert(t, training.CurrentChallenge.Answer[0] == answer[0], "answer of training %d not updated", i)
+	}
+}
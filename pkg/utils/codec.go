@@ -0,0 +1,396 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RecordLog ist eine Datei, an die JSON-Datensätze angehängt werden
+type RecordLog interface {
+	Append(record []byte) error
+	Sync() error
+	Close() error
+	Name() string
+}
+
+// Codec legt fest, wie JSON-Datensätze in einer Datei abgelegt werden
+type Codec interface {
+	Name() string
+	Open(path string, policy SyncPolicy) (RecordLog, error)
+	Create(path string, policy SyncPolicy) (RecordLog, error)
+	Load(path string, consumer func(record []byte) error) (count int, err error)
+	Recover(path string) (RecoveryReport, error)
+}
+
+const (
+	CodecBinary    = "binary"
+	CodecJsonLines = "jsonl"
+)
+
+var ErrInvalidRecord = errors.New("invalid record")
+
+var ErrUnknownCodec = errors.New("unable to detect the codec of the log file")
+
+// BinaryCodec speichert Base64-JSON in den Frames der Log-Dateien (CEHLOG, auch das alte Format)
+var BinaryCodec Codec = binaryCodec{}
+
+// JsonLinesCodec speichert einen JSON-Datensatz je Zeile
+var JsonLinesCodec Codec = jsonLinesCodec{}
+
+func CodecByName(name string) (Codec, error) {
+	switch name {
+	case CodecBinary:
+		return BinaryCodec, nil
+	case CodecJsonLines:
+		return JsonLinesCodec, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %s", name)
+	}
+}
+
+// ConfiguredCodec liefert den Codec für neue Dateien (STORAGE_CODEC, Default binary)
+func ConfiguredCodec() (Codec, error) {
+	return CodecByName(GetEnvOrDefault("STORAGE_CODEC", CodecBinary))
+}
+
+// DetectCodec erkennt den Codec einer bestehenden Datei. Dateien mit dem CEHLOG-Header sind binär,
+// JSON Lines nur dann, wenn die erste vollständige Zeile gültiges JSON ist. Dateien im alten Format
+// haben keinen Header und werden am ersten Datensatz erkannt. Lässt sich der Codec nicht sicher
+// bestimmen, wird ErrUnknownCodec geliefert, damit die Datei nicht mit dem falschen Codec repariert
+// wird. Für leere oder fehlende Dateien ist detected falsch.
+func DetectCodec(path string) (codec Codec, detected bool, err error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+	header := make([]byte, logHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if n == 0 && errors.Is(err, io.EOF) {
+		return nil, false, nil
+	} else if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, false, err
+	}
+	if bytes.HasPrefix(header[:n], logMagic) {
+		return BinaryCodec, true, nil
+	}
+	if valid, err := firstLineIsJson(file); err != nil {
+		return nil, false, err
+	} else if valid {
+		return JsonLinesCodec, true, nil
+	}
+	if legacy, err := firstLegacyFrameIsValid(file); err != nil {
+		return nil, false, err
+	} else if legacy || !startsLikeJson(header[:n]) {
+		return BinaryCodec, true, nil
+	}
+	return nil, false, fmt.Errorf("%s: %w", path, ErrUnknownCodec)
+}
+
+func startsLikeJson(prefix []byte) bool {
+	trimmed := bytes.TrimLeft(prefix, " \t\r\n")
+	return len(trimmed) == 0 || trimmed[0] == '{'
+}
+
+// prüft die erste nicht leere, vollständige Zeile
+func firstLineIsJson(file *os.File) (bool, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		} else if len(bytes.TrimSpace(line)) > 0 {
+			return json.Valid(line), nil
+		}
+	}
+}
+
+// prüft, ob die Datei mit einem vollständigen Datensatz im alten Format (LEN(4) DATA(LEN)) beginnt
+func firstLegacyFrameIsValid(file *os.File) (bool, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	header := make([]byte, 4)
+	if _, err = file.ReadAt(header, 0); errors.Is(err, io.EOF) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	size := int64(binary.LittleEndian.Uint32(header))
+	if size == 0 || 4+size > stat.Size() {
+		return false, nil
+	}
+	data := make([]byte, size)
+	if _, err = file.ReadAt(data, 4); err != nil {
+		return false, err
+	}
+	record, err := decodeB64(data)
+	return err == nil && json.Valid(record), nil
+}
+
+// CodecFor liefert den Codec einer bestehenden Datei, für neue Dateien den bevorzugten
+func CodecFor(path string, preferred Codec) (Codec, error) {
+	codec, detected, err := DetectCodec(path)
+	if err != nil || !detected {
+		return preferred, err
+	}
+	return codec, nil
+}
+
+// Reparaturen schneiden Daten ab, deshalb nur mit dem sicher erkannten Codec der Datei
+func ensureCodec(path string, expected Codec) error {
+	codec, detected, err := DetectCodec(path)
+	if err != nil {
+		return err
+	} else if detected && codec != expected {
+		return fmt.Errorf("%s is a %s file, not %s", path, codec.Name(), expected.Name())
+	}
+	return nil
+}
+
+// LoadRecords liest die JSON-Datensätze einer Datei im jeweils erkannten Format
+func LoadRecords(path string, consumer func(record []byte) error) (count int, err error) {
+	codec, err := CodecFor(path, BinaryCodec)
+	if err != nil {
+		return 0, err
+	}
+	return codec.Load(path, consumer)
+}
+
+// ConvertLogFile schreibt eine Datei mit dem Ziel-Codec neu. Die neue Datei ersetzt die alte erst,
+// wenn sie vollständig geschrieben ist.
+func ConvertLogFile(path string, target Codec) (source Codec, count int, err error) {
+	if source, err = CodecFor(path, BinaryCodec); err != nil {
+		return source, 0, err
+	}
+	err = CompactLogFile(path, target, func(log RecordLog) (err error) {
+		count, err = source.Load(path, log.Append)
+		return err
+	})
+	return source, count, err
+}
+
+type binaryCodec struct{}
+
+type binaryLog struct {
+	*LogFile
+}
+
+func (log binaryLog) Append(record []byte) error {
+	return log.LogFile.Append(encodeB64(record))
+}
+
+func (binaryCodec) Name() string {
+	return CodecBinary
+}
+
+func (binaryCodec) Open(path string, policy SyncPolicy) (RecordLog, error) {
+	log, err := OpenLogFile(path, policy)
+	if err != nil {
+		return nil, err
+	}
+	return binaryLog{log}, nil
+}
+
+func (binaryCodec) Create(path string, policy SyncPolicy) (RecordLog, error) {
+	log, err := CreateLogFile(path, policy)
+	if err != nil {
+		return nil, err
+	}
+	return binaryLog{log}, nil
+}
+
+func (binaryCodec) Load(path string, consumer func(record []byte) error) (int, error) {
+	return LoadFromFile(path, func(data []byte) error {
+		record, err := decodeB64(data)
+		if err != nil {
+			return err
+		}
+		return consumer(record)
+	})
+}
+
+func (codec binaryCodec) Recover(path string) (RecoveryReport, error) {
+	if err := ensureCodec(path, codec); err != nil {
+		return RecoveryReport{}, err
+	}
+	return RecoverLogFile(path)
+}
+
+type jsonLinesCodec struct{}
+
+type jsonLinesLog struct {
+	file   *os.File
+	policy SyncPolicy
+}
+
+func (jsonLinesCodec) Name() string {
+	return CodecJsonLines
+}
+
+func (jsonLinesCodec) Open(path string, policy SyncPolicy) (RecordLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLinesLog{file: file, policy: policy}, nil
+}
+
+func (jsonLinesCodec) Create(path string, policy SyncPolicy) (RecordLog, error) {
+	file, err := os.OpenFile(path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLinesLog{file: file, policy: policy}, nil
+}
+
+// Append schreibt den Datensatz kompakt in eine Zeile, mit einem einzigen write
+func (log *jsonLinesLog) Append(record []byte) error {
+	line := bytes.Buffer{}
+	if err := json.Compact(&line, record); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+	}
+	line.WriteByte('\n')
+	if _, err := log.file.Write(line.Bytes()); err != nil {
+		return err
+	}
+	if log.policy == SyncAlways {
+		return log.file.Sync()
+	}
+	return nil
+}
+
+func (log *jsonLinesLog) Sync() error {
+	return log.file.Sync()
+}
+
+func (log *jsonLinesLog) Close() error {
+	return log.file.Close()
+}
+
+func (log *jsonLinesLog) Name() string {
+	return log.file.Name()
+}
+
+// readLines liefert alle vollständigen Zeilen und den Offset hinter der letzten vollständigen Zeile
+func readLines(path string, consumer func(line []byte, number int) error) (offset int64, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				return offset, io.ErrUnexpectedEOF
+			}
+			return offset, nil
+		} else if err != nil {
+			return offset, err
+		}
+		offset += int64(len(line))
+		if err = consumer(bytes.TrimRight(line, "\r\n"), number); err != nil {
+			return offset, err
+		}
+	}
+}
+
+// Load liest die Datensätze, leere Zeilen werden übersprungen. Eine unvollständige letzte Zeile
+// oder ungültiges JSON führen zum Abbruch.
+func (jsonLinesCodec) Load(path string, consumer func(record []byte) error) (count int, err error) {
+	_, err = readLines(path, func(line []byte, number int) error {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		} else if !json.Valid(line) {
+			return fmt.Errorf("%s line %d: %w", path, number, ErrInvalidRecord)
+		} else if err := consumer(line); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return count, fmt.Errorf("%s: incomplete last line: %w", path, err)
+	}
+	return count, err
+}
+
+// Recover schneidet eine unvollständige letzte Zeile ab und entfernt Zeilen mit ungültigem JSON.
+// Beides wird vorher in die Datei <path>.quarantine übernommen.
+func (codec jsonLinesCodec) Recover(path string) (report RecoveryReport, err error) {
+	if err = ensureCodec(path, codec); err != nil {
+		return report, err
+	}
+	var valid, corrupt [][]byte
+	offset, err := readLines(path, func(line []byte, _ int) error {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		} else if json.Valid(line) {
+			report.Records++
+			valid = append(valid, append([]byte{}, line...))
+		} else {
+			report.Quarantined++
+			corrupt = append(corrupt, append([]byte{}, line...))
+		}
+		return nil
+	})
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		tail, err := readFrom(path, offset)
+		if err != nil {
+			return report, err
+		}
+		report.TruncatedBytes = int64(len(tail))
+		corrupt = append(corrupt, tail)
+	} else if err != nil {
+		return report, err
+	}
+	if !report.Modified() {
+		return report, nil
+	}
+
+	if err = appendLines(path+".quarantine", corrupt); err != nil {
+		return report, err
+	}
+	if report.Quarantined == 0 {
+		return report, os.Truncate(path, offset)
+	}
+	recoverPath := path + ".recover"
+	if err = os.Remove(recoverPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return report, err
+	} else if err = appendLines(recoverPath, valid); err != nil {
+		return report, err
+	} else if err = os.Rename(recoverPath, path); err != nil {
+		return report, err
+	}
+	return report, SyncDir(filepath.Dir(path))
+}
+
+func appendLines(path string, lines [][]byte) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err = file.Write(append(append([]byte{}, line...), '\n')); err != nil {
+			return errors.Join(err, file.Close())
+		}
+	}
+	return errors.Join(file.Sync(), file.Close())
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCodecRecords(t *testing.T, codec Codec, path string, records ...string) {
+	log, err := codec.Create(path, SyncNever)
+	AssertNoError(t, err, "create %s failed", codec.Name())
+	for _, record := range records {
+		AssertNoError(t, log.Append([]byte(record)), "append failed")
+	}
+	AssertNoError(t, log.Close(), "close failed")
+}
+
+func loadRecords(t *testing.T, path string) []string {
+	var records []string
+	_, err := LoadRecords(path, func(record []byte) error {
+		records = append(records, string(record))
+		return nil
+	})
+	AssertNoError(t, err, "load %s failed", path)
+	return records
+}
+
+func TestJsonLinesAreReadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	writeCodecRecords(t, JsonLinesCodec, path, `{"a": 1}`, `{"text":"line\nbreak"}`)
+
+	content, _ := os.ReadFile(path)
+	Assert(t, string(content) == "{\"a\":1}\n{\"text\":\"line\\nbreak\"}\n", "unexpected content %q", content)
+	codec, detected, err := DetectCodec(path)
+	AssertNoError(t, err, "detect failed")
+	Assert(t, detected && codec == JsonLinesCodec, "expected jsonl, got %v", codec)
+	Assert(t, strings.Join(loadRecords(t, path), ",") == `{"a":1},{"text":"line\nbreak"}`, "unexpected records")
+
+	log, err := JsonLinesCodec.Open(path, SyncNever)
+	AssertNoError(t, err, "open failed")
+	Assert(t, errors.Is(log.Append([]byte("no json")), ErrInvalidRecord), "expected ErrInvalidRecord")
+	AssertNoError(t, log.Close(), "close failed")
+}
+
+func TestJsonLinesRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	AssertNoError(t, os.WriteFile(path, []byte("{\"a\":1}\n{broken\n{\"b\":2}\n{\"c\":"), 0644), "write failed")
+
+	_, err := JsonLinesCodec.Load(path, func([]byte) error { return nil })
+	Assert(t, errors.Is(err, ErrInvalidRecord), "expected ErrInvalidRecord, got %v", err)
+
+	report, err := JsonLinesCodec.Recover(path)
+	AssertNoError(t, err, "recover failed")
+	Assert(t, report.Records == 2 && report.Quarantined == 1 && report.TruncatedBytes == 5, "unexpected report %+v", report)
+	Assert(t, strings.Join(loadRecords(t, path), ",") == `{"a":1},{"b":2}`, "unexpected records after recovery")
+	quarantined, _ := os.ReadFile(path + ".quarantine")
+	Assert(t, string(quarantined) == "{broken\n{\"c\":\n", "unexpected quarantine %q", quarantined)
+}
+
+func TestConvertBetweenCodecs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	writeCodecRecords(t, BinaryCodec, path, `{"a":1}`, `{"b":2}`)
+	codec, _, _ := DetectCodec(path)
+	Assert(t, codec == BinaryCodec, "expected binary codec")
+
+	source, count, err := ConvertLogFile(path, JsonLinesCodec)
+	AssertNoError(t, err, "convert failed")
+	Assert(t, source == BinaryCodec && count == 2, "unexpected conversion from %s with %d records", source.Name(), count)
+	codec, _, _ = DetectCodec(path)
+	Assert(t, codec == JsonLinesCodec, "expected jsonl after conversion")
+
+	_, _, err = ConvertLogFile(path, BinaryCodec)
+	AssertNoError(t, err, "convert back failed")
+	Assert(t, strings.Join(loadRecords(t, path), ",") == `{"a":1},{"b":2}`, "records changed by conversion")
+}
+
+func TestLegacyFileStartingWithBraceIsBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	record := `{"text":"` + strings.Repeat("x", 81) + `"}`
+	data := encodeB64([]byte(record))
+	Assert(t, len(data) == 0x7B, "expected payload of 123 bytes, got %d", len(data))
+	AssertNoError(t, os.WriteFile(path, frame(0, data), 0644), "write failed")
+
+	codec, detected, err := DetectCodec(path)
+	AssertNoError(t, err, "detect failed")
+	Assert(t, detected && codec == BinaryCodec, "expected binary codec, got %v", codec)
+
+	_, err = JsonLinesCodec.Recover(path)
+	Assert(t, err != nil, "expected jsonl recovery to be refused")
+	report, err := BinaryCodec.Recover(path)
+	AssertNoError(t, err, "recover failed")
+	Assert(t, !report.Modified(), "unexpected report %+v", report)
+	Assert(t, strings.Join(loadRecords(t, path), ",") == record, "record lost")
+}
+
+func TestUnknownCodecIsNotRecovered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	AssertNoError(t, os.WriteFile(path, []byte(`{"a":`), 0644), "write failed")
+
+	_, _, err := DetectCodec(path)
+	Assert(t, errors.Is(err, ErrUnknownCodec), "expected ErrUnknownCodec, got %v", err)
+	_, err = JsonLinesCodec.Recover(path)
+	Assert(t, errors.Is(err, ErrUnknownCodec), "expected ErrUnknownCodec, got %v", err)
+	content, _ := os.ReadFile(path)
+	Assert(t, string(content) == `{"a":`, "file was modified")
+}
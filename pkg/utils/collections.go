@@ -0,0 +1,10 @@
+package utils
+
+func Contains[T comparable](list []T, value T) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"github.com/mwildt/ceh-utils/pkg/metrics"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Compactable ist ein Log, das durch Neuschreiben seiner aktuellen Einträge verkleinert werden kann
+type Compactable interface {
+	// CompactionStats liefert die Anzahl aller Einträge in der Datei und die Anzahl der
+	// Einträge, die nach einer Kompaktierung übrig bleiben würden
+	CompactionStats() (total int, live int)
+	Compact() error
+}
+
+type CompactionThresholds struct {
+	// Mindestanzahl überholter Einträge
+	MinObsolete int
+	// Mindestanteil überholter Einträge an allen Einträgen (0..1)
+	MinRatio float64
+	// Intervall, in dem unabhängig von Schreibvorgängen geprüft wird
+	Interval time.Duration
+}
+
+func (thresholds CompactionThresholds) exceeded(total int, live int) bool {
+	obsolete := total - live
+	if obsolete <= 0 || obsolete < thresholds.MinObsolete {
+		return false
+	}
+	return float64(obsolete)/float64(total) >= thresholds.MinRatio
+}
+
+// ConfiguredCompactionThresholds liest die Schwellwerte aus COMPACT_MIN_OBSOLETE,
+// COMPACT_MIN_RATIO und COMPACT_INTERVAL
+func ConfiguredCompactionThresholds() CompactionThresholds {
+	thresholds := CompactionThresholds{MinObsolete: 100, MinRatio: 0, Interval: time.Minute}
+	if value, err := strconv.Atoi(GetEnvOrDefault("COMPACT_MIN_OBSOLETE", "")); err == nil {
+		thresholds.MinObsolete = value
+	}
+	if value, err := strconv.ParseFloat(GetEnvOrDefault("COMPACT_MIN_RATIO", ""), 64); err == nil {
+		thresholds.MinRatio = value
+	}
+	if value, err := time.ParseDuration(GetEnvOrDefault("COMPACT_INTERVAL", "")); err == nil {
+		thresholds.Interval = value
+	}
+	return thresholds
+}
+
+var compactionDuration = metrics.NewHistogram("ceh_compaction_duration_seconds", "Duration of log compactions by log and result.",
+	[]float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60}, "log", "result")
+
+// Compactor prüft in einer einzigen Goroutine, ob ein Log kompaktiert werden muss. Schreibende
+// Repositories melden sich per Notify, zusätzlich wird im eingestellten Intervall geprüft.
+type Compactor struct {
+	name       string
+	target     Compactable
+	thresholds CompactionThresholds
+	logger     Logger
+	trigger    chan struct{}
+	done       chan struct{}
+	stopped    chan struct{}
+	once       *sync.Once
+}
+
+func NewCompactor(name string, target Compactable, thresholds CompactionThresholds) *Compactor {
+	compactor := &Compactor{
+		name:       name,
+		target:     target,
+		thresholds: thresholds,
+		logger:     NewStdLogger(name + ".compactor"),
+		trigger:    make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+		once:       &sync.Once{},
+	}
+	go compactor.run()
+	return compactor
+}
+
+func (compactor *Compactor) run() {
+	defer close(compactor.stopped)
+	interval := compactor.thresholds.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-compactor.done:
+			return
+		case <-compactor.trigger:
+			compactor.check()
+		case <-ticker.C:
+			compactor.check()
+		}
+	}
+}
+
+func (compactor *Compactor) check() {
+	total, live := compactor.target.CompactionStats()
+	if !compactor.thresholds.exceeded(total, live) {
+		return
+	}
+	compactor.logger.Info("start compaction: %d of %d records are obsolete", total-live, total)
+	start := time.Now()
+	if err := compactor.target.Compact(); err != nil {
+		compactionDuration.Observe(time.Since(start).Seconds(), compactor.name, "error")
+		compactor.logger.Error("compaction failed: %s", err.Error())
+	} else {
+		compactionDuration.Observe(time.Since(start).Seconds(), compactor.name, "success")
+		compactor.logger.Info("compaction finished in %s", time.Since(start))
+	}
+}
+
+// Notify stößt eine Prüfung an, ohne den Aufrufer zu blockieren
+func (compactor *Compactor) Notify() {
+	select {
+	case compactor.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Stop beendet die Goroutine und wartet auf eine ggf. laufende Kompaktierung
+func (compactor *Compactor) Stop() {
+	compactor.once.Do(func() {
+		close(compactor.done)
+		<-compactor.stopped
+	})
+}
+
+// CompactLogFile schreibt die Log-Datei mit den von write gelieferten Einträgen und dem Codec neu.
+// Die neue Datei wird vollständig geschrieben und per fsync gesichert, bevor sie die bestehende
+// Datei atomar ersetzt. Es gibt also zu keinem Zeitpunkt einen Zustand ohne gültige Datei.
+func CompactLogFile(path string, codec Codec, write func(log RecordLog) error) error {
+	compactPath := path + ".compact"
+	log, err := codec.Create(compactPath, SyncNever)
+	if err != nil {
+		return err
+	}
+	if err = write(log); err != nil {
+		_ = log.Close()
+		_ = os.Remove(compactPath)
+		return err
+	}
+	if err = log.Sync(); err != nil {
+		_ = log.Close()
+		return err
+	}
+	if err = log.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(compactPath, path); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(path))
+}
+
+func SyncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err = dir.Sync(); err != nil {
+		_ = dir.Close()
+		return err
+	}
+	return dir.Close()
+}
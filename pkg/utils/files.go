@@ -2,7 +2,6 @@ package utils
 
 import (
 	"encoding/base64"
-	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"io"
@@ -18,17 +17,11 @@ func B64JsonEncoder[T any](value T) (data []byte, err error) {
 	if err != nil {
 		return data, err
 	}
-	encoder := base64.RawStdEncoding
-	data = make([]byte, encoder.EncodedLen(len(jsonData)))
-	encoder.Encode(data, jsonData)
-
-	return data, err
+	return encodeB64(jsonData), err
 }
 
 func B64JsonDecoder[T any](data []byte) (value T, err error) {
-	encoding := base64.RawStdEncoding
-	jsonValue := make([]byte, encoding.DecodedLen(len(data)))
-	_, err = encoding.Decode(jsonValue, data)
+	jsonValue, err := decodeB64(data)
 	if err != nil {
 		return value, err
 	}
@@ -39,48 +32,35 @@ func B64JsonDecoder[T any](data []byte) (value T, err error) {
 	return value, err
 }
 
-func Append[T any](file *os.File, value T, encoder Encoder[T]) error {
-	encoded, err := encoder(value)
-	if err != nil {
-		return err
-	}
+func encodeB64(data []byte) []byte {
+	encoder := base64.RawStdEncoding
+	encoded := make([]byte, encoder.EncodedLen(len(data)))
+	encoder.Encode(encoded, data)
+	return encoded
+}
 
-	buffLenBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(buffLenBytes, uint32(len(encoded)))
-	if _, err := file.Write(buffLenBytes); err != nil {
-		return err
-	}
-	_, err = file.Write(encoded)
-	return err
+func decodeB64(data []byte) ([]byte, error) {
+	encoding := base64.RawStdEncoding
+	decoded := make([]byte, encoding.DecodedLen(len(data)))
+	n, err := encoding.Decode(decoded, data)
+	return decoded[:n], err
 }
 
-// liest itmes aus einer Datei LEN:ITEM
-func LoadFromFile(path string, consumer func(value []byte) error) (count int, err error) {
-	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
+func CopyFile(source string, target string) error {
+	in, err := os.Open(source)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	defer file.Close()
-
-	for {
-		lenBytes := make([]byte, 4)
-		if _, err := io.ReadFull(file, lenBytes); err != nil {
-			if err == io.EOF {
-				return count, nil
-			}
-			return count, err
-		}
-		dataLen := binary.LittleEndian.Uint32(lenBytes)
-		dataBuffer := make([]byte, int(dataLen))
-		if _, err := io.ReadFull(file, dataBuffer); err != nil {
-			return count, err
-		}
-		if err = consumer(dataBuffer); err != nil {
-			return count, err
-		} else {
-			count = count + 1
-		}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
 	}
+	return out.Close()
 }
 
 func CreateFileIfNotExists(path string) error {
@@ -0,0 +1,496 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Aufbau einer Log-Datei im aktuellen Format:
+//
+//	HEADER: MAGIC(6) VERSION(1) RESERVED(1)
+//	RECORD: LEN(4) CRC32(4) DATA(LEN)
+//
+// Dateien ohne Header stammen aus dem alten Format (Version 0), in dem jeder Datensatz nur
+// aus LEN(4) DATA(LEN) besteht. Alle Zahlen sind little endian.
+const LogFormatVersion = 1
+
+var logMagic = []byte("CEHLOG")
+
+const logHeaderSize = 8
+
+// MaxRecordSize begrenzt die Länge eines Datensatzes, ein größeres Längenfeld gilt als beschädigt
+const MaxRecordSize = 16 << 20
+
+var ErrChecksum = errors.New("record checksum mismatch")
+
+var ErrCorruptRecord = errors.New("corrupt record")
+
+var ErrRecordTooLarge = errors.New("record too large")
+
+var ErrLegacyLogFormat = errors.New("log file uses the legacy format")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type SyncPolicy string
+
+const (
+	// SyncNever überlässt das Schreiben auf die Platte dem Betriebssystem
+	SyncNever SyncPolicy = "never"
+	// SyncAlways führt nach jedem Datensatz ein fsync aus
+	SyncAlways SyncPolicy = "always"
+)
+
+// ConfiguredSyncPolicy liest die fsync-Strategie aus der Umgebungsvariable LOG_FSYNC
+func ConfiguredSyncPolicy() SyncPolicy {
+	if SyncPolicy(GetEnvOrDefault("LOG_FSYNC", string(SyncNever))) == SyncAlways {
+		return SyncAlways
+	}
+	return SyncNever
+}
+
+func logHeader() []byte {
+	return append(append([]byte{}, logMagic...), LogFormatVersion, 0)
+}
+
+func frame(version int, data []byte) []byte {
+	size := 4
+	if version > 0 {
+		size = 8
+	}
+	buffer := make([]byte, size, size+len(data))
+	binary.LittleEndian.PutUint32(buffer, uint32(len(data)))
+	if version > 0 {
+		binary.LittleEndian.PutUint32(buffer[4:], crc32.Checksum(data, crcTable))
+	}
+	return append(buffer, data...)
+}
+
+// LogFile ist eine zum Anhängen geöffnete Log-Datei im aktuellen Format
+type LogFile struct {
+	file   *os.File
+	policy SyncPolicy
+}
+
+// OpenLogFile öffnet eine Log-Datei zum Anhängen. Neue oder leere Dateien erhalten den Header,
+// Dateien im alten Format werden vorher in das aktuelle Format überführt.
+func OpenLogFile(path string, policy SyncPolicy) (*LogFile, error) {
+	if err := CreateFileIfNotExists(path); err != nil {
+		return nil, err
+	}
+	version, err := readLogVersion(path)
+	if errors.Is(err, io.EOF) {
+		if err = writeLogHeader(path); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	} else if version == 0 {
+		if err = UpgradeLogFile(path); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &LogFile{file: file, policy: policy}, nil
+}
+
+// CreateLogFile legt eine neue, leere Log-Datei an. Eine vorhandene Datei wird überschrieben.
+func CreateLogFile(path string, policy SyncPolicy) (*LogFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = file.Write(logHeader()); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &LogFile{file: file, policy: policy}, nil
+}
+
+func writeLogHeader(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(logHeader()); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// liefert die Format-Version einer Datei, io.EOF bei einer leeren Datei
+func readLogVersion(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	header := make([]byte, logHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if n == 0 && errors.Is(err, io.EOF) {
+		return 0, io.EOF
+	}
+	return parseLogVersion(header[:n])
+}
+
+func parseLogVersion(header []byte) (int, error) {
+	if len(header) < logHeaderSize || !bytes.Equal(header[:len(logMagic)], logMagic) {
+		return 0, nil
+	}
+	if version := int(header[len(logMagic)]); version != LogFormatVersion {
+		return version, fmt.Errorf("unsupported log format version %d", version)
+	} else {
+		return version, nil
+	}
+}
+
+// Append schreibt einen Datensatz mit einem einzigen write, damit sich parallele Schreiber
+// auf einer Datei mit O_APPEND nicht gegenseitig unterbrechen
+func (log *LogFile) Append(data []byte) error {
+	if len(data) > MaxRecordSize {
+		return fmt.Errorf("%w: %d bytes", ErrRecordTooLarge, len(data))
+	}
+	if _, err := log.file.Write(frame(LogFormatVersion, data)); err != nil {
+		return err
+	}
+	if log.policy == SyncAlways {
+		return log.file.Sync()
+	}
+	return nil
+}
+
+func (log *LogFile) Sync() error {
+	return log.file.Sync()
+}
+
+func (log *LogFile) Close() error {
+	return log.file.Close()
+}
+
+// Stat liefert die Angaben zur geöffneten Datei, auch wenn sie inzwischen ersetzt wurde
+func (log *LogFile) Stat() (os.FileInfo, error) {
+	return log.file.Stat()
+}
+
+func (log *LogFile) Name() string {
+	return log.file.Name()
+}
+
+func Append[T any](log RecordLog, value T, encoder Encoder[T]) error {
+	encoded, err := encoder(value)
+	if err != nil {
+		return err
+	}
+	return log.Append(encoded)
+}
+
+// LogReader liest die Datensätze einer Log-Datei in beiden Formaten
+type LogReader struct {
+	file    *os.File
+	version int
+	offset  int64
+}
+
+func OpenLogReader(path string) (*LogReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader := &LogReader{file: file}
+	header := make([]byte, logHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		_ = file.Close()
+		return nil, err
+	}
+	if reader.version, err = parseLogVersion(header[:n]); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if reader.version > 0 {
+		reader.offset = logHeaderSize
+	}
+	return reader, nil
+}
+
+func (reader *LogReader) Version() int {
+	return reader.version
+}
+
+// Offset ist die Position hinter dem zuletzt vollständig gelesenen Datensatz
+func (reader *LogReader) Offset() int64 {
+	return reader.offset
+}
+
+// SeekEnd überspringt alle bisher geschriebenen Datensätze
+func (reader *LogReader) SeekEnd() error {
+	stat, err := reader.file.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() > reader.offset {
+		reader.offset = stat.Size()
+	}
+	return nil
+}
+
+// Next liefert den nächsten Datensatz. Am Ende der Datei wird io.EOF geliefert, bei einem
+// unvollständigen Datensatz io.ErrUnexpectedEOF. In beiden Fällen bleibt der Offset
+// unverändert, so dass später erneut gelesen werden kann. Bei ErrChecksum wird der
+// fehlerhafte Datensatz übersprungen und zusätzlich zurückgeliefert.
+//
+// Ein Längenfeld über MaxRecordSize oder über das Ende der Datei hinaus ist nur dann ein
+// abgebrochener Schreibvorgang, wenn kein gültiger Datensatz mehr folgt. Sonst wird bis zum
+// nächsten gültigen Datensatz übersprungen und die übersprungenen Bytes mit ErrCorruptRecord
+// geliefert. Das alte Format hat keine Prüfsumme und wird nur am Ende der Datei erkannt.
+func (reader *LogReader) Next() ([]byte, error) {
+	stat, err := reader.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	headerSize := int64(4)
+	if reader.version > 0 {
+		headerSize = 8
+	}
+	header := make([]byte, headerSize)
+	if n, err := reader.file.ReadAt(header, reader.offset); err != nil {
+		if n == 0 && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		} else if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	size := int64(binary.LittleEndian.Uint32(header))
+	if size > MaxRecordSize || reader.offset+headerSize+size > stat.Size() {
+		if skipped, found, err := reader.resync(stat.Size()); err != nil {
+			return nil, err
+		} else if found {
+			return skipped, ErrCorruptRecord
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, size)
+	if _, err := reader.file.ReadAt(data, reader.offset+headerSize); err != nil {
+		return nil, err
+	}
+	if reader.version > 0 && binary.LittleEndian.Uint32(header[4:]) != crc32.Checksum(data, crcTable) {
+		// ein beschädigtes Längenfeld passt nicht zur Prüfsumme, dann stimmt auch das Ende nicht
+		next := reader.offset + headerSize + size
+		if skipped, found, err := reader.resync(stat.Size()); err != nil {
+			return nil, err
+		} else if found && reader.offset != next {
+			return skipped, ErrCorruptRecord
+		}
+		reader.offset = next
+		return data, ErrChecksum
+	}
+	reader.offset = reader.offset + headerSize + size
+	return data, nil
+}
+
+// resync sucht hinter dem Offset den nächsten Datensatz mit gültiger Prüfsumme und setzt den
+// Offset darauf. Geliefert werden die übersprungenen Bytes.
+func (reader *LogReader) resync(fileSize int64) (skipped []byte, found bool, err error) {
+	if reader.version == 0 {
+		return nil, false, nil
+	}
+	rest := make([]byte, fileSize-reader.offset)
+	if _, err = reader.file.ReadAt(rest, reader.offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, err
+	}
+	for start := 1; start+8 < len(rest); start++ {
+		size := int(binary.LittleEndian.Uint32(rest[start:]))
+		if size == 0 || size > MaxRecordSize || start+8+size > len(rest) {
+			continue
+		}
+		if binary.LittleEndian.Uint32(rest[start+4:]) == crc32.Checksum(rest[start+8:start+8+size], crcTable) {
+			reader.offset = reader.offset + int64(start)
+			return rest[:start], true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// Stat liefert die Angaben zur gelesenen Datei, auch wenn sie inzwischen ersetzt wurde
+func (reader *LogReader) Stat() (os.FileInfo, error) {
+	return reader.file.Stat()
+}
+
+func (reader *LogReader) Close() error {
+	return reader.file.Close()
+}
+
+// liest itmes aus einer Datei, unvollständige oder fehlerhafte Datensätze führen zum Abbruch
+func LoadFromFile(path string, consumer func(value []byte) error) (count int, err error) {
+	reader, err := OpenLogReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	for {
+		data, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		} else if err != nil {
+			return count, fmt.Errorf("%s at offset %d: %w", path, reader.Offset(), err)
+		}
+		if err = consumer(data); err != nil {
+			return count, err
+		} else {
+			count = count + 1
+		}
+	}
+}
+
+// UpgradeLogFile überführt eine Datei aus dem alten in das aktuelle Format
+func UpgradeLogFile(path string) error {
+	upgradePath := path + ".upgrade"
+	target, err := CreateLogFile(upgradePath, SyncNever)
+	if err != nil {
+		return err
+	}
+	if _, err = LoadFromFile(path, target.Append); err != nil {
+		_ = target.Close()
+		return err
+	}
+	if err = target.Sync(); err != nil {
+		_ = target.Close()
+		return err
+	}
+	if err = target.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(upgradePath, path); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(path))
+}
+
+type RecoveryReport struct {
+	Records        int
+	Quarantined    int
+	TruncatedBytes int64
+}
+
+func (report RecoveryReport) Modified() bool {
+	return report.Quarantined > 0 || report.TruncatedBytes > 0
+}
+
+// RecoverLogFile prüft alle Datensätze einer Log-Datei. Ein unvollständiger Datensatz am Ende
+// (z.B. nach einem Absturz während des Schreibens) wird abgeschnitten, Datensätze mit falscher
+// Prüfsumme oder beschädigtem Längenfeld werden entfernt. Beides wird vorher in die Datei <path>.quarantine übernommen.
+func RecoverLogFile(path string) (report RecoveryReport, err error) {
+	reader, err := OpenLogReader(path)
+	if err != nil {
+		return report, err
+	}
+	defer reader.Close()
+
+	valid := make([][]byte, 0)
+	corrupt := make([][]byte, 0)
+	for {
+		data, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			tail, err := readFrom(path, reader.Offset())
+			if err != nil {
+				return report, err
+			}
+			report.TruncatedBytes = int64(len(tail))
+			corrupt = append(corrupt, tail)
+			break
+		} else if errors.Is(err, ErrChecksum) || errors.Is(err, ErrCorruptRecord) {
+			report.Quarantined++
+			corrupt = append(corrupt, data)
+		} else if err != nil {
+			return report, err
+		} else {
+			report.Records++
+			valid = append(valid, data)
+		}
+	}
+	if !report.Modified() {
+		return report, nil
+	}
+
+	if err = quarantine(path+".quarantine", corrupt); err != nil {
+		return report, err
+	}
+	if report.Quarantined == 0 {
+		return report, os.Truncate(path, reader.Offset())
+	}
+	return report, rewriteLogFile(path, reader.Version(), valid)
+}
+
+func readFrom(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(file)
+}
+
+func quarantine(path string, records [][]byte) error {
+	log, err := OpenLogFile(path, SyncAlways)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err = log.Append(record); err != nil {
+			_ = log.Close()
+			return err
+		}
+	}
+	return log.Close()
+}
+
+func rewriteLogFile(path string, version int, records [][]byte) error {
+	rewritePath := path + ".recover"
+	file, err := os.OpenFile(rewritePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err = file.Write(logHeader()); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	for _, record := range records {
+		if _, err = file.Write(frame(version, record)); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(rewritePath, path); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(path))
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRecords(t *testing.T, path string, records ...string) {
+	log, err := OpenLogFile(path, SyncAlways)
+	AssertNoError(t, err, "open failed")
+	for _, record := range records {
+		AssertNoError(t, log.Append([]byte(record)), "append failed")
+	}
+	AssertNoError(t, log.Close(), "close failed")
+}
+
+func readRecords(t *testing.T, path string) (records []string) {
+	_, err := LoadFromFile(path, func(data []byte) error {
+		records = append(records, string(data))
+		return nil
+	})
+	AssertNoError(t, err, "load failed")
+	return records
+}
+
+func TestLogFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	writeRecords(t, path, "one", "two")
+	writeRecords(t, path, "three")
+
+	records := readRecords(t, path)
+	Assert(t, len(records) == 3, "expected 3 records, got %d", len(records))
+	Assert(t, records[2] == "three", "wrong record %s", records[2])
+}
+
+func TestRecoverTruncatesTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	writeRecords(t, path, "one", "two")
+	stat, _ := os.Stat(path)
+	AssertNoError(t, os.Truncate(path, stat.Size()-1), "truncate failed")
+
+	_, err := LoadFromFile(path, func([]byte) error { return nil })
+	Assert(t, err != nil, "expected error for torn record")
+
+	report, err := RecoverLogFile(path)
+	AssertNoError(t, err, "recover failed")
+	Assert(t, report.Records == 1, "expected 1 record, got %d", report.Records)
+	Assert(t, report.TruncatedBytes == 10, "expected 10 truncated bytes, got %d", report.TruncatedBytes)
+
+	records := readRecords(t, path)
+	Assert(t, len(records) == 1 && records[0] == "one", "unexpected records %v", records)
+	Assert(t, len(readRecords(t, path+".quarantine")) == 1, "torn record not quarantined")
+
+	writeRecords(t, path, "three")
+	records = readRecords(t, path)
+	Assert(t, len(records) == 2 && records[1] == "three", "unable to append after recovery %v", records)
+}
+
+func TestRecoverQuarantinesCorruptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	writeRecords(t, path, "one", "two", "three")
+
+	// ein Byte im Payload des zweiten Datensatzes verändern
+	data, _ := os.ReadFile(path)
+	data[logHeaderSize+8+3+8] = 'X'
+	AssertNoError(t, os.WriteFile(path, data, 0644), "write failed")
+
+	report, err := RecoverLogFile(path)
+	AssertNoError(t, err, "recover failed")
+	Assert(t, report.Quarantined == 1, "expected 1 quarantined record, got %d", report.Quarantined)
+
+	records := readRecords(t, path)
+	Assert(t, len(records) == 2 && records[0] == "one" && records[1] == "three", "unexpected records %v", records)
+	quarantined := readRecords(t, path+".quarantine")
+	Assert(t, len(quarantined) == 1 && quarantined[0] == "Xwo", "unexpected quarantine %v", quarantined)
+}
+
+func TestLegacyFileIsReadAndUpgraded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	legacy := make([]byte, 0)
+	for _, record := range []string{"one", "two"} {
+		legacy = binary.LittleEndian.AppendUint32(legacy, uint32(len(record)))
+		legacy = append(legacy, record...)
+	}
+	AssertNoError(t, os.WriteFile(path, legacy, 0644), "write failed")
+
+	Assert(t, len(readRecords(t, path)) == 2, "legacy records not readable")
+
+	writeRecords(t, path, "three")
+	version, err := readLogVersion(path)
+	AssertNoError(t, err, "read version failed")
+	Assert(t, version == LogFormatVersion, "file not upgraded")
+	records := readRecords(t, path)
+	Assert(t, len(records) == 3 && records[0] == "one" && records[2] == "three", "unexpected records %v", records)
+}
+
+func TestRecoverKeepsRecordsBehindCorruptLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	writeRecords(t, path, "one", "two", "three")
+
+	// das Längenfeld des zweiten Datensatzes zeigt weit über das Ende der Datei hinaus
+	data, _ := os.ReadFile(path)
+	binary.LittleEndian.PutUint32(data[logHeaderSize+8+3:], 0x7FFFFFFF)
+	AssertNoError(t, os.WriteFile(path, data, 0644), "write failed")
+
+	_, err := LoadFromFile(path, func([]byte) error { return nil })
+	Assert(t, errors.Is(err, ErrCorruptRecord), "expected ErrCorruptRecord, got %v", err)
+
+	report, err := RecoverLogFile(path)
+	AssertNoError(t, err, "recover failed")
+	Assert(t, report.Records == 2 && report.Quarantined == 1 && report.TruncatedBytes == 0, "unexpected report %+v", report)
+	records := readRecords(t, path)
+	Assert(t, len(records) == 2 && records[0] == "one" && records[1] == "three", "unexpected records %v", records)
+}
+
+func TestRecoverSkipsRecordWithFlippedLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	writeRecords(t, path, "one", "two", "three", "four")
+
+	// ein kleineres Längenfeld im zweiten Datensatz verschiebt alle folgenden Datensätze
+	data, _ := os.ReadFile(path)
+	binary.LittleEndian.PutUint32(data[logHeaderSize+8+3:], 1)
+	AssertNoError(t, os.WriteFile(path, data, 0644), "write failed")
+
+	report, err := RecoverLogFile(path)
+	AssertNoError(t, err, "recover failed")
+	Assert(t, report.Records == 3 && report.Quarantined == 1, "unexpected report %+v", report)
+	records := readRecords(t, path)
+	Assert(t, len(records) == 3 && records[1] == "three" && records[2] == "four", "unexpected records %v", records)
+}
+
+func TestAppendRejectsOversizedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	log, err := OpenLogFile(path, SyncNever)
+	AssertNoError(t, err, "open failed")
+	defer log.Close()
+	Assert(t, errors.Is(log.Append(make([]byte, MaxRecordSize+1)), ErrRecordTooLarge), "expected ErrRecordTooLarge")
+}
@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
-	"time"
+	"strings"
+	"sync"
 )
 
 type logLevel string
@@ -17,6 +20,26 @@ const (
 	Debug logLevel = "DEBUG"
 )
 
+func (level logLevel) slogLevel() slog.Level {
+	switch level {
+	case Debug:
+		return slog.LevelDebug
+	case Warn:
+		return slog.LevelWarn
+	case Error:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Felder, die ein Request über den Kontext an alle Log-Ausgaben weitergibt
+const (
+	LogFieldRequestId  = "requestId"
+	LogFieldTrainingId = "trainingId"
+	LogFieldUser       = "user"
+)
+
 type Logger interface {
 	Info(template string, args ...any)
 
@@ -29,27 +52,62 @@ type Logger interface {
 	Log(level logLevel, template string, args ...any)
 
 	Logger(name string) Logger
+
+	// With liefert einen Logger, der das Feld in jeder Ausgabe mitschreibt
+	With(key string, value any) Logger
+
+	// WithContext liefert einen Logger mit den Feldern des Kontexts (siehe WithLogField)
+	WithContext(ctx context.Context) Logger
 }
 
 type logger struct {
 	name string
-	out  io.Writer
+	out  *slog.Logger
 }
 
+var configuredHandler = sync.OnceValue(func() slog.Handler {
+	handler, err := NewLogHandler(os.Stdout, GetEnvOrDefault("LOG_FORMAT", "text"), GetEnvOrDefault("LOG_LEVEL", "info"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging configuration, using defaults: %s\n", err.Error())
+		handler, _ = NewLogHandler(os.Stdout, "text", "info")
+	}
+	return handler
+})
+
+// NewLogHandler erzeugt den slog-Handler für das Format (text oder json) und das minimale Level
+func NewLogHandler(out io.Writer, format string, level string) (slog.Handler, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %s", level)
+	}
+	options := &slog.HandlerOptions{Level: minLevel}
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.NewTextHandler(out, options), nil
+	case "json":
+		return slog.NewJSONHandler(out, options), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %s", format)
+	}
+}
+
+// NewStdLogger schreibt auf stdout, Format und Level kommen aus LOG_FORMAT und LOG_LEVEL
 func NewStdLogger(name string) Logger {
+	return NewLogger(name, configuredHandler())
+}
+
+func NewLogger(name string, handler slog.Handler) Logger {
 	return logger{
 		name: name,
-		out:  os.Stdout,
+		out:  slog.New(handler),
 	}
 }
 
 func (l logger) Log(level logLevel, template string, args ...any) {
-	message := fmt.Sprintf("%s [%s] %s: "+template, append([]any{
-		time.Now().Format(time.RFC3339),
-		level,
-		l.name,
-	}, args...)...)
-	fmt.Println(message)
+	if !l.out.Enabled(context.Background(), level.slogLevel()) {
+		return
+	}
+	l.out.Log(context.Background(), level.slogLevel(), fmt.Sprintf(template, args...), "logger", l.name)
 }
 
 func (l logger) Info(template string, args ...any) {
@@ -74,3 +132,42 @@ func (l logger) Logger(name string) Logger {
 		out:  l.out,
 	}
 }
+
+func (l logger) With(key string, value any) Logger {
+	return logger{
+		name: l.name,
+		out:  l.out.With(key, value),
+	}
+}
+
+func (l logger) WithContext(ctx context.Context) Logger {
+	fields := logFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return logger{
+		name: l.name,
+		out:  l.out.With(fields...),
+	}
+}
+
+type logFieldsKey struct{}
+
+// WithLogField hängt ein Feld an den Kontext an, das Logger über WithContext übernehmen. Ein
+// bereits gesetztes Feld wird ersetzt.
+func WithLogField(ctx context.Context, key string, value any) context.Context {
+	fields := logFields(ctx)
+	for i := 0; i < len(fields); i += 2 {
+		if fields[i] == key {
+			replaced := append([]any{}, fields...)
+			replaced[i+1] = value
+			return context.WithValue(ctx, logFieldsKey{}, replaced)
+		}
+	}
+	return context.WithValue(ctx, logFieldsKey{}, append(fields[:len(fields):len(fields)], key, value))
+}
+
+func logFields(ctx context.Context) []any {
+	fields, _ := ctx.Value(logFieldsKey{}).([]any)
+	return fields
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLoggerFiltersByLevel(t *testing.T) {
+	var out bytes.Buffer
+	handler, err := NewLogHandler(&out, "text", "warn")
+	AssertNoError(t, err, "create handler failed")
+	logger := NewLogger("test", handler)
+	logger.Debug("debug %d", 1)
+	logger.Info("info %d", 2)
+	logger.Warn("warn %d", 3)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	Assert(t, len(lines) == 1, "expected one line, got %q", out.String())
+	Assert(t, strings.Contains(lines[0], "level=WARN") && strings.Contains(lines[0], `msg="warn 3"`), "unexpected line %s", lines[0])
+
+	_, err = NewLogHandler(&out, "xml", "info")
+	Assert(t, err != nil, "expected error for unknown format")
+	_, err = NewLogHandler(&out, "json", "verbose")
+	Assert(t, err != nil, "expected error for unknown level")
+}
+
+func TestLoggerWritesContextFields(t *testing.T) {
+	var out bytes.Buffer
+	handler, _ := NewLogHandler(&out, "json", "debug")
+	ctx := WithLogField(context.Background(), LogFieldRequestId, "r-1")
+	ctx = WithLogField(ctx, LogFieldUser, "alice")
+	NewLogger("http", handler).Logger("trace").WithContext(ctx).Debug("%s %s", "GET", "/api")
+
+	var line map[string]any
+	AssertNoError(t, json.Unmarshal(out.Bytes(), &line), "invalid json %s", out.String())
+	Assert(t, line["msg"] == "GET /api" && line["level"] == "DEBUG", "unexpected message %v", line)
+	Assert(t, line["logger"] == "http::trace", "unexpected logger %v", line["logger"])
+	Assert(t, line[LogFieldRequestId] == "r-1" && line[LogFieldUser] == "alice", "context fields missing in %v", line)
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersionField ist das JSON-Feld, in dem jeder gespeicherte Datensatz seine Schema-Version
+// trägt. Datensätze ohne das Feld haben die Version 0.
+const SchemaVersionField = "schemaVersion"
+
+// JsonObject ist ein Datensatz, wie ihn eine Migration sieht. Migrationen arbeiten auf dem
+// JSON-Objekt, damit alte Versionen kein eigenes Struct im Code brauchen.
+type JsonObject map[string]json.RawMessage
+
+// Migration überführt einen Datensatz von der Version From in die Version From+1
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(record JsonObject) (JsonObject, error)
+}
+
+// MigrationRegistry enthält die Migrationen für eine Art von Datensätzen bis zur aktuellen Version
+type MigrationRegistry struct {
+	name       string
+	current    int
+	migrations map[int]Migration
+}
+
+// NewMigrationRegistry prüft, dass es für jede Version vor der aktuellen genau eine Migration gibt.
+// Eine Lücke ist ein Programmierfehler und führt zu einem panic.
+func NewMigrationRegistry(name string, current int, migrations ...Migration) *MigrationRegistry {
+	registry := &MigrationRegistry{name: name, current: current, migrations: make(map[int]Migration)}
+	for _, migration := range migrations {
+		if _, exists := registry.migrations[migration.From]; exists {
+			panic(fmt.Sprintf("%s: duplicate migration from version %d", name, migration.From))
+		}
+		registry.migrations[migration.From] = migration
+	}
+	for version := 0; version < current; version++ {
+		if _, exists := registry.migrations[version]; !exists {
+			panic(fmt.Sprintf("%s: missing migration from version %d", name, version))
+		}
+	}
+	return registry
+}
+
+func (registry *MigrationRegistry) Name() string {
+	return registry.name
+}
+
+func (registry *MigrationRegistry) Current() int {
+	return registry.current
+}
+
+// Pending liefert die Migrationen, die ein Datensatz der Version from durchläuft
+func (registry *MigrationRegistry) Pending(from int) (pending []Migration) {
+	for version := from; version < registry.current; version++ {
+		pending = append(pending, registry.migrations[version])
+	}
+	return pending
+}
+
+// Migrate bringt einen JSON-Datensatz auf die aktuelle Version und liefert die Version, in der
+// er gespeichert war
+func (registry *MigrationRegistry) Migrate(data []byte) (migrated []byte, from int, err error) {
+	record := JsonObject{}
+	if err = json.Unmarshal(data, &record); err != nil {
+		return data, from, err
+	}
+	if version, exists := record[SchemaVersionField]; exists {
+		if err = json.Unmarshal(version, &from); err != nil {
+			return data, from, fmt.Errorf("%s: invalid schema version: %w", registry.name, err)
+		}
+	}
+	if from == registry.current {
+		return data, from, nil
+	} else if from > registry.current {
+		return data, from, fmt.Errorf("%s: schema version %d is newer than supported version %d", registry.name, from, registry.current)
+	}
+	for _, migration := range registry.Pending(from) {
+		if record, err = migration.Apply(record); err != nil {
+			return data, from, fmt.Errorf("%s: migration from version %d failed: %w", registry.name, migration.From, err)
+		}
+	}
+	migrated, err = registry.stamp(record)
+	return migrated, from, err
+}
+
+func (registry *MigrationRegistry) stamp(record JsonObject) ([]byte, error) {
+	version, err := json.Marshal(registry.current)
+	if err != nil {
+		return nil, err
+	}
+	record[SchemaVersionField] = version
+	return json.Marshal(record)
+}
+
+// Stamp serialisiert einen Datensatz der aktuellen Version mit dem Feld schemaVersion
+func (registry *MigrationRegistry) Stamp(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return data, err
+	}
+	record := JsonObject{}
+	if err = json.Unmarshal(data, &record); err != nil {
+		return data, err
+	}
+	return registry.stamp(record)
+}
+
+// VersionedJsonEncoder erzeugt JSON-Datensätze mit der aktuellen Schema-Version
+func VersionedJsonEncoder[T any](registry *MigrationRegistry) Encoder[T] {
+	return func(value T) ([]byte, error) {
+		return registry.Stamp(value)
+	}
+}
+
+// VersionedJsonDecoder migriert Datensätze beim Laden auf die aktuelle Version. from ist die
+// Version, in der der Datensatz gespeichert war.
+func VersionedJsonDecoder[T any](registry *MigrationRegistry) func(data []byte) (value T, from int, err error) {
+	return func(data []byte) (value T, from int, err error) {
+		migrated, from, err := registry.Migrate(data)
+		if err != nil {
+			return value, from, err
+		}
+		err = json.Unmarshal(migrated, &value)
+		return value, from, err
+	}
+}
+
+// MigrationReport beschreibt einen Datensatz einer Log-Datei, der migriert werden muss
+type MigrationReport struct {
+	Index      int
+	From       int
+	Migrations []Migration
+}
+
+// MigrateLogFile prüft alle Datensätze einer Log-Datei. Ohne dryRun wird die Datei mit den
+// migrierten Datensätzen im bisherigen Codec neu geschrieben, sofern sich etwas ändert.
+func MigrateLogFile(path string, registry *MigrationRegistry, dryRun bool) (reports []MigrationReport, err error) {
+	codec, err := CodecFor(path, BinaryCodec)
+	if err != nil {
+		return reports, err
+	}
+	var records [][]byte
+	index := 0
+	if _, err = codec.Load(path, func(data []byte) error {
+		migrated, from, err := registry.Migrate(data)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+		if from != registry.current {
+			reports = append(reports, MigrationReport{Index: index, From: from, Migrations: registry.Pending(from)})
+		}
+		records = append(records, migrated)
+		index++
+		return nil
+	}); err != nil || dryRun || len(reports) == 0 {
+		return reports, err
+	}
+	return reports, CompactLogFile(path, codec, func(log RecordLog) error {
+		for _, record := range records {
+			if err := log.Append(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
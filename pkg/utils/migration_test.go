@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type renamedRecord struct {
+	Title string `json:"title"`
+}
+
+func renameRegistry() *MigrationRegistry {
+	return NewMigrationRegistry("test", 2,
+		Migration{From: 0, Description: "stamp", Apply: func(record JsonObject) (JsonObject, error) {
+			return record, nil
+		}},
+		Migration{From: 1, Description: "rename name to title", Apply: func(record JsonObject) (JsonObject, error) {
+			record["title"] = record["name"]
+			delete(record, "name")
+			return record, nil
+		}},
+	)
+}
+
+func TestMigrateRunsPendingMigrations(t *testing.T) {
+	registry := renameRegistry()
+	for from, data := range map[int]string{0: `{"name":"a"}`, 1: `{"schemaVersion":1,"name":"a"}`, 2: `{"schemaVersion":2,"title":"a"}`} {
+		migrated, version, err := registry.Migrate([]byte(data))
+		AssertNoError(t, err, "migrate from %d failed", from)
+		Assert(t, version == from, "expected version %d, got %d", from, version)
+		record := struct {
+			SchemaVersion int    `json:"schemaVersion"`
+			Title         string `json:"title"`
+		}{}
+		AssertNoError(t, json.Unmarshal(migrated, &record), "unmarshal failed")
+		Assert(t, record.SchemaVersion == 2 && record.Title == "a", "unexpected record %s", migrated)
+	}
+
+	_, _, err := registry.Migrate([]byte(`{"schemaVersion":3}`))
+	Assert(t, err != nil, "expected error for newer schema version")
+}
+
+func TestRegistryRejectsMissingMigration(t *testing.T) {
+	defer func() {
+		Assert(t, recover() != nil, "expected panic for missing migration")
+	}()
+	NewMigrationRegistry("test", 2, Migration{From: 0})
+}
+
+func TestMigrateLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	log, err := CreateLogFile(path, SyncNever)
+	AssertNoError(t, err, "create log failed")
+	for _, record := range []string{`{"name":"a"}`, `{"schemaVersion":2,"title":"b"}`} {
+		AssertNoError(t, log.Append(encodeB64([]byte(record))), "append failed")
+	}
+	AssertNoError(t, log.Close(), "close failed")
+
+	registry := renameRegistry()
+	reports, err := MigrateLogFile(path, registry, true)
+	AssertNoError(t, err, "dry run failed")
+	Assert(t, len(reports) == 1 && reports[0].Index == 0 && len(reports[0].Migrations) == 2, "unexpected reports %v", reports)
+
+	reports, err = MigrateLogFile(path, registry, false)
+	AssertNoError(t, err, "migration failed")
+	Assert(t, len(reports) == 1, "expected 1 migrated record, got %d", len(reports))
+
+	decode := VersionedJsonDecoder[renamedRecord](registry)
+	var titles []string
+	_, err = BinaryCodec.Load(path, func(data []byte) error {
+		record, from, err := decode(data)
+		Assert(t, from == 2, "record not migrated in file")
+		titles = append(titles, record.Title)
+		return err
+	})
+	AssertNoError(t, err, "load failed")
+	Assert(t, strings.Join(titles, ",") == "a,b", "unexpected titles %v", titles)
+}
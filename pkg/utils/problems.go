@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Fehlerarten der Domäne. Fehler, die eine dieser Arten tragen, werden von SendError auf den
+// passenden Status abgebildet und mit ihrer Meldung an den Client gegeben.
+var (
+	ErrInvalid     = errors.New("invalid")
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrForbidden   = errors.New("forbidden")
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// DomainError ist ein fachlicher Fehler mit einer für den Client bestimmten Meldung
+type DomainError struct {
+	Kind    error
+	Message string
+}
+
+func (err *DomainError) Error() string {
+	return err.Message
+}
+
+func (err *DomainError) Unwrap() error {
+	return err.Kind
+}
+
+// Invalid meldet ungültige Eingaben, die fachlich nicht verarbeitet werden können (422)
+func Invalid(format string, args ...any) error {
+	return &DomainError{Kind: ErrInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict meldet eine Anfrage, die dem aktuellen Zustand widerspricht (409)
+func Conflict(format string, args ...any) error {
+	return &DomainError{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden meldet eine Änderung, die grundsätzlich nicht erlaubt ist (403)
+func Forbidden(format string, args ...any) error {
+	return &DomainError{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unavailable meldet, dass die Anfrage derzeit nicht bedient werden kann (503)
+func Unavailable(format string, args ...any) error {
+	return &DomainError{Kind: ErrUnavailable, Message: fmt.Sprintf(format, args...)}
+}
+
+// Problem ist eine Fehlerantwort nach RFC 7807
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// SendProblem schreibt eine Fehlerantwort als application/problem+json
+func SendProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	payload, _ := json.Marshal(Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = w.Write(payload)
+}
+
+// StatusOf liefert den Status zu einem Fehler, 500 für Fehler ohne Fehlerart
+func StatusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// SendError bildet err auf eine Fehlerantwort ab. Die Meldung interner Fehler wird nur geloggt.
+func SendError(w http.ResponseWriter, r *http.Request, err error) {
+	if status := StatusOf(err); status != http.StatusInternalServerError {
+		SendProblem(w, r, status, err.Error())
+	} else {
+		InternalServerError(w, r, err)
+	}
+}
+
+func BadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	SendProblem(w, r, http.StatusBadRequest, detail)
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request, detail string) {
+	SendProblem(w, r, http.StatusNotFound, detail)
+}
+
+func InternalServerError(w http.ResponseWriter, r *http.Request, err error) {
+	problemLogger.WithContext(r.Context()).Error("%s %s failed: %s", r.Method, r.URL.Path, err)
+	SendProblem(w, r, http.StatusInternalServerError, "")
+}
+
+var problemLogger = NewStdLogger("http-problem")
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendErrorMapsDomainErrors(t *testing.T) {
+	for err, expected := range map[error]int{
+		Invalid("options must be min %d", 2):                  http.StatusUnprocessableEntity,
+		fmt.Errorf("%w: 42", Conflict("already exists")):      http.StatusConflict,
+		Forbidden("read-only"):                                http.StatusForbidden,
+		Unavailable("no question available"):                  http.StatusServiceUnavailable,
+		&DomainError{Kind: ErrNotFound, Message: "not found"}: http.StatusNotFound,
+		errors.New("disk full"):                               http.StatusInternalServerError,
+	} {
+		recorder := httptest.NewRecorder()
+		SendError(recorder, httptest.NewRequest(http.MethodPost, "/api/things", nil), err)
+		Assert(t, recorder.Code == expected, "expected %d for %v, got %d", expected, err, recorder.Code)
+		Assert(t, recorder.Header().Get("Content-Type") == "application/problem+json", "unexpected content type %s", recorder.Header().Get("Content-Type"))
+
+		var problem Problem
+		AssertNoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem), "invalid problem")
+		Assert(t, problem.Status == expected && problem.Title == http.StatusText(expected), "unexpected problem %v", problem)
+		Assert(t, problem.Instance == "/api/things", "unexpected instance %s", problem.Instance)
+		if expected == http.StatusInternalServerError {
+			Assert(t, problem.Detail == "", "internal error leaked: %s", problem.Detail)
+		} else {
+			Assert(t, problem.Detail == err.Error(), "expected detail %q, got %q", err.Error(), problem.Detail)
+		}
+	}
+}
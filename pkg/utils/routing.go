@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+)
+
+// SubResources verteilt Requests auf eine Route wie /api/trainings/{trainingId}/{resource} anhand
+// des Parameters name. go-http bricht den Vergleich einer Route, in der auf einen Parameter ein
+// festes Segment folgt, mit einer Panic ab, wenn das feste Segment nicht passt. Routen unterhalb
+// eines Parameters enden deshalb immer mit einem Parameter.
+func SubResources(name string, handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if resource, exists := routing.GetParameter(r.Context(), name); !exists {
+			NotFound(w, r, "resource not found")
+		} else if handler, exists := handlers[resource]; !exists {
+			NotFound(w, r, "resource not found")
+		} else {
+			handler(w, r)
+		}
+	}
+}
+
+// Secured wendet ApiSecured auf einen einzelnen Handler an, z.B. innerhalb von SubResources
+func Secured(handler http.HandlerFunc) http.HandlerFunc {
+	return routing.FilterChain{ApiSecured()}.Build(handler)
+}
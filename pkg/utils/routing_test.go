@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSubResourcesDispatchByParameter(t *testing.T) {
+	handled := ""
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id, _ := routing.GetParameter(r.Context(), "itemId")
+			handled = name + ":" + id
+		}
+	}
+	router := routing.NewRouter(func(router routing.Routing) {
+		router.HandleFunc(routing.Get("/api/items/{itemId}"), handler("item"))
+		router.HandleFunc(routing.Get("/api/items/{itemId}/{resource}"), SubResources("resource", map[string]http.HandlerFunc{
+			"parts":   handler("parts"),
+			"history": handler("history"),
+		}))
+	})
+
+	for path, expected := range map[string]string{"/api/items/1": "item:1", "/api/items/2/parts": "parts:2", "/api/items/3/history": "history:3"} {
+		handled = ""
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		Assert(t, handled == expected, "expected %s for %s, got %s", expected, path, handled)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/items/4/unknown", nil))
+	Assert(t, recorder.Code == http.StatusNotFound, "expected 404 for unknown resource, got %d", recorder.Code)
+}
+
+func TestSecuredRequiresApiKey(t *testing.T) {
+	t.Setenv("API_KEY", "secret")
+	handled := false
+	handler := Secured(func(w http.ResponseWriter, r *http.Request) { handled = true })
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+	Assert(t, !handled && recorder.Code == http.StatusUnauthorized, "expected 401 without key, got %d", recorder.Code)
+
+	request := httptest.NewRequest(http.MethodPost, "/", nil)
+	request.Header.Set("x-api-key", "c2VjcmV0")
+	handler(httptest.NewRecorder(), request)
+	Assert(t, handled, "expected handler to run with valid key")
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"encoding/base64"
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+)
+
+// ApiSecured lässt nur Requests mit dem API-Key (x-api-key, base64 kodiert) durch
+func ApiSecured() routing.Filter {
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		apiToken := r.Header.Get("x-api-key")
+		apiKey := GetEnvOrDefault("API_KEY", "")
+		if apiKey == "" {
+			NewStdLogger("apiSecurity").Warn("Unable to find apiKey, operation denied")
+		}
+		if apiKey == "" || apiToken != base64.StdEncoding.EncodeToString([]byte(apiKey)) {
+			SendProblem(w, r, http.StatusUnauthorized, "missing or invalid api key")
+		} else {
+			next(w, r)
+		}
+	}
+}